@@ -0,0 +1,307 @@
+// Package archive reads zip, 7z, rar and tar archives stored in a
+// user's filesystem and creates zip and tar ones.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/mholt/archiver/v3"
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+// Format is an archive format.
+type Format string
+
+const (
+	Zip      Format = "zip"
+	SevenZip Format = "7z"
+	Rar      Format = "rar"
+	Tar      Format = "tar"
+	TarGz    Format = "targz"
+	TarBz2   Format = "tarbz2"
+	TarXz    Format = "tarxz"
+	TarLz4   Format = "tarlz4"
+	TarSz    Format = "tarsz"
+	TarZstd  Format = "tarzst"
+)
+
+var extensions = []struct {
+	ext    string
+	format Format
+}{
+	// Longest first so ".tar.gz" wins over ".gz".
+	{".tar.gz", TarGz},
+	{".tar.bz2", TarBz2},
+	{".tar.xz", TarXz},
+	{".tar.lz4", TarLz4},
+	{".tar.sz", TarSz},
+	{".tar.zst", TarZstd},
+	{".tgz", TarGz},
+	{".tbz2", TarBz2},
+	{".txz", TarXz},
+	{".tar", Tar},
+	{".zip", Zip},
+	{".7z", SevenZip},
+	{".rar", Rar},
+}
+
+// FormatOf returns the format of the archive called name, judging
+// by its extension.
+func FormatOf(name string) (Format, bool) {
+	name = strings.ToLower(name)
+	for _, e := range extensions {
+		if strings.HasSuffix(name, e.ext) {
+			return e.format, true
+		}
+	}
+	return "", false
+}
+
+// IsArchive reports whether name has the extension of a supported
+// archive format.
+func IsArchive(name string) bool {
+	_, ok := FormatOf(name)
+	return ok
+}
+
+// Entry is a file or directory in an archive.
+type Entry struct {
+	// Name is the slash separated path of the entry in the archive.
+	Name    string
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+	IsDir   bool
+	// CRC32 is the checksum stored in the archive, if the format
+	// keeps one.
+	CRC32 uint32
+}
+
+// WalkFunc is called for every entry of an archive. r reads the
+// contents of the entry and is only valid until WalkFunc returns. The
+// contents are only opened when r is first read, so walks that don't
+// read them don't decompress the archive.
+type WalkFunc func(e *Entry, r io.Reader) error
+
+// SkipAll can be returned by a WalkFunc to stop walking without
+// failing.
+var SkipAll = errors.New("skip everything and stop the walk") //nolint:revive,stylecheck
+
+// Walk calls fn for every entry of the archive at name in fs, in the
+// order they're stored.
+func Walk(fs afero.Fs, name string, fn WalkFunc) error {
+	format, ok := FormatOf(name)
+	if !ok {
+		return fmt.Errorf("%s is not a supported archive: %w", name, fbErrors.ErrInvalidRequestParams)
+	}
+
+	file, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case Zip:
+		err = walkZip(file, info.Size(), fn)
+	case SevenZip:
+		err = walkSevenZip(file, info.Size(), fn)
+	default:
+		err = walkStream(format, file, info.Size(), fn)
+	}
+
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkZip(r io.ReaderAt, size int64, fn WalkFunc) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		e := &Entry{
+			Name:    f.Name,
+			Size:    int64(f.UncompressedSize64), //nolint:gosec
+			ModTime: f.Modified,
+			Mode:    f.Mode(),
+			IsDir:   f.FileInfo().IsDir(),
+			CRC32:   f.CRC32,
+		}
+		if err := walkEntry(e, f.Open, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkSevenZip(r io.ReaderAt, size int64, fn WalkFunc) error {
+	zr, err := sevenzip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		info := f.FileInfo()
+		e := &Entry{
+			Name:    f.Name,
+			Size:    int64(f.UncompressedSize), //nolint:gosec
+			ModTime: f.Modified,
+			Mode:    info.Mode(),
+			IsDir:   info.IsDir(),
+			CRC32:   f.CRC32,
+		}
+		if err := walkEntry(e, f.Open, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkEntry gives fn the contents of regular files, opened when they
+// are first read.
+func walkEntry(e *Entry, open func() (io.ReadCloser, error), fn WalkFunc) error {
+	e.Name = cleanName(e.Name)
+	if e.IsDir || !e.Mode.IsRegular() {
+		return fn(e, eofReader{})
+	}
+
+	r := &lazyReader{open: open}
+	defer r.close()
+	return fn(e, r)
+}
+
+// lazyReader opens what it reads on its first read.
+type lazyReader struct {
+	open func() (io.ReadCloser, error)
+	rc   io.ReadCloser
+	err  error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.rc == nil && l.err == nil {
+		l.rc, l.err = l.open()
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.rc.Read(p)
+}
+
+func (l *lazyReader) close() {
+	if l.rc != nil {
+		l.rc.Close()
+	}
+}
+
+func streamReader(format Format) archiver.Reader {
+	switch format {
+	case Rar:
+		return archiver.NewRar()
+	case Tar:
+		return archiver.NewTar()
+	case TarGz:
+		return archiver.NewTarGz()
+	case TarBz2:
+		return archiver.NewTarBz2()
+	case TarXz:
+		return archiver.NewTarXz()
+	case TarLz4:
+		return archiver.NewTarLz4()
+	case TarSz:
+		return archiver.NewTarSz()
+	default:
+		return archiver.NewTarZstd()
+	}
+}
+
+// walkStream walks the formats that can only be read front to back.
+func walkStream(format Format, r io.Reader, size int64, fn WalkFunc) error {
+	ar := streamReader(format)
+	if err := ar.Open(r, size); err != nil {
+		return err
+	}
+	defer ar.Close()
+
+	for {
+		f, err := ar.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		e := &Entry{
+			Name:    f.Name(),
+			Size:    f.Size(),
+			ModTime: f.ModTime(),
+			Mode:    f.Mode(),
+			IsDir:   f.IsDir(),
+		}
+		if hdr, ok := f.Header.(*tar.Header); ok {
+			// The FileInfo of tar entries only has the base name.
+			e.Name = hdr.Name
+		}
+
+		if err := walkEntry(e, func() (io.ReadCloser, error) { return f.ReadCloser, nil }, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanName turns the name of an entry into a relative slash
+// separated path. Names trying to escape the archive, like
+// "../../etc/passwd", are kept as they are so callers can reject
+// them with IsSafe.
+func cleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || hasDotDot(name) {
+		return name
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return ""
+	}
+	return cleaned
+}
+
+func hasDotDot(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSafe reports whether the name of an entry stays inside the
+// directory the archive is extracted to.
+func IsSafe(name string) bool {
+	return name != "" && !strings.HasPrefix(name, "/") && !hasDotDot(name)
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
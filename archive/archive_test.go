@@ -0,0 +1,278 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+type entry struct {
+	name, content string
+}
+
+func makeZip(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, e.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(e.name, "/") {
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newFs(t *testing.T, files map[string][]byte) afero.Fs {
+	t.Helper()
+	fs := afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+	for name, data := range files {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afero.WriteFile(fs, name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+type counter struct {
+	bytes int64
+	files int
+}
+
+func (c *counter) Add(n int64) { c.bytes += n }
+func (c *counter) FileDone()   { c.files++ }
+
+type denyChecker string
+
+func (d denyChecker) Check(p string) bool { return !strings.HasPrefix(p, string(d)) }
+
+func (d denyChecker) CheckAction(p string, _ rules.Action) bool { return d.Check(p) }
+
+// ruleChecker checks paths against a list of rules.
+type ruleChecker []rules.Rule
+
+func (r ruleChecker) Check(p string) bool { return rules.Allowed(p, "", r) }
+
+func (r ruleChecker) CheckAction(p string, action rules.Action) bool {
+	return rules.Allowed(p, action, r)
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]Format{
+		"pack.ZIP":        Zip,
+		"pack.tar.gz":     TarGz,
+		"pack.tgz":        TarGz,
+		"pack.7z":         SevenZip,
+		"pack.tar.zst":    TarZstd,
+		"mame.tar.bz2":    TarBz2,
+		"snes.rar":        Rar,
+		"plain.tar":       Tar,
+		"not-archive.sfc": "",
+	} {
+		if got, _ := FormatOf(name); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"/pack.zip": makeZip(t,
+			entry{"snes/", ""},
+			entry{"snes/zelda.sfc", "zelda"},
+			entry{"hidden/secret.txt", "secret"},
+			entry{"mario.sfc", "mario!"},
+		),
+		"/pack.tar.gz": makeTarGz(t,
+			entry{"snes/", ""},
+			entry{"snes/zelda.sfc", "zelda"},
+			entry{"hidden/secret.txt", "secret"},
+			entry{"./mario.sfc", "mario!"},
+		),
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs := newFs(t, map[string][]byte{name: data})
+			progress := &counter{}
+
+			err := Extract(context.Background(), fs, name, "/out", ExtractOptions{
+				Checker:  denyChecker("/out/hidden"),
+				Progress: progress,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for p, want := range map[string]string{"/out/snes/zelda.sfc": "zelda", "/out/mario.sfc": "mario!"} {
+				if got, _ := afero.ReadFile(fs, p); string(got) != want {
+					t.Errorf("%s: expected %q, got %q", p, want, got)
+				}
+			}
+			if exists, _ := afero.Exists(fs, "/out/hidden/secret.txt"); exists {
+				t.Error("extracted an entry the checker rejected")
+			}
+			if progress.files != 2 || progress.bytes != 11 {
+				t.Errorf("unexpected progress %+v", progress)
+			}
+
+			err = Extract(context.Background(), fs, name, "/out", ExtractOptions{})
+			if !errors.Is(err, fbErrors.ErrExist) {
+				t.Errorf("expected ErrExist, got %v", err)
+			}
+			if err := Extract(context.Background(), fs, name, "/out", ExtractOptions{Override: true}); err != nil {
+				t.Errorf("override failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	for _, evil := range []string{"../escaped.txt", "a/../../escaped.txt", "/abs.txt", "..\\escaped.txt"} {
+		fs := newFs(t, map[string][]byte{"/evil.zip": makeZip(t, entry{evil, "pwned"})})
+
+		err := Extract(context.Background(), fs, "/evil.zip", "/dst/out", ExtractOptions{})
+		if !errors.Is(err, fbErrors.ErrPermissionDenied) {
+			t.Errorf("%s: expected ErrPermissionDenied, got %v", evil, err)
+		}
+		for _, p := range []string{"/dst/escaped.txt", "/escaped.txt", "/abs.txt"} {
+			if exists, _ := afero.Exists(fs, p); exists {
+				t.Errorf("%s: wrote %s outside of the destination", evil, p)
+			}
+		}
+	}
+}
+
+func TestExtractActions(t *testing.T) {
+	fs := newFs(t, map[string][]byte{
+		"/pack.zip":            makeZip(t, entry{"saves/slot1.sav", "new"}, entry{"saves/slot2.sav", "new"}, entry{"bios/scph1001.bin", "new"}),
+		"/out/saves/slot1.sav": []byte("old"),
+	})
+
+	err := Extract(context.Background(), fs, "/pack.zip", "/out", ExtractOptions{
+		Checker: ruleChecker{
+			{Path: "/out/saves", Actions: []rules.Action{rules.Modify}},
+			{Path: "/out/bios", Actions: []rules.Action{rules.Create}},
+		},
+		Override: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"/out/saves/slot1.sav": "old",
+		"/out/saves/slot2.sav": "new",
+	} {
+		if got, _ := afero.ReadFile(fs, name); string(got) != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	if exists, _ := afero.Exists(fs, "/out/bios/scph1001.bin"); exists {
+		t.Error("extracted a path the rules don't let create")
+	}
+}
+
+func TestWalkSevenZip(t *testing.T) {
+	data, err := os.ReadFile("testdata/file_and_empty.7z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newFs(t, map[string][]byte{"/pack.7z": data})
+
+	count, size, err := Measure(fs, "/pack.7z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || size != 21 {
+		t.Errorf("unexpected measure %d files, %d bytes", count, size)
+	}
+
+	if err := Extract(context.Background(), fs, "/pack.7z", "/out", ExtractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := afero.ReadFile(fs, "/out/large"); string(got) != "Huuuuge file contents" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestWalkEntryOpensLazily(t *testing.T) {
+	opened := 0
+	open := func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("data")), nil
+	}
+	e := &Entry{Name: "a.txt", Size: 4}
+
+	if err := walkEntry(e, open, func(*Entry, io.Reader) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if opened != 0 {
+		t.Errorf("expected entries that aren't read not to be opened, got %d opens", opened)
+	}
+
+	var got []byte
+	err := walkEntry(e, open, func(_ *Entry, r io.Reader) (err error) {
+		got, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil || string(got) != "data" || opened != 1 {
+		t.Errorf("expected the entry to be opened once and read, got %q after %d opens: %v", got, opened, err)
+	}
+}
+
+func TestExtractCancelled(t *testing.T) {
+	fs := newFs(t, map[string][]byte{"/pack.zip": makeZip(t, entry{"a", "a"})})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Extract(ctx, fs, "/pack.zip", "/out", ExtractOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// MaxLevel is the highest compression level accepted by NewWriter.
+const MaxLevel = 9
+
+// CreateOptions configures Create.
+type CreateOptions struct {
+	Format Format `json:"format"`
+	// Store keeps the files of a zip archive uncompressed, which is
+	// much faster for files that are already compressed.
+	Store bool `json:"store,omitempty"`
+	// Level goes from 1, the fastest, to MaxLevel, the smallest. Zero
+	// uses the default of the format. Formats without levels ignore it.
+	Level int `json:"level,omitempty"`
+	// Exclude holds glob patterns, as understood by path.Match, for the
+	// files and directories to leave out. They're matched against both
+	// the base name and the path inside the archive.
+	Exclude []string `json:"exclude,omitempty"`
+	// Checker, if set, decides which files can be read. Files it
+	// rejects are left out.
+	Checker rules.Checker `json:"-"`
+	// Progress, if set, is kept up to date.
+	Progress Progress `json:"-"`
+}
+
+// Writable reports whether archives of format can be created.
+func (f Format) Writable() bool {
+	switch f {
+	case Zip, Tar, TarGz, TarBz2, TarXz, TarLz4, TarSz:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extension returns the usual file extension of format, dot included.
+func (f Format) Extension() string {
+	for _, e := range extensions {
+		if e.format == f {
+			return e.ext
+		}
+	}
+	return ""
+}
+
+// NewWriter returns a writer for format using the given compression
+// level, see CreateOptions.
+func NewWriter(format Format, store bool, level int) (archiver.Writer, error) {
+	if level < 0 || level > MaxLevel {
+		return nil, fmt.Errorf("invalid compression level %d: %w", level, fbErrors.ErrInvalidRequestParams)
+	}
+	if store && format != Zip {
+		return nil, fmt.Errorf("only zip archives can be stored: %w", fbErrors.ErrInvalidRequestParams)
+	}
+
+	switch format {
+	case Zip:
+		z := archiver.NewZip()
+		if store {
+			z.FileMethod = archiver.Store
+		}
+		if level > 0 {
+			z.CompressionLevel = level
+		}
+		return z, nil
+	case Tar:
+		return archiver.NewTar(), nil
+	case TarGz:
+		t := archiver.NewTarGz()
+		if level > 0 {
+			t.CompressionLevel = level
+		}
+		return t, nil
+	case TarBz2:
+		t := archiver.NewTarBz2()
+		if level > 0 {
+			t.CompressionLevel = level
+		}
+		return t, nil
+	case TarXz:
+		return archiver.NewTarXz(), nil
+	case TarLz4:
+		t := archiver.NewTarLz4()
+		if level > 0 {
+			t.CompressionLevel = level
+		}
+		return t, nil
+	case TarSz:
+		return archiver.NewTarSz(), nil
+	default:
+		return nil, fmt.Errorf("can't create %q archives: %w", format, fbErrors.ErrInvalidRequestParams)
+	}
+}
+
+// Excluded reports whether name, the path of a file inside an
+// archive, matches one of patterns.
+func Excluded(name string, patterns []string) bool {
+	base := path.Base(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Create writes an archive of the files and directories sources into
+// dst, both in fs. Entries are named relative to the closest common
+// directory of sources. The archive is written next to dst first and
+// only replaces it once complete.
+func Create(ctx context.Context, fs afero.Fs, sources []string, dst string, opts CreateOptions) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("nothing to archive: %w", fbErrors.ErrInvalidRequestParams)
+	}
+
+	ar, err := NewWriter(opts.Format, opts.Store, opts.Level)
+	if err != nil {
+		return err
+	}
+
+	cleaned := make([]string, len(sources))
+	for i, src := range sources {
+		cleaned[i] = path.Clean("/" + src)
+	}
+	sources = cleaned
+	dst = path.Clean("/" + dst)
+	root, err := archiveRoot(fs, sources)
+	if err != nil {
+		return err
+	}
+
+	tmp := path.Join(path.Dir(dst), "."+path.Base(dst)+".part")
+	out, err := fs.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, permFile)
+	if err != nil {
+		return err
+	}
+
+	err = writeArchive(ctx, fs, ar, out, sources, root, []string{dst, tmp}, opts)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = fs.Remove(tmp)
+	}
+	return err
+}
+
+// archiveRoot returns the directory the entries of an archive of
+// sources are named relative to.
+func archiveRoot(fs afero.Fs, sources []string) (string, error) {
+	if len(sources) > 1 {
+		return commonDir(sources), nil
+	}
+	root := sources[0]
+
+	info, err := fs.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		root = path.Dir(root)
+	}
+	return root, nil
+}
+
+// commonDir returns the deepest directory holding all of paths,
+// which must be clean and absolute.
+func commonDir(paths []string) string {
+	dir := paths[0]
+	for _, p := range paths[1:] {
+		for dir != "/" && p != dir && !strings.HasPrefix(p, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
+
+func writeArchive(
+	ctx context.Context,
+	fs afero.Fs,
+	ar archiver.Writer,
+	out io.Writer,
+	sources []string,
+	root string,
+	skip []string,
+	opts CreateOptions,
+) error {
+	if err := ar.Create(out); err != nil {
+		return err
+	}
+
+	for _, src := range sources {
+		err := afero.Walk(fs, src, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return addEntry(ctx, fs, ar, p, info, root, skip, opts)
+		})
+		if err != nil {
+			ar.Close()
+			return err
+		}
+	}
+
+	return ar.Close()
+}
+
+func addEntry(
+	ctx context.Context,
+	fs afero.Fs,
+	ar archiver.Writer,
+	p string,
+	info os.FileInfo,
+	root string,
+	skip []string,
+	opts CreateOptions,
+) error {
+	for _, s := range skip {
+		if p == s {
+			return nil
+		}
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+	excluded := name != "" && Excluded(name, opts.Exclude)
+	if excluded || (opts.Checker != nil && !opts.Checker.Check(p)) {
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	if name == "" || (!info.IsDir() && !info.Mode().IsRegular()) {
+		return nil
+	}
+
+	file, err := fs.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = ar.Write(archiver.File{
+		FileInfo: archiver.FileInfo{
+			FileInfo:   info,
+			CustomName: name,
+		},
+		ReadCloser: io.NopCloser(&progressReader{ctx: ctx, r: file, progress: opts.Progress}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() && opts.Progress != nil {
+		opts.Progress.FileDone()
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+func readArchive(t *testing.T, fs afero.Fs, name string) map[string]string {
+	t.Helper()
+	contents := map[string]string{}
+	err := Walk(fs, name, func(e *Entry, r io.Reader) error {
+		if e.IsDir {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		contents[e.Name] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func romFs(t *testing.T) afero.Fs {
+	t.Helper()
+	return newFs(t, map[string][]byte{
+		"/snes/zelda.sfc":        []byte("zelda"),
+		"/snes/zelda.srm":        []byte("save"),
+		"/snes/hacks/mario.sfc":  []byte("mario!"),
+		"/snes/hidden/rom.sfc":   []byte("hidden"),
+		"/snes/.cache/thumb.png": []byte("png"),
+	})
+}
+
+func TestCreate(t *testing.T) {
+	for _, format := range []Format{Zip, Tar, TarGz, TarBz2, TarXz, TarLz4, TarSz} {
+		t.Run(string(format), func(t *testing.T) {
+			fs := romFs(t)
+			dst := "/snes/all" + format.Extension()
+			progress := &counter{}
+
+			err := Create(context.Background(), fs, []string{"/snes"}, dst, CreateOptions{
+				Format:   format,
+				Level:    1,
+				Exclude:  []string{"*.srm", ".cache"},
+				Checker:  denyChecker("/snes/hidden"),
+				Progress: progress,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"zelda.sfc": "zelda", "hacks/mario.sfc": "mario!"}
+			if got := readArchive(t, fs, dst); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+			if progress.files != 2 || progress.bytes != 11 {
+				t.Errorf("unexpected progress %+v", progress)
+			}
+			if exists, _ := afero.Exists(fs, "/snes/.all"+format.Extension()+".part"); exists {
+				t.Error("left the partial archive behind")
+			}
+		})
+	}
+}
+
+func TestCreateSelection(t *testing.T) {
+	fs := romFs(t)
+
+	err := Create(context.Background(), fs, []string{"/snes/zelda.sfc", "/snes/hacks"}, "/out.zip", CreateOptions{Format: Zip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"zelda.sfc": "zelda", "hacks/mario.sfc": "mario!"}
+	if got := readArchive(t, fs, "/out.zip"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	err = Create(context.Background(), fs, []string{"/snes/zelda.sfc"}, "/single.zip", CreateOptions{Format: Zip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readArchive(t, fs, "/single.zip"); !reflect.DeepEqual(got, map[string]string{"zelda.sfc": "zelda"}) {
+		t.Errorf("unexpected single file archive %v", got)
+	}
+}
+
+func TestCreateStore(t *testing.T) {
+	fs := romFs(t)
+
+	err := Create(context.Background(), fs, []string{"/snes/hacks"}, "/hacks.zip", CreateOptions{Format: Zip, Store: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Open("/hacks.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, _ := f.Stat()
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+		if file.Method != zip.Store {
+			t.Errorf("%s: expected stored, got method %d", file.Name, file.Method)
+		}
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"mario.sfc"}) {
+		t.Errorf("unexpected entries %v", names)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	fs := romFs(t)
+
+	for name, opts := range map[string]CreateOptions{
+		"7z":             {Format: SevenZip},
+		"stored tar":     {Format: Tar, Store: true},
+		"level":          {Format: Zip, Level: MaxLevel + 1},
+		"negative level": {Format: TarGz, Level: -1},
+	} {
+		err := Create(context.Background(), fs, []string{"/snes"}, "/out", opts)
+		if !errors.Is(err, fbErrors.ErrInvalidRequestParams) {
+			t.Errorf("%s: expected ErrInvalidRequestParams, got %v", name, err)
+		}
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	patterns := []string{"*.srm", "saves/*", "thumbs"}
+	for name, want := range map[string]bool{
+		"zelda.srm":        true,
+		"snes/zelda.srm":   true,
+		"saves/zelda.sav":  true,
+		"snes/thumbs":      true,
+		"snes/zelda.sfc":   false,
+		"snes/saves/a.sav": false,
+	} {
+		if got := Excluded(name, patterns); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// Permissions of the files and directories Extract and Create write.
+// They match files.PermFile and files.PermDir, which can't be used
+// here since the files package relies on this one to detect archives.
+const (
+	permFile = 0644
+	permDir  = 0755
+)
+
+// Progress receives the progress of an extraction.
+type Progress interface {
+	// Add is called with the number of bytes just written.
+	Add(n int64)
+	// FileDone is called after each extracted file.
+	FileDone()
+}
+
+// ExtractOptions configures Extract.
+type ExtractOptions struct {
+	// Checker, if set, decides which destination paths can be
+	// created or, if they exist, modified. Entries it rejects are
+	// skipped.
+	Checker rules.ActionChecker
+	// Override replaces existing files instead of failing.
+	Override bool
+	// Progress, if set, is kept up to date.
+	Progress Progress
+}
+
+// Measure returns the number of files in the archive at name and
+// their uncompressed size.
+func Measure(fs afero.Fs, name string) (count int, size int64, err error) {
+	err = Walk(fs, name, func(e *Entry, _ io.Reader) error {
+		if !e.IsDir && e.Mode.IsRegular() {
+			count++
+			size += e.Size
+		}
+		return nil
+	})
+	return count, size, err
+}
+
+// Extract extracts the archive at src into the directory dst, both
+// in fs. Entries with paths escaping dst fail the extraction before
+// anything is written outside of it; symlinks and other special
+// files are skipped.
+func Extract(ctx context.Context, fs afero.Fs, src, dst string, opts ExtractOptions) error {
+	dst = path.Clean("/" + dst)
+	if err := fs.MkdirAll(dst, permDir); err != nil {
+		return err
+	}
+
+	return Walk(fs, src, func(e *Entry, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.Name == "" {
+			return nil
+		}
+		if !IsSafe(e.Name) {
+			return fmt.Errorf("entry %q escapes the destination: %w", e.Name, fbErrors.ErrPermissionDenied)
+		}
+
+		target := path.Join(dst, e.Name)
+		if opts.Checker != nil {
+			action := rules.Create
+			if _, err := fs.Stat(target); err == nil {
+				action = rules.Modify
+			}
+			if !opts.Checker.CheckAction(target, action) {
+				return nil
+			}
+		}
+
+		if e.IsDir {
+			return fs.MkdirAll(target, permDir)
+		}
+		if !e.Mode.IsRegular() {
+			return nil
+		}
+
+		return extractFile(ctx, fs, e, r, target, opts)
+	})
+}
+
+func extractFile(ctx context.Context, fs afero.Fs, e *Entry, r io.Reader, target string, opts ExtractOptions) error {
+	if info, err := fs.Stat(target); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s: %w", target, fbErrors.ErrIsDirectory)
+		}
+		if !opts.Override {
+			return fmt.Errorf("%s: %w", target, fbErrors.ErrExist)
+		}
+	}
+
+	if err := fs.MkdirAll(path.Dir(target), permDir); err != nil {
+		return err
+	}
+
+	out, err := fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, permFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, &progressReader{ctx: ctx, r: r, progress: opts.Progress})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if !e.ModTime.IsZero() {
+		_ = fs.Chtimes(target, e.ModTime, e.ModTime)
+	}
+	if opts.Progress != nil {
+		opts.Progress.FileDone()
+	}
+	return nil
+}
+
+type progressReader struct {
+	ctx      context.Context
+	r        io.Reader
+	progress Progress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	if p.progress != nil && n > 0 {
+		p.progress.Add(int64(n))
+	}
+	return n, err
+}
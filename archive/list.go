@@ -0,0 +1,96 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+// List returns the entries in the directory dir of the archive at
+// name, "" or "/" being its root. Directories that have no entry of
+// their own but hold entries are listed as well.
+func List(fs afero.Fs, name, dir string) ([]*Entry, error) {
+	dir = cleanName(strings.TrimLeft(dir, "/"))
+	if dir != "" && !IsSafe(dir) {
+		return nil, fmt.Errorf("%s: %w", dir, fbErrors.ErrNotExist)
+	}
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	found := dir == ""
+	var entries []*Entry
+	dirs := map[string]*Entry{}
+	err := Walk(fs, name, func(e *Entry, _ io.Reader) error {
+		if !IsSafe(e.Name) {
+			return nil
+		}
+		if e.Name == dir {
+			found = found || e.IsDir
+			return nil
+		}
+		if !strings.HasPrefix(e.Name, prefix) {
+			return nil
+		}
+		found = true
+
+		child, rest, nested := strings.Cut(strings.TrimPrefix(e.Name, prefix), "/")
+		if !nested && !e.IsDir {
+			entries = append(entries, e)
+			return nil
+		}
+
+		p := prefix + child
+		d, ok := dirs[p]
+		if !ok {
+			d = &Entry{Name: p, Mode: os.ModeDir | permDir, IsDir: true}
+			dirs[p] = d
+			entries = append(entries, d)
+		}
+		if rest == "" {
+			// The entry of the directory itself.
+			d.ModTime, d.Mode = e.ModTime, e.Mode
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: %w", dir, fbErrors.ErrNotExist)
+	}
+
+	return entries, nil
+}
+
+// Find calls fn with the entry of the archive at name whose path is
+// entry. It fails with ErrNotExist if there's none.
+func Find(fs afero.Fs, name, entry string, fn WalkFunc) error {
+	entry = cleanName(strings.TrimLeft(entry, "/"))
+	found := false
+
+	err := Walk(fs, name, func(e *Entry, r io.Reader) error {
+		if e.Name != entry || !IsSafe(e.Name) {
+			return nil
+		}
+		found = true
+		if err := fn(e, r); err != nil {
+			return err
+		}
+		return SkipAll
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s: %w", path.Join(name, entry), fbErrors.ErrNotExist)
+	}
+	return nil
+}
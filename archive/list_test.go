@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"testing"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+func TestList(t *testing.T) {
+	fs := newFs(t, map[string][]byte{"/mame.zip": makeZip(t,
+		entry{"roms/", ""},
+		entry{"roms/pacman.zip", "pacman"},
+		entry{"roms/neogeo/kof98.zip", "kof98"},
+		entry{"snap/pacman.png", "png"},
+		entry{"readme.txt", "hi"},
+	)})
+
+	names := func(entries []*Entry) map[string]bool {
+		got := map[string]bool{}
+		for _, e := range entries {
+			got[e.Name] = e.IsDir
+		}
+		return got
+	}
+
+	root, err := List(fs, "/mame.zip", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"roms": true, "snap": true, "readme.txt": false}
+	if got := names(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("root: expected %v, got %v", want, got)
+	}
+
+	roms, err := List(fs, "/mame.zip", "/roms/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]bool{"roms/pacman.zip": false, "roms/neogeo": true}
+	if got := names(roms); !reflect.DeepEqual(got, want) {
+		t.Errorf("roms: expected %v, got %v", want, got)
+	}
+	for _, e := range roms {
+		if e.Name == "roms/pacman.zip" && (e.Size != 6 || e.CRC32 != crc32.ChecksumIEEE([]byte("pacman"))) {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+
+	for _, dir := range []string{"nope", "readme.txt", "../etc"} {
+		if _, err := List(fs, "/mame.zip", dir); !errors.Is(err, fbErrors.ErrNotExist) {
+			t.Errorf("%s: expected ErrNotExist, got %v", dir, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	fs := newFs(t, map[string][]byte{"/pack.tar.gz": makeTarGz(t,
+		entry{"snes/", ""},
+		entry{"snes/zelda.sfc", "zelda"},
+		entry{"mario.sfc", "mario!"},
+	)})
+
+	var got string
+	err := Find(fs, "/pack.tar.gz", "/snes/zelda.sfc", func(_ *Entry, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		got = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "zelda" {
+		t.Errorf("expected %q, got %q", "zelda", got)
+	}
+
+	err = Find(fs, "/pack.tar.gz", "snes/mario.sfc", func(*Entry, io.Reader) error { return nil })
+	if !errors.Is(err, fbErrors.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
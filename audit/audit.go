@@ -0,0 +1,40 @@
+package audit
+
+import "time"
+
+// MaxAge is how long entries are kept in the audit log.
+const MaxAge = 90 * 24 * time.Hour
+
+// Action is the kind of an audited event.
+type Action string
+
+const (
+	// ActionLogin is recorded when a support session logs in.
+	ActionLogin Action = "login"
+	// ActionLogout is recorded when a support session logs out.
+	ActionLogout Action = "logout"
+	// ActionRequest is recorded for every request made by a support session.
+	ActionRequest Action = "request"
+)
+
+// Entry is a single event in the audit log.
+type Entry struct {
+	ID         int       `json:"id" storm:"id,increment"`
+	Time       time.Time `json:"time" storm:"index"`
+	Session    string    `json:"session" storm:"index"`
+	Username   string    `json:"username"`
+	Action     Action    `json:"action"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Dst        string    `json:"dst,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	RemoteAddr string    `json:"remoteAddr"`
+}
+
+// Filter selects audit log entries. The zero value matches every entry.
+type Filter struct {
+	Session string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
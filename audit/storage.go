@@ -0,0 +1,38 @@
+package audit
+
+import "time"
+
+// StorageBackend is the interface to implement for an audit log storage.
+type StorageBackend interface {
+	Save(e *Entry) error
+	Find(f Filter) ([]*Entry, error)
+	DeleteBefore(t time.Time) error
+}
+
+// Storage is an audit log storage.
+type Storage struct {
+	back StorageBackend
+}
+
+// NewStorage creates an audit log storage from a backend.
+func NewStorage(back StorageBackend) *Storage {
+	return &Storage{back: back}
+}
+
+// Save wraps a StorageBackend.Save, setting the entry time if empty.
+func (s *Storage) Save(e *Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return s.back.Save(e)
+}
+
+// Find wraps a StorageBackend.Find. Entries are returned newest first.
+func (s *Storage) Find(f Filter) ([]*Entry, error) {
+	return s.back.Find(f)
+}
+
+// Prune deletes the entries older than maxAge.
+func (s *Storage) Prune(maxAge time.Duration) error {
+	return s.back.DeleteBefore(time.Now().Add(-maxAge))
+}
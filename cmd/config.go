@@ -48,6 +48,9 @@ func addConfigFlags(flags *pflag.FlagSet) {
 	flags.String("branding.files", "", "path to directory with images and custom styles")
 	flags.Bool("branding.disableExternal", false, "disable external links such as GitHub links")
 	flags.Bool("branding.disableUsedPercentage", false, "disable used disk percentage graph")
+
+	flags.String("trash.maxAge", "", "how long deleted files are kept in the trash, 0 to keep them forever (default 720h)")
+	flags.Uint64("trash.maxSize", 0, "size in bytes past which the oldest files in a user's trash are purged, 0 for no limit")
 }
 
 //nolint:gocyclo
@@ -153,6 +156,9 @@ func printSettings(ser *settings.Server, set *settings.Settings, auther auth.Aut
 	fmt.Fprintf(w, "\tDisable used disk percentage graph:\t%t\n", set.Branding.DisableUsedPercentage)
 	fmt.Fprintf(w, "\tColor:\t%s\n", set.Branding.Color)
 	fmt.Fprintf(w, "\tTheme:\t%s\n", set.Branding.Theme)
+	fmt.Fprintln(w, "\nTrash:")
+	fmt.Fprintf(w, "\tMax age:\t%s\n", set.Trash.GetMaxAge())
+	fmt.Fprintf(w, "\tMax size:\t%d\n", set.Trash.MaxSize)
 	fmt.Fprintln(w, "\nServer:")
 	fmt.Fprintf(w, "\tLog:\t%s\n", ser.Log)
 	fmt.Fprintf(w, "\tPort:\t%s\n", ser.Port)
@@ -163,12 +169,17 @@ func printSettings(ser *settings.Server, set *settings.Settings, auther auth.Aut
 	fmt.Fprintf(w, "\tTLS Cert:\t%s\n", ser.TLSCert)
 	fmt.Fprintf(w, "\tTLS Key:\t%s\n", ser.TLSKey)
 	fmt.Fprintf(w, "\tExec Enabled:\t%t\n", ser.EnableExec)
+	fmt.Fprintf(w, "\tSearch index:\t%s\n", ser.SearchIndex)
+	fmt.Fprintf(w, "\tWatch limit:\t%s\n", ser.WatchLimit)
 	fmt.Fprintln(w, "\nDefaults:")
 	fmt.Fprintf(w, "\tScope:\t%s\n", set.Defaults.Scope)
 	fmt.Fprintf(w, "\tLocale:\t%s\n", set.Defaults.Locale)
 	fmt.Fprintf(w, "\tView mode:\t%s\n", set.Defaults.ViewMode)
 	fmt.Fprintf(w, "\tSingle Click:\t%t\n", set.Defaults.SingleClick)
 	fmt.Fprintf(w, "\tCommands:\t%s\n", strings.Join(set.Defaults.Commands, " "))
+	fmt.Fprintf(w, "\tQuota:\n")
+	fmt.Fprintf(w, "\t\tBytes:\t%d\n", set.Defaults.Quota.Bytes)
+	fmt.Fprintf(w, "\t\tFiles:\t%d\n", set.Defaults.Quota.Files)
 	fmt.Fprintf(w, "\tSorting:\n")
 	fmt.Fprintf(w, "\t\tBy:\t%s\n", set.Defaults.Sorting.By)
 	fmt.Fprintf(w, "\t\tAsc:\t%t\n", set.Defaults.Sorting.Asc)
@@ -181,6 +192,10 @@ func printSettings(ser *settings.Server, set *settings.Settings, auther auth.Aut
 	fmt.Fprintf(w, "\t\tDelete:\t%t\n", set.Defaults.Perm.Delete)
 	fmt.Fprintf(w, "\t\tShare:\t%t\n", set.Defaults.Perm.Share)
 	fmt.Fprintf(w, "\t\tDownload:\t%t\n", set.Defaults.Perm.Download)
+	fmt.Fprintln(w, "\nVirtual directories:")
+	for _, dir := range set.Virtual {
+		fmt.Fprintf(w, "\t%s:\t%s\n", dir.Name, dir.Provider)
+	}
 	w.Flush()
 
 	b, err := json.MarshalIndent(auther, "", "  ")
@@ -42,17 +42,23 @@ override the options.`,
 				Theme:                 mustGetString(flags, "branding.theme"),
 				Files:                 mustGetString(flags, "branding.files"),
 			},
+			Trash: settings.Trash{
+				MaxAge:  mustGetString(flags, "trash.maxAge"),
+				MaxSize: mustGetUint64(flags, "trash.maxSize"),
+			},
 		}
 
 		ser := &settings.Server{
-			Address: mustGetString(flags, "address"),
-			Socket:  mustGetString(flags, "socket"),
-			Root:    mustGetString(flags, "root"),
-			BaseURL: mustGetString(flags, "baseurl"),
-			TLSKey:  mustGetString(flags, "key"),
-			TLSCert: mustGetString(flags, "cert"),
-			Port:    mustGetString(flags, "port"),
-			Log:     mustGetString(flags, "log"),
+			Address:     mustGetString(flags, "address"),
+			Socket:      mustGetString(flags, "socket"),
+			Root:        mustGetString(flags, "root"),
+			BaseURL:     mustGetString(flags, "baseurl"),
+			TLSKey:      mustGetString(flags, "key"),
+			TLSCert:     mustGetString(flags, "cert"),
+			Port:        mustGetString(flags, "port"),
+			Log:         mustGetString(flags, "log"),
+			SearchIndex: mustGetString(flags, "search-index"),
+			WatchLimit:  mustGetString(flags, "watch-limit"),
 		}
 
 		err := d.store.Settings.Save(s)
@@ -43,6 +43,10 @@ you want to change. Other options will remain unchanged.`,
 				ser.Port = mustGetString(flags, flag.Name)
 			case "log":
 				ser.Log = mustGetString(flags, flag.Name)
+			case "search-index":
+				ser.SearchIndex = mustGetString(flags, flag.Name)
+			case "watch-limit":
+				ser.WatchLimit = mustGetString(flags, flag.Name)
 			case "signup":
 				set.Signup = mustGetBool(flags, flag.Name)
 			case "auth.method":
@@ -63,6 +67,10 @@ you want to change. Other options will remain unchanged.`,
 				set.Branding.DisableUsedPercentage = mustGetBool(flags, flag.Name)
 			case "branding.files":
 				set.Branding.Files = mustGetString(flags, flag.Name)
+			case "trash.maxAge":
+				set.Trash.MaxAge = mustGetString(flags, flag.Name)
+			case "trash.maxSize":
+				set.Trash.MaxSize = mustGetUint64(flags, flag.Name)
 			}
 		})
 
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/dupes"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+func init() {
+	rootCmd.AddCommand(dupesCmd)
+	dupesCmd.Flags().String("algo", dupes.DefaultAlgorithm, "checksum algorithm (crc32, md5, sha1, sha256 or sha512)")
+	dupesCmd.Flags().StringSlice("ext", nil, "only look at files with these extensions")
+	dupesCmd.Flags().Int64("min-size", 0, "skip files smaller than this many bytes")
+	dupesCmd.Flags().Bool("archives", false, "compare the files inside archives too, by CRC32")
+	dupesCmd.Flags().Bool("hidden", false, "look at hidden files too")
+}
+
+// visibleChecker hides dotfiles, like users with hideDotfiles set.
+type visibleChecker struct{}
+
+func (visibleChecker) Check(path string) bool {
+	return !rules.MatchHidden(path)
+}
+
+var dupesCmd = &cobra.Command{
+	Use:   "dupes <dir>",
+	Short: "Find duplicate files",
+	Long: `Find the duplicate files of a directory. Files are compared by
+size, then by a checksum of their first bytes and then by a checksum
+of their whole content.
+
+With --archives, the files inside zip, 7z and other archives are
+compared too, by CRC32, so that a loose ROM is found to be the same
+as a zipped one.
+
+Groups are listed the ones that would free the most space first.
+It doesn't need a database.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		extensions, err := flags.GetStringSlice("ext")
+		checkErr(err)
+		minSize, err := flags.GetInt64("min-size")
+		checkErr(err)
+
+		opts := dupes.Options{
+			Algorithm:  mustGetString(flags, "algo"),
+			Extensions: extensions,
+			MinSize:    minSize,
+			Archives:   mustGetBool(flags, "archives"),
+		}
+		if !mustGetBool(flags, "hidden") {
+			opts.Checker = visibleChecker{}
+		}
+
+		dir, err := filepath.Abs(args[0])
+		checkErr(err)
+		fs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+		report, err := dupes.Find(context.Background(), fs, "/", opts)
+		checkErr(err)
+
+		printDupesReport(report)
+	},
+}
+
+func printDupesReport(report *dupes.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	for _, g := range report.Groups {
+		fmt.Fprintf(w, "%s:%s\t%d bytes\t%d reclaimable\t\n", g.Algorithm, g.Checksum, g.Size, g.Reclaimable)
+		for _, f := range g.Files {
+			name := f.Path
+			if f.Entry != "" {
+				name += "/" + f.Entry
+			}
+			fmt.Fprintf(w, "  %s\t%s\t\t\n", name, f.ModTime.Format("2006-01-02 15:04"))
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d groups of duplicates among %d files, %d bytes reclaimable\n",
+		len(report.Groups), report.Files, report.Reclaimable)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/storage"
+)
+
+func init() {
+	rootCmd.AddCommand(groupsCmd)
+}
+
+var groupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Groups management utility",
+	Long: `Groups management utility. Users in a group get its permissions
+and commands on top of their own, and its rules before their own.
+Add users to groups with the --groups flag of 'users add' and
+'users update', and rules to groups with the --group flag of 'rules'.`,
+	Args: cobra.NoArgs,
+}
+
+func printGroups(list []*groups.Group) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tName\tScope\tAdmin\tExecute\tCreate\tRename\tModify\tDelete\tShare\tDownload\tRules\tCommands")
+
+	for _, g := range list {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%d\t%s\t\n",
+			g.ID,
+			g.Name,
+			g.Scope,
+			g.Perm.Admin,
+			g.Perm.Execute,
+			g.Perm.Create,
+			g.Perm.Rename,
+			g.Perm.Modify,
+			g.Perm.Delete,
+			g.Perm.Share,
+			g.Perm.Download,
+			len(g.Rules),
+			strings.Join(g.Commands, ","),
+		)
+	}
+
+	w.Flush()
+}
+
+// mustGetGroup gets a group by its name or id.
+func mustGetGroup(st *storage.Storage, arg string) *groups.Group {
+	name, id := parseUsernameOrID(arg)
+	var (
+		group *groups.Group
+		err   error
+	)
+	if name != "" {
+		group, err = st.Groups.Get(name)
+	} else {
+		group, err = st.Groups.Get(id)
+	}
+	checkErr(err)
+	return group
+}
+
+// Groups grant permissions, so they're all off by default.
+func addGroupFlags(flags *pflag.FlagSet) {
+	flags.Bool("perm.admin", false, "admin perm for the group")
+	flags.Bool("perm.execute", false, "execute perm for the group")
+	flags.Bool("perm.create", false, "create perm for the group")
+	flags.Bool("perm.rename", false, "rename perm for the group")
+	flags.Bool("perm.modify", false, "modify perm for the group")
+	flags.Bool("perm.delete", false, "delete perm for the group")
+	flags.Bool("perm.share", false, "share perm for the group")
+	flags.Bool("perm.download", false, "download perm for the group")
+	flags.StringSlice("commands", nil, "a list of the commands the users of the group can execute")
+	flags.String("scope", "", "scope of the users created in the group without one")
+}
+
+// getGroupFlags sets the options of the group that were set with flags.
+func getGroupFlags(flags *pflag.FlagSet, g *groups.Group) {
+	flags.Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "scope":
+			g.Scope = mustGetString(flags, flag.Name)
+		case "perm.admin":
+			g.Perm.Admin = mustGetBool(flags, flag.Name)
+		case "perm.execute":
+			g.Perm.Execute = mustGetBool(flags, flag.Name)
+		case "perm.create":
+			g.Perm.Create = mustGetBool(flags, flag.Name)
+		case "perm.rename":
+			g.Perm.Rename = mustGetBool(flags, flag.Name)
+		case "perm.modify":
+			g.Perm.Modify = mustGetBool(flags, flag.Name)
+		case "perm.delete":
+			g.Perm.Delete = mustGetBool(flags, flag.Name)
+		case "perm.share":
+			g.Perm.Share = mustGetBool(flags, flag.Name)
+		case "perm.download":
+			g.Perm.Download = mustGetBool(flags, flag.Name)
+		case "commands":
+			commands, err := flags.GetStringSlice(flag.Name)
+			checkErr(err)
+			g.Commands = commands
+		}
+	})
+}
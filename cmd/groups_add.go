@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+)
+
+func init() {
+	groupsCmd.AddCommand(groupsAddCmd)
+	addGroupFlags(groupsAddCmd.Flags())
+}
+
+var groupsAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create a new group",
+	Long:  `Create a new group and add it to the database.`,
+	Args:  cobra.ExactArgs(1),
+	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
+		group := &groups.Group{Name: args[0]}
+		getGroupFlags(cmd.Flags(), group)
+
+		err := d.store.Groups.Save(group)
+		checkErr(err)
+		printGroups([]*groups.Group{group})
+	}, pythonConfig{}),
+}
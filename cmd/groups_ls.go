@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+)
+
+func init() {
+	groupsCmd.AddCommand(groupsLsCmd)
+}
+
+var groupsLsCmd = &cobra.Command{
+	Use:   "ls [id|name]",
+	Short: "List all groups, or find one by name or id",
+	Args:  cobra.MaximumNArgs(1),
+	Run: python(func(_ *cobra.Command, args []string, d pythonData) {
+		if len(args) == 1 {
+			printGroups([]*groups.Group{mustGetGroup(d.store, args[0])})
+			return
+		}
+
+		list, err := d.store.Groups.Gets()
+		checkErr(err)
+		printGroups(list)
+	}, pythonConfig{}),
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	groupsCmd.AddCommand(groupsRmCmd)
+}
+
+var groupsRmCmd = &cobra.Command{
+	Use:   "rm <id|name>",
+	Short: "Delete a group by name or id",
+	Long:  `Delete a group by name or id, and remove its users from it.`,
+	Args:  cobra.ExactArgs(1),
+	Run: python(func(_ *cobra.Command, args []string, d pythonData) {
+		group := mustGetGroup(d.store, args[0])
+		err := d.store.Groups.Delete(group.ID)
+		checkErr(err)
+		fmt.Println("group deleted successfully")
+	}, pythonConfig{}),
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+)
+
+func init() {
+	groupsCmd.AddCommand(groupsUpdateCmd)
+
+	groupsUpdateCmd.Flags().String("name", "", "new name")
+	addGroupFlags(groupsUpdateCmd.Flags())
+}
+
+var groupsUpdateCmd = &cobra.Command{
+	Use:   "update <id|name>",
+	Short: "Updates an existing group",
+	Long: `Updates an existing group. Set the flags for the
+options you want to change.`,
+	Args: cobra.ExactArgs(1),
+	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
+		group := mustGetGroup(d.store, args[0])
+		getGroupFlags(cmd.Flags(), group)
+		if name := mustGetString(cmd.Flags(), "name"); name != "" {
+			group.Name = name
+		}
+
+		err := d.store.Groups.Save(group)
+		checkErr(err)
+		printGroups([]*groups.Group{group})
+	}, pythonConfig{}),
+}
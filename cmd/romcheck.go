@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/romcheck"
+)
+
+func init() {
+	rootCmd.AddCommand(romcheckCmd)
+	romcheckCmd.Flags().Bool("all", false, "list complete games too")
+}
+
+var romcheckCmd = &cobra.Command{
+	Use:   "romcheck <dat> <dir>",
+	Short: "Verify a ROM directory against a DAT file",
+	Long: `Verify the ROM sets of a directory against a Logiqx XML or
+ClrMamePro DAT file, such as the ones of MAME or No-Intro. Games
+are looked for in zip and 7z archives or directories named after
+them, or as loose ROMs. ROMs are matched by CRC32.
+
+Every game is reported complete, missing or bad-dump, when some of
+its ROMs are missing or don't match. Files of the directory that
+belong to no game are reported unknown. Only the problems are
+listed unless --all is given.
+
+It doesn't need a database, so it can run on any computer holding
+a copy of the ROMs.`,
+	Args: cobra.ExactArgs(2), //nolint:gomnd
+	Run: func(cmd *cobra.Command, args []string) {
+		dat, err := romcheck.Load(args[0])
+		checkErr(err)
+
+		dir, err := filepath.Abs(args[1])
+		checkErr(err)
+		fs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+		report, err := romcheck.Check(context.Background(), fs, "/", dat, nil)
+		checkErr(err)
+
+		printRomcheckReport(report, mustGetBool(cmd.Flags(), "all"))
+	},
+}
+
+func printRomcheckReport(report *romcheck.Report, all bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintln(w, "Status\tGame\tPath\t")
+	for _, result := range report.Results {
+		if result.Status == romcheck.Complete && !all {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", result.Status, result.Game, result.Path)
+		for _, rom := range result.ROMs {
+			if rom.Status != romcheck.Complete {
+				fmt.Fprintf(w, "\t  %s %s\t%s\t\n", rom.Status, rom.Name, rom.Path)
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\n%s: %d complete, %d missing, %d bad-dump, %d unknown\n",
+		report.Dat, report.Complete, report.Missing, report.BadDump, report.Unknown)
+}
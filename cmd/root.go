@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"io"
@@ -65,6 +66,12 @@ func addServerFlags(flags *pflag.FlagSet) {
 	flags.StringP("baseurl", "b", "", "base url")
 	flags.String("cache-dir", "", "file cache directory (disabled if empty)")
 	flags.String("token-expiration-time", "2h", "user session timeout")
+	flags.String("support-session-timeout", "2h", "remote support session timeout")
+	flags.String("metrics-token", "", "bearer token allowed to read /metrics besides admins")
+	flags.String("metrics-address", "", "separate address to serve unauthenticated metrics on (disabled if empty)")
+	flags.String("dat-dir", "", "directory with the DAT files ROM sets are verified against (disabled if empty)")
+	flags.String("search-index", "", "search index database, kept up to date to avoid walking the root on searches (disabled if empty)")
+	flags.String("watch-limit", "", "how many directories of the root are watched for changes at most, negative to disable watching (default 4096)")
 	flags.Int("img-processors", 4, "image processors count") //nolint:gomnd
 	flags.Bool("disable-thumbnails", false, "disable image thumbnails")
 	flags.Bool("disable-preview-resize", false, "disable resize of image previews")
@@ -167,33 +174,50 @@ user created with the credentials from options "username" and "password".`,
 			checkErr(err)
 		}
 
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
 		sigc := make(chan os.Signal, 1)
 		signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
-		go cleanupHandler(listener, sigc)
+		go cleanupHandler(listener, sigc, cancel)
 
 		assetsFs, err := fs.Sub(frontend.Assets(), "dist")
 		if err != nil {
 			panic(err)
 		}
 
-		handler, err := fbhttp.NewHandler(imgSvc, fileCache, d.store, server, assetsFs)
+		handler, err := fbhttp.NewHandler(ctx, imgSvc, fileCache, d.store, server, assetsFs)
 		checkErr(err)
 
 		defer listener.Close()
 
+		if server.MetricsAddress != "" {
+			go func() {
+				log.Println("Serving metrics on", server.MetricsAddress)
+				//nolint: gosec
+				// The file server keeps running without its metrics.
+				if err := http.ListenAndServe(server.MetricsAddress, fbhttp.NewMetricsHandler()); err != nil {
+					log.Printf("Failed to serve metrics on %s: %v", server.MetricsAddress, err)
+				}
+			}()
+		}
+
 		log.Println("Listening on", listener.Addr().String())
 		//nolint: gosec
-		if err := http.Serve(listener, handler); err != nil {
+		if err := http.Serve(listener, handler); err != nil && ctx.Err() == nil {
 			log.Fatal(err)
 		}
 	}, pythonConfig{allowNoDB: true}),
 }
 
-func cleanupHandler(listener net.Listener, c chan os.Signal) { //nolint:interfacer
+// cleanupHandler stops the background work of the server and closes
+// its listener on a signal, so that serving returns and the database
+// gets closed. Interrupted jobs are resumed on the next start.
+func cleanupHandler(listener net.Listener, c chan os.Signal, stop context.CancelFunc) { //nolint:interfacer
 	sig := <-c
 	log.Printf("Caught signal %s: shutting down.", sig)
+	stop()
 	listener.Close()
-	os.Exit(0)
 }
 
 //nolint:gocyclo
@@ -266,6 +290,30 @@ func getRunParams(flags *pflag.FlagSet, st *storage.Storage) *settings.Server {
 		server.TokenExpirationTime = val
 	}
 
+	if val, set := getParamB(flags, "support-session-timeout"); set {
+		server.SupportSessionTimeout = val
+	}
+
+	if val, set := getParamB(flags, "metrics-token"); set {
+		server.MetricsToken = val
+	}
+
+	if val, set := getParamB(flags, "metrics-address"); set {
+		server.MetricsAddress = val
+	}
+
+	if val, set := getParamB(flags, "dat-dir"); set {
+		server.DatDir = val
+	}
+
+	if val, set := getParamB(flags, "search-index"); set {
+		server.SearchIndex = val
+	}
+
+	if val, set := getParamB(flags, "watch-limit"); set {
+		server.WatchLimit = val
+	}
+
 	return server
 }
 
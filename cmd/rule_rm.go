@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
@@ -17,8 +18,8 @@ func init() {
 
 var rulesRmCommand = &cobra.Command{
 	Use:   "rm <index> [index_end]",
-	Short: "Remove a global rule or user rule",
-	Long: `Remove a global rule or user rule. The provided index
+	Short: "Remove a global, group or user rule",
+	Long: `Remove a global, group or user rule. The provided index
 is the same that's printed when you run 'rules ls'. Note
 that after each removal/addition, the index of the
 commands change. So be careful when removing them after each
@@ -55,12 +56,18 @@ including 'index_end'.`,
 			checkErr(err)
 		}
 
+		group := func(g *groups.Group) {
+			g.Rules = append(g.Rules[:i], g.Rules[f+1:]...)
+			err := d.store.Groups.Save(g)
+			checkErr(err)
+		}
+
 		global := func(s *settings.Settings) {
 			s.Rules = append(s.Rules[:i], s.Rules[f+1:]...)
 			err := d.store.Settings.Save(s)
 			checkErr(err)
 		}
 
-		runRules(d.store, cmd, user, global)
+		runRules(d.store, cmd, user, group, global)
 	}, pythonConfig{}),
 }
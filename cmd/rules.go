@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/storage"
@@ -16,20 +18,32 @@ func init() {
 	rootCmd.AddCommand(rulesCmd)
 	rulesCmd.PersistentFlags().StringP("username", "u", "", "username of user to which the rules apply")
 	rulesCmd.PersistentFlags().UintP("id", "i", 0, "id of user to which the rules apply")
+	rulesCmd.PersistentFlags().String("group", "", "name or id of group to which the rules apply")
 }
 
 var rulesCmd = &cobra.Command{
 	Use:   "rules",
 	Short: "Rules management utility",
-	Long: `On each subcommand you'll have available at least two flags:
-"username" and "id". You must either set only one of them
+	Long: `On each subcommand you'll have available at least three flags:
+"username", "id" and "group". You must either set only one of them
 or none. If you set one of them, the command will apply to
-an user, otherwise it will be applied to the global set or
-rules.`,
+an user or a group, otherwise it will be applied to the global
+set or rules.`,
 	Args: cobra.NoArgs,
 }
 
-func runRules(st *storage.Storage, cmd *cobra.Command, usersFn func(*users.User), globalFn func(*settings.Settings)) {
+func runRules(st *storage.Storage, cmd *cobra.Command, usersFn func(*users.User), groupFn func(*groups.Group), globalFn func(*settings.Settings)) {
+	if name := mustGetString(cmd.Flags(), "group"); name != "" {
+		group := mustGetGroup(st, name)
+
+		if groupFn != nil {
+			groupFn(group)
+		}
+
+		printRules(group.Rules, "group "+group.Name)
+		return
+	}
+
 	id := getUserIdentifier(cmd.Flags())
 	if id != nil {
 		user, err := st.Users.Get("", id)
@@ -39,7 +53,7 @@ func runRules(st *storage.Storage, cmd *cobra.Command, usersFn func(*users.User)
 			usersFn(user)
 		}
 
-		printRules(user.Rules, id)
+		printRules(user.Rules, fmt.Sprintf("user %v", id))
 		return
 	}
 
@@ -50,7 +64,7 @@ func runRules(st *storage.Storage, cmd *cobra.Command, usersFn func(*users.User)
 		globalFn(s)
 	}
 
-	printRules(s.Rules, id)
+	printRules(s.Rules, "")
 }
 
 func getUserIdentifier(flags *pflag.FlagSet) interface{} {
@@ -66,27 +80,42 @@ func getUserIdentifier(flags *pflag.FlagSet) interface{} {
 	return nil
 }
 
-func printRules(rulez []rules.Rule, id interface{}) {
-	if id == nil {
+// printRules prints the rules of owner, or the global ones if it's
+// empty.
+func printRules(rulez []rules.Rule, owner string) {
+	if owner == "" {
 		fmt.Printf("Global Rules:\n\n")
 	} else {
-		fmt.Printf("Rules for user %v:\n\n", id)
+		fmt.Printf("Rules for %s:\n\n", owner)
 	}
 
 	for id, rule := range rulez {
 		fmt.Printf("(%d) ", id)
-		if rule.Regex {
-			if rule.Allow {
-				fmt.Printf("Allow Regex: \t%s\n", rule.Regexp.Raw)
-			} else {
-				fmt.Printf("Disallow Regex: \t%s\n", rule.Regexp.Raw)
-			}
-		} else {
-			if rule.Allow {
-				fmt.Printf("Allow Path: \t%s\n", rule.Path)
-			} else {
-				fmt.Printf("Disallow Path: \t%s\n", rule.Path)
-			}
+		printRule(rule)
+	}
+}
+
+func printRule(rule rules.Rule) {
+	kind := "Path"
+	exp := rule.Path
+	if rule.Regex {
+		kind = "Regex"
+		exp = rule.Regexp.Raw
+	} else if rule.Glob {
+		kind = "Glob"
+	}
+
+	if len(rule.Actions) > 0 {
+		actions := make([]string, len(rule.Actions))
+		for i, action := range rule.Actions {
+			actions[i] = string(action)
 		}
+		kind += " (" + strings.Join(actions, ", ") + ")"
+	}
+
+	if rule.Allow {
+		fmt.Printf("Allow %s: \t%s\n", kind, exp)
+	} else {
+		fmt.Printf("Disallow %s: \t%s\n", kind, exp)
 	}
 }
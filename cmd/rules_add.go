@@ -1,10 +1,12 @@
 package cmd
 
 import (
-	"regexp"
+	"log"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/users"
@@ -14,25 +16,41 @@ func init() {
 	rulesCmd.AddCommand(rulesAddCmd)
 	rulesAddCmd.Flags().BoolP("allow", "a", false, "indicates this is an allow rule")
 	rulesAddCmd.Flags().BoolP("regex", "r", false, "indicates this is a regex rule")
+	rulesAddCmd.Flags().BoolP("glob", "g", false, "indicates this is a glob rule, such as **/*.srm")
+	rulesAddCmd.Flags().StringSlice("actions", nil, "actions the rule allows or disallows, instead of hiding the path (read, create, modify or delete)")
+	rulesAddCmd.Flags().Bool("read-only", false, "disallow creating, modifying and deleting files (same as --actions create,modify,delete)")
+	rulesAddCmd.Flags().Bool("write-only", false, "disallow reading files but not uploading them (same as --actions read)")
 }
 
 var rulesAddCmd = &cobra.Command{
-	Use:   "add <path|expression>",
-	Short: "Add a global rule or user rule",
-	Long:  `Add a global rule or user rule.`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "add <path|expression|glob>",
+	Short: "Add a global, group or user rule",
+	Long: `Add a global, group or user rule.
+
+Rules hide the paths they match, unless they allow them. Rules with
+actions leave the paths visible and only allow or disallow these
+actions on them, such as --read-only for /boot, --actions delete for
+/roms/bios or --write-only for an /incoming upload directory.
+
+Rules match the paths starting with the given one, unless they're
+--regex rules, or --glob rules matching what's within the paths that
+match a doublestar pattern, where ** spans directories.`,
+	Args: cobra.ExactArgs(1),
 	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
 		allow := mustGetBool(cmd.Flags(), "allow")
 		regex := mustGetBool(cmd.Flags(), "regex")
+		glob := mustGetBool(cmd.Flags(), "glob")
 		exp := args[0]
 
-		if regex {
-			regexp.MustCompile(exp)
+		if regex && glob {
+			log.Fatal("only one of --regex and --glob can be set")
 		}
 
 		rule := rules.Rule{
-			Allow: allow,
-			Regex: regex,
+			Allow:   allow,
+			Regex:   regex,
+			Glob:    glob,
+			Actions: getRuleActions(cmd.Flags()),
 		}
 
 		if regex {
@@ -40,6 +58,7 @@ var rulesAddCmd = &cobra.Command{
 		} else {
 			rule.Path = exp
 		}
+		checkErr(rule.Compile())
 
 		user := func(u *users.User) {
 			u.Rules = append(u.Rules, rule)
@@ -47,12 +66,43 @@ var rulesAddCmd = &cobra.Command{
 			checkErr(err)
 		}
 
+		group := func(g *groups.Group) {
+			g.Rules = append(g.Rules, rule)
+			err := d.store.Groups.Save(g)
+			checkErr(err)
+		}
+
 		global := func(s *settings.Settings) {
 			s.Rules = append(s.Rules, rule)
 			err := d.store.Settings.Save(s)
 			checkErr(err)
 		}
 
-		runRules(d.store, cmd, user, global)
+		runRules(d.store, cmd, user, group, global)
 	}, pythonConfig{}),
 }
+
+func getRuleActions(flags *pflag.FlagSet) []rules.Action {
+	names, err := flags.GetStringSlice("actions")
+	checkErr(err)
+	readOnly := mustGetBool(flags, "read-only")
+	writeOnly := mustGetBool(flags, "write-only")
+	if (len(names) > 0 && (readOnly || writeOnly)) || (readOnly && writeOnly) {
+		log.Fatal("only one of --actions, --read-only and --write-only can be set")
+	}
+
+	switch {
+	case readOnly:
+		return []rules.Action{rules.Create, rules.Modify, rules.Delete}
+	case writeOnly:
+		return []rules.Action{rules.Read}
+	}
+
+	var actions []rules.Action
+	for _, name := range names {
+		action, err := rules.ParseAction(name)
+		checkErr(err)
+		actions = append(actions, action)
+	}
+	return actions
+}
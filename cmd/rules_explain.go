@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+func init() {
+	rulesCmd.AddCommand(rulesTestCmd)
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test <path>",
+	Short: "Show which rules match a path",
+	Long: `Show which global rules and, if a user is given, which rules of
+its groups and of the user match a path, and whether it can be seen
+and each action done on it as a result. If a group is given instead,
+its rules are shown along with the global ones.`,
+	Args: cobra.ExactArgs(1),
+	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
+		s, err := d.store.Settings.Get()
+		checkErr(err)
+
+		sources := []rules.Source{{Name: "global", Rules: s.Rules}}
+		hideDotfiles := false
+		if name := mustGetString(cmd.Flags(), "group"); name != "" {
+			group := mustGetGroup(d.store, name)
+			sources = append(sources, rules.Source{Name: "group " + group.Name, Rules: group.Rules})
+		} else if id := getUserIdentifier(cmd.Flags()); id != nil {
+			user, err := d.store.Users.Get("", id)
+			checkErr(err)
+			list, err := d.store.Groups.Of(user)
+			checkErr(err)
+			sources = groups.RuleSources(s.Rules, user, list)
+			hideDotfiles = user.HideDotfiles
+		}
+
+		path := args[0]
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		printExplanation(rules.Explain(path, hideDotfiles, sources...))
+	}, pythonConfig{}),
+}
+
+func printExplanation(e *rules.Explanation) {
+	fmt.Printf("Rules matching %s:\n\n", e.Path)
+	if len(e.Matches) == 0 {
+		fmt.Println("None")
+	}
+	for _, match := range e.Matches {
+		fmt.Printf("(%s, %d) ", match.Source, match.Index)
+		printRule(match.Rule)
+	}
+	if e.Dotfile {
+		fmt.Println("\nThe path is a dotfile, which the user hides.")
+	}
+
+	fmt.Printf("\nDecision:\n\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	printDecision := func(name string, decision rules.Decision) {
+		result := "allowed"
+		if !decision.Allow {
+			result = "denied"
+		}
+		if decision.Rule >= 0 {
+			match := e.Matches[decision.Rule]
+			result += fmt.Sprintf(" by rule %d of %s", match.Index, match.Source)
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", name, result)
+	}
+	printDecision("see", e.Visible)
+	for _, action := range rules.Actions {
+		printDecision(string(action), e.Actions[action])
+	}
+	w.Flush()
+}
@@ -10,10 +10,10 @@ func init() {
 
 var rulesLsCommand = &cobra.Command{
 	Use:   "ls",
-	Short: "List global rules or user specific rules",
-	Long:  `List global rules or user specific rules.`,
+	Short: "List global, group or user specific rules",
+	Long:  `List global, group or user specific rules.`,
 	Args:  cobra.NoArgs,
 	Run: python(func(cmd *cobra.Command, _ []string, d pythonData) {
-		runRules(d.store, cmd, nil, nil)
+		runRules(d.store, cmd, nil, nil, nil)
 	}, pythonConfig{}),
 }
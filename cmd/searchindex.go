@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/filebrowser/filebrowser/v2/searchindex"
+)
+
+func init() {
+	rootCmd.AddCommand(searchIndexCmd)
+	searchIndexCmd.AddCommand(searchIndexStatusCmd)
+	searchIndexCmd.AddCommand(searchIndexRebuildCmd)
+	searchIndexCmd.PersistentFlags().String("search-index", "", "search index database (defaults to the one of the server)")
+}
+
+var searchIndexCmd = &cobra.Command{
+	Use:   "search-index",
+	Short: "Search index management utility",
+	Long: `Search index management utility. The search index lists the
+files of the root, so that searches don't walk it every time. The
+server crawls it on start and keeps it up to date as files change.
+
+The index can't be opened while the server is running: use the
+/api/searchindex endpoint then.`,
+	Args: cobra.NoArgs,
+}
+
+var searchIndexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the search index",
+	Args:  cobra.NoArgs,
+	Run: python(func(cmd *cobra.Command, _ []string, d pythonData) {
+		idx := openSearchIndex(cmd, d)
+		defer idx.Close()
+
+		status, err := idx.Status()
+		checkErr(err)
+		printSearchIndexStatus(status)
+	}, pythonConfig{}),
+}
+
+var searchIndexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Crawl the root into the search index",
+	Long: `Crawl the root into the search index. The server crawls it
+again on start anyway, but searches use the index from the start
+if it was built since.`,
+	Args: cobra.NoArgs,
+	Run: python(func(cmd *cobra.Command, _ []string, d pythonData) {
+		idx := openSearchIndex(cmd, d)
+		defer idx.Close()
+
+		checkErr(idx.Build(context.Background()))
+		status, err := idx.Status()
+		checkErr(err)
+		printSearchIndexStatus(status)
+	}, pythonConfig{}),
+}
+
+func openSearchIndex(cmd *cobra.Command, d pythonData) *searchindex.Index {
+	ser, err := d.store.Settings.GetServer()
+	checkErr(err)
+
+	name := ser.SearchIndex
+	if val, set := getParamB(cmd.Flags(), "search-index"); set {
+		name = val
+	}
+	if name == "" {
+		log.Fatal("the search index is disabled, set it with --search-index")
+	}
+
+	idx, err := searchindex.Open(name, ser.Root)
+	if errors.Is(err, bolt.ErrTimeout) {
+		log.Fatalf("can't open %s, it's probably used by the server", name)
+	}
+	checkErr(err)
+	return idx
+}
+
+func printSearchIndexStatus(status *searchindex.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintf(w, "Root:\t%s\n", status.Root)
+	fmt.Fprintf(w, "State:\t%s\n", status.State)
+	if status.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", status.Error)
+	}
+	fmt.Fprintf(w, "Entries:\t%d\n", status.Entries)
+	if !status.BuiltAt.IsZero() {
+		fmt.Fprintf(w, "Built:\t%s in %s\n", status.BuiltAt.Local().Format("2006-01-02 15:04:05"), status.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+}
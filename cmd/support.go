@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/audit"
+)
+
+func init() {
+	rootCmd.AddCommand(supportCmd)
+}
+
+var supportCmd = &cobra.Command{
+	Use:   "support",
+	Short: "Remote support management utility",
+	Long:  `Remote support management utility.`,
+	Args:  cobra.NoArgs,
+}
+
+func printAuditEntries(entries []*audit.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Time\tSession\tUsername\tAction\tMethod\tPath\tDestination\tStatus\tRemote Address")
+
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n",
+			e.Time.Format("2006-01-02 15:04:05"),
+			e.Session,
+			e.Username,
+			e.Action,
+			e.Method,
+			e.Path,
+			e.Dst,
+			e.Status,
+			e.RemoteAddr,
+		)
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/audit"
+)
+
+func init() {
+	supportCmd.AddCommand(supportAuditCmd)
+	supportAuditCmd.Flags().String("session", "", "only show entries of this support session")
+	supportAuditCmd.Flags().Duration("since", 0, "only show entries newer than this duration (e.g. 24h)")
+	supportAuditCmd.Flags().Uint("limit", 100, "maximum number of entries to show, 0 for all") //nolint:gomnd
+}
+
+var supportAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the support sessions audit log",
+	Long: `Show the support sessions audit log, newest entries first.
+Every login, request and logout made by a support session is
+recorded in it.`,
+	Args: cobra.NoArgs,
+	Run: python(func(cmd *cobra.Command, _ []string, d pythonData) {
+		flags := cmd.Flags()
+		filter := audit.Filter{
+			Session: mustGetString(flags, "session"),
+			Limit:   int(mustGetUint(flags, "limit")),
+		}
+
+		since, err := flags.GetDuration("since")
+		checkErr(err)
+		if since > 0 {
+			filter.Since = time.Now().Add(-since)
+		}
+
+		entries, err := d.store.Audit.Find(filter)
+		checkErr(err)
+		printAuditEntries(entries)
+	}, pythonConfig{}),
+}
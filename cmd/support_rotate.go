@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	supportCmd.AddCommand(supportRotateSecretCmd)
+}
+
+var supportRotateSecretCmd = &cobra.Command{
+	Use:   "rotate-secret",
+	Short: "Generate a new TOTP secret for support logins",
+	Long: `Generate a new TOTP secret for support logins. The previous
+secret stops working immediately, so the authenticator used by
+support technicians must be enrolled again with the printed key.`,
+	Args: cobra.NoArgs,
+	Run: python(func(_ *cobra.Command, _ []string, d pythonData) {
+		key, err := d.store.Settings.RotateSupportSecret()
+		checkErr(err)
+
+		fmt.Printf("Secret: %s\n", key.Secret())
+		fmt.Printf("URL:    %s\n", key.URL())
+	}, pythonConfig{}),
+}
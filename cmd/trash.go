@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/trash"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Trash management utility",
+	Long: `Trash management utility. Files deleted by users are moved
+into a per-user trash, from where they can be restored until
+they're purged.`,
+	Args: cobra.NoArgs,
+}
+
+// getTrashUser returns the user identified by arg, with its
+// filesystem rooted at the server root.
+func getTrashUser(d pythonData, arg string) *users.User {
+	ser, err := d.store.Settings.GetServer()
+	checkErr(err)
+
+	var user *users.User
+	username, id := parseUsernameOrID(arg)
+	if username != "" {
+		user, err = d.store.Users.Get(ser.Root, username)
+	} else {
+		user, err = d.store.Users.Get(ser.Root, id)
+	}
+	checkErr(err)
+	return user
+}
+
+func getTrashBin(d pythonData, arg string) *trash.Bin {
+	user := getTrashUser(d, arg)
+	return trash.NewForUser(user.Fs, user.ID)
+}
+
+func printTrashItems(items []*trash.Item) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDeleted\tSize\tDir\tPath")
+
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%t\t%s\t\n",
+			item.ID,
+			item.DeletedAt.Local().Format("2006-01-02 15:04:05"),
+			item.Size,
+			item.IsDir,
+			item.Path,
+		)
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	trashCmd.AddCommand(trashLsCmd)
+}
+
+var trashLsCmd = &cobra.Command{
+	Use:   "ls <id|username>",
+	Short: "List the trash of a user",
+	Long:  `List the trash of a user, most recently deleted items first.`,
+	Args:  cobra.ExactArgs(1),
+	Run: python(func(_ *cobra.Command, args []string, d pythonData) {
+		items, err := getTrashBin(d, args[0]).List()
+		checkErr(err)
+		printTrashItems(items)
+	}, pythonConfig{}),
+}
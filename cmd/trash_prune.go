@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/filebrowser/filebrowser/v2/trash"
+)
+
+func init() {
+	trashCmd.AddCommand(trashPruneCmd)
+}
+
+var trashPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Purge expired items from every trash",
+	Long: `Purge the items that exceed the trash.maxAge and trash.maxSize
+settings from the trash of every user. The server does this by
+itself every hour.`,
+	Args: cobra.NoArgs,
+	Run: python(func(_ *cobra.Command, _ []string, d pythonData) {
+		set, err := d.store.Settings.Get()
+		checkErr(err)
+		ser, err := d.store.Settings.GetServer()
+		checkErr(err)
+		list, err := d.store.Users.Gets(ser.Root)
+		checkErr(err)
+
+		for _, u := range list {
+			pruned, err := trash.NewForUser(u.Fs, u.ID).Prune(set.Trash.GetMaxAge(), int64(set.Trash.MaxSize)) //nolint:gosec
+			checkErr(err)
+			for _, item := range pruned {
+				fmt.Printf("%s: purged %s (%s)\n", u.Username, item.Path, item.ID)
+			}
+		}
+	}, pythonConfig{}),
+}
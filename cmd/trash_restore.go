@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashRestoreCmd.Flags().String("to", "", "restore to this path instead of the original one")
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <id|username> <item>",
+	Short: "Restore an item from the trash of a user",
+	Long: `Restore an item from the trash of a user to the path it was
+deleted from, or to the path given by --to. Existing files
+are never replaced.`,
+	Args: cobra.ExactArgs(2), //nolint:gomnd
+	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
+		item, err := getTrashBin(d, args[0]).Restore(args[1], mustGetString(cmd.Flags(), "to"))
+		checkErr(err)
+		fmt.Printf("restored to %s\n", item.Path)
+	}, pythonConfig{}),
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	trashCmd.AddCommand(trashRmCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+}
+
+var trashRmCmd = &cobra.Command{
+	Use:   "rm <id|username> <item>...",
+	Short: "Permanently delete items from the trash of a user",
+	Long:  `Permanently delete items from the trash of a user.`,
+	Args:  cobra.MinimumNArgs(2), //nolint:gomnd
+	Run: python(func(_ *cobra.Command, args []string, d pythonData) {
+		bin := getTrashBin(d, args[0])
+		for _, id := range args[1:] {
+			checkErr(bin.Purge(id))
+		}
+		fmt.Println("items deleted successfully")
+	}, pythonConfig{}),
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty <id|username>",
+	Short: "Permanently delete everything in the trash of a user",
+	Long:  `Permanently delete everything in the trash of a user.`,
+	Args:  cobra.ExactArgs(1),
+	Run: python(func(_ *cobra.Command, args []string, d pythonData) {
+		checkErr(getTrashBin(d, args[0]).Empty())
+		fmt.Println("trash emptied successfully")
+	}, pythonConfig{}),
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/storage"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
@@ -27,10 +30,15 @@ var usersCmd = &cobra.Command{
 
 func printUsers(usrs []*users.User) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tUsername\tScope\tLocale\tV. Mode\tS.Click\tAdmin\tExecute\tCreate\tRename\tModify\tDelete\tShare\tDownload\tPwd Lock")
+	fmt.Fprintln(w, "ID\tUsername\tScope\tLocale\tV. Mode\tS.Click\tAdmin\tExecute\tCreate\tRename\tModify\tDelete\tShare\tDownload\tPwd Lock\tGroups")
 
 	for _, u := range usrs {
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t\n",
+		ids := make([]string, len(u.Groups))
+		for i, id := range u.Groups {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%t\t%s\t\n",
 			u.ID,
 			u.Username,
 			u.Scope,
@@ -46,6 +54,7 @@ func printUsers(usrs []*users.User) {
 			u.Perm.Share,
 			u.Perm.Download,
 			u.LockPassword,
+			strings.Join(ids, ","),
 		)
 	}
 
@@ -77,6 +86,32 @@ func addUserFlags(flags *pflag.FlagSet) {
 	flags.String("locale", "en", "locale for users")
 	flags.String("viewMode", string(users.ListViewMode), "view mode for users")
 	flags.Bool("singleClick", false, "use single clicks only")
+	flags.Int64("quota.bytes", 0, "bytes users can store in their scope, 0 for no limit")
+	flags.Int64("quota.files", 0, "files users can store in their scope, 0 for no limit")
+}
+
+// getUserGroups returns the groups set with the groups flag, and
+// whether it was set.
+func getUserGroups(flags *pflag.FlagSet, st *storage.Storage) ([]*groups.Group, bool) {
+	if !flags.Changed("groups") {
+		return nil, false
+	}
+
+	names, err := flags.GetStringSlice("groups")
+	checkErr(err)
+	list := []*groups.Group{}
+	for _, name := range names {
+		list = append(list, mustGetGroup(st, name))
+	}
+	return list, true
+}
+
+func groupIDs(list []*groups.Group) []uint {
+	ids := make([]uint, len(list))
+	for i, g := range list {
+		ids[i] = g.ID
+	}
+	return ids
 }
 
 func getViewMode(flags *pflag.FlagSet) users.ViewMode {
@@ -123,6 +158,10 @@ func getUserDefaults(flags *pflag.FlagSet, defaults *settings.UserDefaults, all
 			defaults.Sorting.By = mustGetString(flags, flag.Name)
 		case "sorting.asc":
 			defaults.Sorting.Asc = mustGetBool(flags, flag.Name)
+		case "quota.bytes":
+			defaults.Quota.Bytes = mustGetInt64(flags, flag.Name)
+		case "quota.files":
+			defaults.Quota.Files = mustGetInt64(flags, flag.Name)
 		}
 	}
 
@@ -3,19 +3,22 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
 func init() {
 	usersCmd.AddCommand(usersAddCmd)
 	addUserFlags(usersAddCmd.Flags())
+	usersAddCmd.Flags().StringSlice("groups", nil, "names or ids of the groups of the user")
 }
 
 var usersAddCmd = &cobra.Command{
 	Use:   "add <username> <password>",
 	Short: "Create a new user",
-	Long:  `Create a new user and add it to the database.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Create a new user and add it to the database. Users get the
+scope of their groups unless --scope is set.`,
+	Args: cobra.ExactArgs(2),
 	Run: python(func(cmd *cobra.Command, args []string, d pythonData) {
 		s, err := d.store.Settings.Get()
 		checkErr(err)
@@ -40,6 +43,13 @@ var usersAddCmd = &cobra.Command{
 		s2, err := d.store.Settings.Get()
 		checkErr(err)
 
+		if list, ok := getUserGroups(cmd.Flags(), d.store); ok {
+			user.Groups = groupIDs(list)
+			if scope := groups.Scope(list); scope != "" && !cmd.Flags().Changed("scope") {
+				user.Scope = scope
+			}
+		}
+
 		userHome, err := s2.MakeUserDir(user.Username, user.Scope, servSettings.Root)
 		checkErr(err)
 		user.Scope = userHome
@@ -13,6 +13,7 @@ func init() {
 	usersUpdateCmd.Flags().StringP("password", "p", "", "new password")
 	usersUpdateCmd.Flags().StringP("username", "u", "", "new username")
 	addUserFlags(usersUpdateCmd.Flags())
+	usersUpdateCmd.Flags().StringSlice("groups", nil, "names or ids of the groups of the user")
 }
 
 var usersUpdateCmd = &cobra.Command{
@@ -48,6 +49,7 @@ options you want to change.`,
 			Perm:        user.Perm,
 			Sorting:     user.Sorting,
 			Commands:    user.Commands,
+			Quota:       user.Quota,
 		}
 		getUserDefaults(flags, &defaults, false)
 		user.Scope = defaults.Scope
@@ -57,8 +59,13 @@ options you want to change.`,
 		user.Perm = defaults.Perm
 		user.Commands = defaults.Commands
 		user.Sorting = defaults.Sorting
+		user.Quota = defaults.Quota
 		user.LockPassword = mustGetBool(flags, "lockPassword")
 
+		if list, ok := getUserGroups(flags, d.store); ok {
+			user.Groups = groupIDs(list)
+		}
+
 		if newUsername != "" {
 			user.Username = newUsername
 		}
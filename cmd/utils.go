@@ -43,6 +43,18 @@ func mustGetUint(flags *pflag.FlagSet, flag string) uint {
 	return b
 }
 
+func mustGetUint64(flags *pflag.FlagSet, flag string) uint64 {
+	b, err := flags.GetUint64(flag)
+	checkErr(err)
+	return b
+}
+
+func mustGetInt64(flags *pflag.FlagSet, flag string) int64 {
+	b, err := flags.GetInt64(flag)
+	checkErr(err)
+	return b
+}
+
 func generateKey() []byte {
 	k, err := settings.GenerateKey()
 	checkErr(err)
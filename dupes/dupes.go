@@ -0,0 +1,372 @@
+// Package dupes finds duplicate files. Files are grouped by size,
+// then by a checksum of their first bytes and only then by a checksum
+// of their whole content, so that most files are never read in full.
+package dupes
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// DefaultAlgorithm is the checksum used when Options.Algorithm isn't
+// set.
+const DefaultAlgorithm = "sha256"
+
+// partialSize is the number of bytes of the partial checksum.
+const partialSize = 64 * 1024
+
+// Options configures Find.
+type Options struct {
+	// Algorithm is one of the algorithms of files.FileInfo.Checksum.
+	Algorithm string
+	// Extensions, if set, restricts the search to the files with one
+	// of these extensions, such as ".sfc".
+	Extensions []string
+	// MinSize skips the files smaller than it. Empty files are always
+	// skipped.
+	MinSize int64
+	// Archives compares the files inside zip, 7z and other archives
+	// too. Their content is compared by CRC32, which is what zip and
+	// 7z archives store.
+	Archives bool
+	// Checker, if set, decides which files are looked at.
+	Checker rules.Checker
+}
+
+// File is a copy of a duplicated file.
+type File struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"modified"`
+	// Entry is the name of the file inside the archive at Path, if
+	// it's in one.
+	Entry string `json:"entry,omitempty"`
+}
+
+// Group is a set of files with the same content.
+type Group struct {
+	Size      int64   `json:"size"`
+	Algorithm string  `json:"algorithm"`
+	Checksum  string  `json:"checksum"`
+	Files     []*File `json:"files"`
+	// Reclaimable is the size of all the copies but one. Copies inside
+	// archives can't be removed on their own, so they're kept over the
+	// others and not counted.
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// Report lists the duplicates of a directory, the groups that would
+// free the most space first.
+type Report struct {
+	Path        string   `json:"path"`
+	Files       int      `json:"files"`
+	Reclaimable int64    `json:"reclaimable"`
+	Groups      []*Group `json:"groups"`
+}
+
+// candidate is a file, or a file inside an archive, that may have
+// duplicates.
+type candidate struct {
+	File
+	size int64
+	// crc is the CRC32 stored in the archive for entries.
+	crc uint32
+}
+
+func (c *candidate) inArchive() bool {
+	return c.Entry != ""
+}
+
+type finder struct {
+	ctx  context.Context
+	fs   afero.Fs
+	opts Options
+	exts map[string]bool
+}
+
+// Find looks for duplicate files in the directory dir of fs.
+func Find(ctx context.Context, fs afero.Fs, dir string, opts Options) (*Report, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = DefaultAlgorithm
+	}
+	if _, err := files.NewHash(opts.Algorithm); err != nil {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q: %w", opts.Algorithm, err)
+	}
+
+	f := &finder{ctx: ctx, fs: fs, opts: opts}
+	if len(opts.Extensions) > 0 {
+		f.exts = map[string]bool{}
+		for _, ext := range opts.Extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			f.exts[ext] = true
+		}
+	}
+
+	dir = path.Clean("/" + dir)
+	candidates, err := f.scan(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Path: dir, Files: len(candidates), Groups: []*Group{}}
+	for size, same := range groupBySize(candidates) {
+		groups, err := f.compare(size, same)
+		if err != nil {
+			return nil, err
+		}
+		report.Groups = append(report.Groups, groups...)
+	}
+
+	for _, g := range report.Groups {
+		sort.Slice(g.Files, func(i, j int) bool {
+			if g.Files[i].Path != g.Files[j].Path {
+				return g.Files[i].Path < g.Files[j].Path
+			}
+			return g.Files[i].Entry < g.Files[j].Entry
+		})
+		report.Reclaimable += g.Reclaimable
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Reclaimable != b.Reclaimable {
+			return a.Reclaimable > b.Reclaimable
+		}
+		return a.Files[0].Path < b.Files[0].Path
+	})
+
+	return report, nil
+}
+
+func (f *finder) matches(name string) bool {
+	return f.exts == nil || f.exts[strings.ToLower(path.Ext(name))]
+}
+
+// scan lists the files of dir, and of the archives in it, that may
+// have duplicates.
+func (f *finder) scan(dir string) ([]*candidate, error) {
+	var candidates []*candidate
+	err := afero.Walk(f.fs, dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := f.ctx.Err(); err != nil {
+			return err
+		}
+		if f.opts.Checker != nil && !f.opts.Checker.Check(p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if f.opts.Archives && archive.IsArchive(p) {
+			entries, err := f.scanArchive(p, info)
+			if err != nil {
+				return err
+			}
+			candidates = append(candidates, entries...)
+		}
+
+		if info.Size() > 0 && info.Size() >= f.opts.MinSize && f.matches(p) {
+			candidates = append(candidates, &candidate{
+				File: File{Path: p, ModTime: info.ModTime()},
+				size: info.Size(),
+			})
+		}
+		return nil
+	})
+	return candidates, err
+}
+
+// scanArchive lists the files inside the archive at p. Broken
+// archives are ignored.
+func (f *finder) scanArchive(p string, info os.FileInfo) ([]*candidate, error) {
+	var entries []*candidate
+	err := archive.Walk(f.fs, p, func(e *archive.Entry, r io.Reader) error {
+		if err := f.ctx.Err(); err != nil {
+			return err
+		}
+		if e.IsDir || !e.Mode.IsRegular() || e.Size == 0 || e.Size < f.opts.MinSize || !f.matches(e.Name) {
+			return nil
+		}
+
+		sum := e.CRC32
+		if sum == 0 {
+			// Only zip and 7z archives store checksums.
+			h := crc32.NewIEEE()
+			if _, err := io.Copy(h, r); err != nil {
+				return err
+			}
+			sum = h.Sum32()
+		}
+
+		modTime := e.ModTime
+		if modTime.IsZero() {
+			modTime = info.ModTime()
+		}
+		entries = append(entries, &candidate{
+			File: File{Path: p, ModTime: modTime, Entry: e.Name},
+			size: e.Size,
+			crc:  sum,
+		})
+		return nil
+	})
+	if err != nil && f.ctx.Err() != nil {
+		return nil, f.ctx.Err()
+	}
+	if err != nil {
+		return nil, nil
+	}
+	return entries, nil
+}
+
+func groupBySize(candidates []*candidate) map[int64][]*candidate {
+	bySize := map[int64][]*candidate{}
+	for _, c := range candidates {
+		bySize[c.size] = append(bySize[c.size], c)
+	}
+	for size, same := range bySize {
+		if len(same) < 2 { //nolint:gomnd
+			delete(bySize, size)
+		}
+	}
+	return bySize
+}
+
+// compare groups files of the same size by content.
+func (f *finder) compare(size int64, same []*candidate) ([]*Group, error) {
+	for _, c := range same {
+		if c.inArchive() {
+			return f.compareCRC(size, same)
+		}
+	}
+
+	// Files that fit in the partial checksum need no other.
+	algo := f.opts.Algorithm
+	byPartial, err := f.groupBy(same, func(c *candidate) (string, error) {
+		return f.checksum(c.Path, algo, partialSize)
+	})
+	if err != nil || size <= partialSize {
+		return newGroups(size, algo, byPartial), err
+	}
+
+	var groups []*Group
+	for _, partial := range byPartial {
+		byFull, err := f.groupBy(partial, func(c *candidate) (string, error) {
+			file := &files.FileInfo{Fs: f.fs, Path: c.Path}
+			if err := file.Checksum(algo); err != nil {
+				return "", err
+			}
+			return file.Checksums[algo], nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, newGroups(size, algo, byFull)...)
+	}
+	return groups, nil
+}
+
+// compareCRC groups files of the same size, some of them inside
+// archives, by CRC32.
+func (f *finder) compareCRC(size int64, same []*candidate) ([]*Group, error) {
+	byCRC, err := f.groupBy(same, func(c *candidate) (string, error) {
+		if c.inArchive() {
+			return fmt.Sprintf("%08x", c.crc), nil
+		}
+		return f.checksum(c.Path, "crc32", -1)
+	})
+	return newGroups(size, "crc32", byCRC), err
+}
+
+// groupBy splits candidates by key. Files that vanished since they
+// were listed are left out.
+func (f *finder) groupBy(candidates []*candidate, key func(c *candidate) (string, error)) (map[string][]*candidate, error) {
+	groups := map[string][]*candidate{}
+	for _, c := range candidates {
+		if err := f.ctx.Err(); err != nil {
+			return nil, err
+		}
+		k, err := key(c)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		groups[k] = append(groups[k], c)
+	}
+	return groups, nil
+}
+
+// checksum returns the checksum of the first limit bytes of the file
+// at name, or of all of it if limit is negative.
+func (f *finder) checksum(name, algo string, limit int64) (string, error) {
+	h, err := files.NewHash(algo)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if limit >= 0 {
+		r = io.LimitReader(file, limit)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newGroups(size int64, algo string, byChecksum map[string][]*candidate) []*Group {
+	var groups []*Group
+	for sum, same := range byChecksum {
+		if len(same) < 2 { //nolint:gomnd
+			continue
+		}
+
+		g := &Group{Size: size, Algorithm: algo, Checksum: sum}
+		loose, archived := 0, 0
+		for _, c := range same {
+			file := c.File
+			g.Files = append(g.Files, &file)
+			if c.inArchive() {
+				archived++
+			} else {
+				loose++
+			}
+		}
+		if archived > 0 {
+			g.Reclaimable = int64(loose) * size
+		} else {
+			g.Reclaimable = int64(loose-1) * size
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
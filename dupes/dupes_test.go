@@ -0,0 +1,129 @@
+package dupes
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+type denyPrefix string
+
+func (p denyPrefix) Check(path string) bool { return !strings.HasPrefix(path, string(p)) }
+
+func writeFile(t *testing.T, fs afero.Fs, name, content string) {
+	t.Helper()
+	if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, fs afero.Fs, name string, entries map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for entry, content := range entries {
+		w, err := zw.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, name, buf.String())
+}
+
+func paths(g *Group) []string {
+	var list []string
+	for _, f := range g.Files {
+		p := f.Path
+		if f.Entry != "" {
+			p += "!" + f.Entry
+		}
+		list = append(list, p)
+	}
+	return list
+}
+
+func TestFind(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	big := strings.Repeat("x", partialSize)
+	writeFile(t, fs, "/roms/snes/zelda.sfc", "zelda")
+	writeFile(t, fs, "/roms/backup/zelda.sfc", "zelda")
+	writeFile(t, fs, "/roms/backup/zelda copy.sfc", "zelda")
+	writeFile(t, fs, "/roms/snes/mario.sfc", "mario")
+	// Same size and first bytes, different content.
+	writeFile(t, fs, "/video/a.mkv", big+"a")
+	writeFile(t, fs, "/video/b.mkv", big+"b")
+	writeFile(t, fs, "/video/c.mkv", big+"a")
+	writeFile(t, fs, "/roms/.trash/zelda.sfc", "zelda")
+	writeFile(t, fs, "/roms/private/zelda.sfc", "zelda")
+	writeFile(t, fs, "/empty1", "")
+	writeFile(t, fs, "/empty2", "")
+	writeZip(t, fs, "/roms/zips/mario.zip", map[string]string{"mario.sfc": "mario"})
+
+	report, err := Find(context.Background(), fs, "/", Options{Checker: denyPrefix("/roms/private")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("unexpected groups %+v", report.Groups)
+	}
+	video := report.Groups[0]
+	if video.Size != partialSize+1 || video.Reclaimable != partialSize+1 || strings.Join(paths(video), ",") != "/video/a.mkv,/video/c.mkv" {
+		t.Errorf("unexpected group %v %+v", paths(video), video)
+	}
+	zelda := report.Groups[1]
+	if zelda.Reclaimable != 15 || zelda.Algorithm != DefaultAlgorithm || len(zelda.Files) != 4 {
+		t.Errorf("unexpected group %v %+v", paths(zelda), zelda)
+	}
+	if report.Reclaimable != partialSize+16 {
+		t.Errorf("unexpected reclaimable bytes %d", report.Reclaimable)
+	}
+
+	report, err = Find(context.Background(), fs, "/roms", Options{
+		Algorithm:  "md5",
+		Extensions: []string{"SFC"},
+		Archives:   true,
+		Checker:    denyPrefix("/roms/."),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("unexpected groups %+v", report.Groups)
+	}
+	for _, g := range report.Groups {
+		if g.Algorithm != "crc32" {
+			t.Errorf("expected groups with archives to be compared by CRC32, got %s", g.Algorithm)
+		}
+	}
+	mario := report.Groups[1]
+	if strings.Join(paths(mario), ",") != "/roms/snes/mario.sfc,/roms/zips/mario.zip!mario.sfc" || mario.Reclaimable != 5 || mario.Checksum != "1a5dc49e" {
+		t.Errorf("unexpected group %v %+v", paths(mario), mario)
+	}
+
+	// Only the zip is big enough.
+	report, err = Find(context.Background(), fs, "/roms", Options{MinSize: 6})
+	if err != nil || len(report.Groups) != 0 || report.Files != 1 {
+		t.Errorf("expected small files to be skipped, got %+v %v", report, err)
+	}
+
+	if _, err := Find(context.Background(), fs, "/", Options{Algorithm: "crc64"}); !errors.Is(err, fbErrors.ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+}
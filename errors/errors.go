@@ -18,4 +18,7 @@ var (
 	ErrInvalidRequestParams = errors.New("invalid request params")
 	ErrSourceIsParent       = errors.New("source is parent")
 	ErrRootUserDeletion     = errors.New("user with id 1 can't be deleted")
+	ErrEmptyGroupName       = errors.New("group name is empty")
+	ErrQuotaExceeded        = errors.New("storage quota exceeded")
+	ErrOtherDevice          = errors.New("the file is on another device")
 )
@@ -0,0 +1,139 @@
+// Package events broadcasts filesystem changes to the connected clients.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type is the kind of change an event describes.
+type Type string
+
+const (
+	Create Type = "create"
+	Modify Type = "modify"
+	Delete Type = "delete"
+	Rename Type = "rename"
+)
+
+// Source tells where an event comes from.
+type Source string
+
+const (
+	// SourceServer events are published by File Browser's own handlers.
+	SourceServer Source = "server"
+	// SourceWatcher events are noticed by watching the filesystem.
+	SourceWatcher Source = "watcher"
+)
+
+// Event is a change in the filesystem. Paths are absolute paths
+// on the host. Dst is only set for renames and copies, when known.
+type Event struct {
+	Type   Type      `json:"type"`
+	Path   string    `json:"path"`
+	Dst    string    `json:"dst,omitempty"`
+	IsDir  bool      `json:"isDir"`
+	Source Source    `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// DefaultBuffer is the number of events buffered for each subscriber.
+const DefaultBuffer = 256
+
+// Hub broadcasts events to its subscribers.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewHub creates a Hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the events published to a Hub.
+type Subscription struct {
+	hub *Hub
+	c   chan Event
+
+	mu      sync.Mutex
+	dropped int
+	closed  bool
+}
+
+// Subscribe creates a subscription buffering up to buffer events.
+// The subscription must be closed once it isn't needed anymore.
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	s := &Subscription{hub: h, c: make(chan Event, buffer)}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// Publish sends e to every subscriber. Subscribers that don't keep
+// up lose the events that don't fit in their buffer.
+func (h *Hub) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subs {
+		s.send(e)
+	}
+}
+
+// Events returns the channel the events are delivered on. It's
+// closed when the subscription is.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Dropped returns and resets the number of events lost since the
+// last call because the subscriber didn't keep up.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+// Close unsubscribes from the hub.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	delete(s.hub.subs, s)
+	s.hub.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+func (s *Subscription) send(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.c <- e:
+	default:
+		s.dropped++
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestHubDropsEventsOfSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1)
+
+	hub.Publish(Event{Type: Create, Path: "/a"})
+	hub.Publish(Event{Type: Create, Path: "/b"})
+
+	if e := <-sub.Events(); e.Path != "/a" || e.Time.IsZero() {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if n := sub.Dropped(); n != 1 {
+		t.Errorf("expected 1 dropped event, got %d", n)
+	}
+
+	sub.Close()
+	hub.Publish(Event{Type: Create, Path: "/c"})
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected the events channel to be closed")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHub()
+	sub := hub.Subscribe(DefaultBuffer)
+	defer sub.Close()
+
+	if err := Watch(ctx, root, hub, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(typ Type, path string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case e := <-sub.Events():
+				if e.Type == typ && e.Path == path {
+					if e.Source != SourceWatcher {
+						t.Errorf("unexpected source %q", e.Source)
+					}
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %s %s", typ, path)
+			}
+		}
+	}
+
+	dir := filepath.Join(root, "roms")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	expect(Create, dir)
+
+	// Files in new directories are noticed too.
+	file := filepath.Join(dir, "game.zip")
+	if err := os.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expect(Create, file)
+	expect(Modify, file)
+
+	renamed := filepath.Join(dir, "renamed.zip")
+	if err := os.Rename(file, renamed); err != nil {
+		t.Fatal(err)
+	}
+	expect(Rename, file)
+	expect(Create, renamed)
+
+	if err := os.Remove(renamed); err != nil {
+		t.Fatal(err)
+	}
+	expect(Delete, renamed)
+}
+
+func TestWatchLimit(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/1", "a/2", "b/1"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsw.Close()
+
+	w := &watcher{fs: fsw, hub: NewHub(), limit: 3, pending: map[string]*time.Timer{}}
+	if err := w.addTree(context.Background(), root, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fsw.WatchList()); n != 3 {
+		t.Errorf("expected 3 watched directories, got %d", n)
+	}
+
+	// Nothing is watched once the context is done.
+	_ = fsw.Remove(root)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.addTree(ctx, filepath.Join(root, "b"), false); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fsw.WatchList()); n != 2 {
+		t.Errorf("expected 2 watched directories, got %d", n)
+	}
+}
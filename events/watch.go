@@ -0,0 +1,227 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long consecutive writes to a file are
+// coalesced into a single modify event.
+const DefaultDebounce = 250 * time.Millisecond
+
+// DefaultLimit is how many directories are watched at most when no
+// limit is given. Every directory takes an inotify watch, and large
+// libraries on USB drives can hold more than the system allows.
+const DefaultLimit = 4096
+
+type watcher struct {
+	fs       *fsnotify.Watcher
+	hub      *Hub
+	debounce time.Duration
+	limit    int
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+// Watch watches root and its subdirectories, publishing their changes
+// to hub until ctx is done. Renames are published with the old path
+// only: the new one comes in a separate create event.
+//
+// At most limit directories are watched, DefaultLimit if it's zero;
+// changes in the others aren't noticed. Only root is watched before
+// Watch returns: its subdirectories are added in the background, and
+// changes in those that weren't added yet aren't noticed either.
+func Watch(ctx context.Context, root string, hub *Hub, limit int) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	w := &watcher{
+		fs:       fsw,
+		hub:      hub,
+		debounce: DefaultDebounce,
+		limit:    limit,
+		pending:  map[string]*time.Timer{},
+	}
+
+	if err := fsw.Add(root); err != nil {
+		fsw.Close()
+		return err
+	}
+
+	go w.run(ctx)
+	// Adding root again is harmless: it's walked for its
+	// subdirectories.
+	go w.addTree(ctx, root, false) //nolint:errcheck
+	return nil
+}
+
+// addTree watches dir and all of its subdirectories. Directories
+// that can't be watched are skipped. If announce is set, create events
+// are published for the entries inside dir, since they may have been
+// created before it was watched. The walk stops when ctx is done.
+func (w *watcher) addTree(ctx context.Context, dir string, announce bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+
+		if announce && path != dir {
+			w.publish(Event{Type: Create, Path: path, IsDir: d.IsDir()})
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		return w.add(path)
+	})
+}
+
+// add watches path. It returns filepath.SkipDir if path can't be
+// watched and filepath.SkipAll if nothing else can be either.
+func (w *watcher) add(path string) error {
+	// Directories that are removed drop their watches by themselves,
+	// so they are counted rather than tracked.
+	if len(w.fs.WatchList()) >= w.limit {
+		log.Printf("events: watching %d directories already, %s and the directories after it won't be watched", w.limit, path)
+		return filepath.SkipAll
+	}
+
+	err := w.fs.Add(path)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fsnotify.ErrClosed):
+		return filepath.SkipAll
+	case errors.Is(err, syscall.ENOSPC):
+		log.Printf("events: watch limit reached, %s and the directories after it won't be watched", path)
+		return filepath.SkipAll
+	}
+	log.Printf("events: can't watch %s: %v", path, err)
+	return filepath.SkipDir
+}
+
+func (w *watcher) run(ctx context.Context) {
+	defer w.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("events: watcher error: %v", err)
+		case e, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.handle(ctx, e)
+		}
+	}
+}
+
+func (w *watcher) handle(ctx context.Context, e fsnotify.Event) {
+	switch {
+	case e.Has(fsnotify.Create):
+		info, err := os.Lstat(e.Name)
+		if err != nil {
+			return
+		}
+
+		isDir := info.IsDir()
+		w.publish(Event{Type: Create, Path: e.Name, IsDir: isDir})
+		if isDir {
+			_ = w.addTree(ctx, e.Name, true)
+		}
+	case e.Has(fsnotify.Write):
+		w.publishLater(e.Name)
+	case e.Has(fsnotify.Remove):
+		w.cancel(e.Name)
+		w.publish(Event{Type: Delete, Path: e.Name})
+	case e.Has(fsnotify.Rename):
+		w.cancel(e.Name)
+		w.unwatchTree(e.Name)
+		w.publish(Event{Type: Rename, Path: e.Name})
+	}
+}
+
+func (w *watcher) publish(e Event) {
+	e.Source = SourceWatcher
+	w.hub.Publish(e)
+}
+
+// publishLater publishes a modify event for path once it
+// hasn't been written to for the debounce duration.
+func (w *watcher) publishLater(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[path]; ok {
+		t.Reset(w.debounce)
+		return
+	}
+
+	w.pending[path] = time.AfterFunc(w.debounce, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+
+		w.publish(Event{Type: Modify, Path: path})
+	})
+}
+
+func (w *watcher) cancel(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[path]; ok {
+		t.Stop()
+		delete(w.pending, path)
+	}
+}
+
+// unwatchTree stops watching a directory that was moved away, along
+// with its subdirectories. Their new location is watched on create.
+func (w *watcher) unwatchTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for _, path := range w.fs.WatchList() {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			_ = w.fs.Remove(path)
+		}
+	}
+}
+
+func (w *watcher) stop() {
+	w.fs.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path, t := range w.pending {
+		t.Stop()
+		delete(w.pending, path)
+	}
+}
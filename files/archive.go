@@ -0,0 +1,77 @@
+package files
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+)
+
+// ReadArchive sets the listing of i, an archive, to the entries in
+// its directory dir, "" being the root of the archive. The paths of
+// the entries are relative to that root.
+func (i *FileInfo) ReadArchive(dir string) error {
+	entries, err := archive.List(i.Fs, i.Path, dir)
+	if err != nil {
+		return err
+	}
+
+	listing := &Listing{Items: []*FileInfo{}}
+	for _, e := range entries {
+		name := path.Base(e.Name)
+		file := &FileInfo{
+			Fs:        i.Fs,
+			Path:      e.Name,
+			Name:      name,
+			Size:      e.Size,
+			ModTime:   e.ModTime,
+			Mode:      e.Mode,
+			IsDir:     e.IsDir,
+			Extension: filepath.Ext(name),
+		}
+
+		if file.IsDir {
+			listing.NumDirs++
+		} else {
+			listing.NumFiles++
+			file.Type = TypeByName(name)
+			if e.CRC32 != 0 {
+				file.CRC32 = fmt.Sprintf("%08x", e.CRC32)
+			}
+		}
+
+		listing.Items = append(listing.Items, file)
+	}
+
+	i.Listing = listing
+	return nil
+}
+
+// TypeByName guesses the type of a file from its extension alone,
+// for files whose contents can't be read cheaply.
+//
+//nolint:goconst
+func TypeByName(name string) string {
+	if archive.IsArchive(name) {
+		return "archive"
+	}
+
+	mimetype := mime.TypeByExtension(filepath.Ext(name))
+	switch {
+	case strings.HasPrefix(mimetype, "video"):
+		return "video"
+	case strings.HasPrefix(mimetype, "audio"):
+		return "audio"
+	case strings.HasPrefix(mimetype, "image"):
+		return "image"
+	case strings.HasSuffix(mimetype, "pdf"):
+		return "pdf"
+	case strings.HasPrefix(mimetype, "text"):
+		return "textImmutable"
+	default:
+		return "blob"
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"hash"
+	"hash/crc32"
 	"image"
 	"io"
 	"io/fs"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/spf13/afero"
 
+	"github.com/filebrowser/filebrowser/v2/archive"
 	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
 	"github.com/filebrowser/filebrowser/v2/rules"
 )
@@ -50,7 +52,9 @@ type FileInfo struct {
 	Type       string            `json:"type"`
 	Subtitles  []string          `json:"subtitles,omitempty"`
 	Content    string            `json:"content,omitempty"`
+	Window     *Window           `json:"window,omitempty"`
 	Checksums  map[string]string `json:"checksums,omitempty"`
+	CRC32      string            `json:"crc32,omitempty"`
 	Token      string            `json:"token,omitempty"`
 	currentDir []os.FileInfo     `json:"-"`
 	Resolution *ImageResolution  `json:"resolution,omitempty"`
@@ -66,6 +70,8 @@ type FileOptions struct {
 	Token      string
 	Checker    rules.Checker
 	Content    bool
+	// Range, when set, only reads part of the file into Content.
+	Range *Range
 }
 
 type ImageResolution struct {
@@ -94,7 +100,7 @@ func NewFileInfo(opts *FileOptions) (*FileInfo, error) {
 			return file, nil
 		}
 
-		err = file.detectType(opts.Modify, opts.Content, true)
+		err = file.detectType(opts.Modify, opts.Content, true, opts.Range)
 		if err != nil {
 			return nil, err
 		}
@@ -179,20 +185,9 @@ func (i *FileInfo) Checksum(algo string) error {
 	}
 	defer reader.Close()
 
-	var h hash.Hash
-
-	//nolint:gosec
-	switch algo {
-	case "md5":
-		h = md5.New()
-	case "sha1":
-		h = sha1.New()
-	case "sha256":
-		h = sha256.New()
-	case "sha512":
-		h = sha512.New()
-	default:
-		return fbErrors.ErrInvalidOption
+	h, err := NewHash(algo)
+	if err != nil {
+		return err
 	}
 
 	_, err = io.Copy(h, reader)
@@ -204,6 +199,26 @@ func (i *FileInfo) Checksum(algo string) error {
 	return nil
 }
 
+// NewHash returns a hash for one of the checksum algorithms: crc32,
+// md5, sha1, sha256 or sha512.
+func NewHash(algo string) (hash.Hash, error) {
+	//nolint:gosec
+	switch algo {
+	case "crc32":
+		return crc32.NewIEEE(), nil
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fbErrors.ErrInvalidOption
+	}
+}
+
 func (i *FileInfo) RealPath() string {
 	if realPathFs, ok := i.Fs.(interface {
 		RealPath(name string) (fPath string, err error)
@@ -218,7 +233,7 @@ func (i *FileInfo) RealPath() string {
 }
 
 //nolint:goconst
-func (i *FileInfo) detectType(modify, saveContent, readHeader bool) error {
+func (i *FileInfo) detectType(modify, saveContent, readHeader bool, rng *Range) error {
 	if IsNamedPipe(i.Mode) {
 		i.Type = "blob"
 		return nil
@@ -240,6 +255,9 @@ func (i *FileInfo) detectType(modify, saveContent, readHeader bool) error {
 	}
 
 	switch {
+	case archive.IsArchive(i.Name):
+		i.Type = "archive"
+		return nil
 	case strings.HasPrefix(mimetype, "video"):
 		i.Type = "video"
 		i.detectSubtitles()
@@ -259,13 +277,17 @@ func (i *FileInfo) detectType(modify, saveContent, readHeader bool) error {
 	case strings.HasSuffix(mimetype, "pdf"):
 		i.Type = "pdf"
 		return nil
-	case (strings.HasPrefix(mimetype, "text") || !isBinary(buffer)) && i.Size <= 10*1024*1024: // 10 MB
+	case (strings.HasPrefix(mimetype, "text") || !IsBinary(buffer)) && (i.Size <= MaxWindowSize || rng != nil):
 		i.Type = "text"
 
 		if !modify {
 			i.Type = "textImmutable"
 		}
 
+		if saveContent && rng != nil {
+			return i.readWindow(*rng)
+		}
+
 		if saveContent {
 			afs := &afero.Afero{Fs: i.Fs}
 			content, err := afs.ReadFile(i.Path)
@@ -283,6 +305,17 @@ func (i *FileInfo) detectType(modify, saveContent, readHeader bool) error {
 	return nil
 }
 
+func (i *FileInfo) readWindow(rng Range) error {
+	reader, err := i.Fs.Open(i.Path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	i.Content, i.Window, err = ReadWindow(reader, rng)
+	return err
+}
+
 func calculateImageResolution(fSys afero.Fs, filePath string) (*ImageResolution, error) {
 	file, err := fSys.Open(filePath)
 	if err != nil {
@@ -451,7 +484,7 @@ func (i *FileInfo) readListing(checker rules.Checker, readHeader bool) error {
 			if isInvalidLink {
 				file.Type = "invalid_link"
 			} else {
-				err := file.detectType(true, false, readHeader)
+				err := file.detectType(true, false, readHeader, nil)
 				if err != nil {
 					return err
 				}
@@ -5,7 +5,9 @@ import (
 	"unicode/utf8"
 )
 
-func isBinary(content []byte) bool {
+// IsBinary reports whether content, usually the first bytes of a
+// file, looks like binary data rather than text.
+func IsBinary(content []byte) bool {
 	maybeStr := string(content)
 	runeCnt := utf8.RuneCount(content)
 	runeIndex := 0
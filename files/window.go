@@ -0,0 +1,191 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// MaxWindowSize is the maximum number of bytes read into Content at once.
+const MaxWindowSize = 10 * 1024 * 1024 // 10 MB
+
+const windowChunkSize = 32 * 1024
+
+// Range selects the part of a file to read into Content.
+// When Lines is set, Offset and Limit count lines instead
+// of bytes. A Limit <= 0 reads up to MaxWindowSize.
+type Range struct {
+	Offset int64
+	Limit  int64
+	Lines  bool
+}
+
+// Window describes the part of a file held in Content.
+type Window struct {
+	Lines      bool  `json:"lines"`
+	Offset     int64 `json:"offset"`
+	Limit      int64 `json:"limit"`
+	Start      int64 `json:"start"`
+	Length     int64 `json:"length"`
+	StartLine  int64 `json:"startLine"`
+	LineCount  int64 `json:"lineCount"`
+	TotalSize  int64 `json:"totalSize"`
+	TotalLines int64 `json:"totalLines"`
+	Truncated  bool  `json:"truncated"`
+}
+
+// ReadWindow reads the part of r selected by rng. The whole reader is
+// scanned once to count its lines, but only the window is kept in memory.
+func ReadWindow(r io.Reader, rng Range) (string, *Window, error) {
+	if rng.Offset < 0 {
+		rng.Offset = 0
+	}
+
+	w := &Window{
+		Lines:  rng.Lines,
+		Offset: rng.Offset,
+		Limit:  rng.Limit,
+	}
+
+	s := &windowScanner{w: w, rng: rng, start: -1}
+	if !rng.Lines {
+		s.start = rng.Offset
+		s.end = rng.Offset + MaxWindowSize
+		if rng.Limit > 0 && rng.Limit < MaxWindowSize {
+			s.end = rng.Offset + rng.Limit
+		}
+	} else if rng.Offset == 0 {
+		s.start = 0
+	}
+
+	chunk := make([]byte, windowChunkSize)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			s.scan(chunk[:n])
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	w.TotalSize = s.pos
+	w.TotalLines = s.newlines
+	if s.pos > 0 && s.last != '\n' {
+		w.TotalLines++
+	}
+
+	if !rng.Lines && s.end < s.pos && (rng.Limit <= 0 || rng.Limit > MaxWindowSize) {
+		w.Truncated = true
+	}
+
+	if s.start < 0 || s.start > s.pos {
+		// The window begins past the end of the file.
+		s.start = s.pos
+		s.startLine = w.TotalLines
+	}
+
+	content := s.buf.Bytes()
+	w.Start = s.start
+	w.Length = int64(len(content))
+	w.StartLine = s.startLine
+	w.LineCount = int64(bytes.Count(content, []byte{'\n'}))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		w.LineCount++
+	}
+
+	return string(content), w, nil
+}
+
+type windowScanner struct {
+	w   *Window
+	rng Range
+	buf bytes.Buffer
+
+	pos       int64 // bytes scanned
+	newlines  int64 // newlines scanned
+	last      byte  // last byte scanned
+	start     int64 // byte offset where the window starts, -1 if unknown
+	end       int64 // byte offset where a bytes window ends
+	startLine int64 // newlines before the window start
+}
+
+func (s *windowScanner) scan(data []byte) {
+	if s.rng.Lines {
+		s.scanLines(data)
+	} else {
+		s.scanBytes(data)
+	}
+
+	s.pos += int64(len(data))
+	s.newlines += int64(bytes.Count(data, []byte{'\n'}))
+	s.last = data[len(data)-1]
+}
+
+func (s *windowScanner) scanBytes(data []byte) {
+	from, to := s.pos, s.pos+int64(len(data))
+
+	if from < s.start {
+		before := data[:min(s.start, to)-from]
+		s.startLine += int64(bytes.Count(before, []byte{'\n'}))
+	}
+
+	lo, hi := max(from, s.start), min(to, s.end)
+	if lo < hi {
+		s.buf.Write(data[lo-from : hi-from])
+	}
+}
+
+func (s *windowScanner) scanLines(data []byte) {
+	first, last := s.rng.Offset, s.rng.Offset+s.rng.Limit
+	if s.rng.Limit <= 0 {
+		last = -1
+	}
+
+	line := s.newlines
+	for i := 0; i < len(data); {
+		inWindow := line >= first && (last < 0 || line < last)
+		j := bytes.IndexByte(data[i:], '\n')
+
+		if !inWindow {
+			if j < 0 || (last >= 0 && line >= last) {
+				return
+			}
+
+			i += j + 1
+			line++
+			if line == first {
+				s.start = s.pos + int64(i)
+				s.startLine = line
+			}
+			continue
+		}
+
+		end := len(data)
+		if j >= 0 {
+			end = i + j + 1
+		}
+
+		s.write(data[i:end])
+		if j < 0 {
+			return
+		}
+
+		i = end
+		line++
+	}
+}
+
+func (s *windowScanner) write(data []byte) {
+	room := MaxWindowSize - s.buf.Len()
+	if len(data) > room {
+		data = data[:room]
+		s.w.Truncated = true
+	}
+
+	s.buf.Write(data)
+}
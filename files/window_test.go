@@ -0,0 +1,65 @@
+package files
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadWindow(t *testing.T) {
+	const content = "zero\none\ntwo\nthree\nfour"
+
+	cases := map[string]struct {
+		rng       Range
+		want      string
+		start     int64
+		startLine int64
+		lineCount int64
+	}{
+		"bytes from start":     {rng: Range{Limit: 8}, want: "zero\none", start: 0, startLine: 0, lineCount: 2},
+		"bytes in the middle":  {rng: Range{Offset: 9, Limit: 4}, want: "two\n", start: 9, startLine: 2, lineCount: 1},
+		"bytes past the end":   {rng: Range{Offset: 100, Limit: 4}, want: "", start: 23, startLine: 5, lineCount: 0},
+		"bytes without limit":  {rng: Range{Offset: 19}, want: "four", start: 19, startLine: 4, lineCount: 1},
+		"lines from start":     {rng: Range{Limit: 2, Lines: true}, want: "zero\none\n", start: 0, startLine: 0, lineCount: 2},
+		"lines in the middle":  {rng: Range{Offset: 2, Limit: 2, Lines: true}, want: "two\nthree\n", start: 9, startLine: 2, lineCount: 2},
+		"lines up to the end":  {rng: Range{Offset: 3, Lines: true}, want: "three\nfour", start: 13, startLine: 3, lineCount: 2},
+		"lines past the end":   {rng: Range{Offset: 10, Limit: 2, Lines: true}, want: "", start: 23, startLine: 5, lineCount: 0},
+		"lines, last one only": {rng: Range{Offset: 4, Limit: 1, Lines: true}, want: "four", start: 19, startLine: 4, lineCount: 1},
+	}
+
+	for name, tc := range cases {
+		got, w, err := ReadWindow(strings.NewReader(content), tc.rng)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("%s: got content %q; want %q", name, got, tc.want)
+		}
+		if w.Start != tc.start || w.StartLine != tc.startLine || w.LineCount != tc.lineCount {
+			t.Errorf("%s: got start %d, start line %d, line count %d; want %d, %d, %d",
+				name, w.Start, w.StartLine, w.LineCount, tc.start, tc.startLine, tc.lineCount)
+		}
+		if w.TotalSize != int64(len(content)) || w.TotalLines != 5 {
+			t.Errorf("%s: got total size %d and total lines %d", name, w.TotalSize, w.TotalLines)
+		}
+		if w.Length != int64(len(tc.want)) {
+			t.Errorf("%s: got length %d; want %d", name, w.Length, len(tc.want))
+		}
+	}
+}
+
+func TestReadWindowLongFile(t *testing.T) {
+	content := strings.Repeat("0123456789abcde\n", 10000)
+
+	got, w, err := ReadWindow(strings.NewReader(content), Range{Offset: 5000, Limit: 3, Lines: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != strings.Repeat("0123456789abcde\n", 3) || w.Start != 5000*16 {
+		t.Errorf("unexpected window at %d: %q", w.Start, got)
+	}
+	if w.TotalLines != 10000 || w.Truncated {
+		t.Errorf("got %d total lines, truncated %v", w.TotalLines, w.Truncated)
+	}
+}
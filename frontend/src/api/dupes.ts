@@ -0,0 +1,50 @@
+import { fetchJSON, removePrefix } from "./utils";
+
+export interface DupeFile {
+  path: string;
+  modified: string;
+  entry?: string;
+}
+
+export interface DupeGroup {
+  size: number;
+  algorithm: string;
+  checksum: string;
+  files: DupeFile[];
+  reclaimable: number;
+}
+
+export interface DupesReport {
+  path: string;
+  files: number;
+  reclaimable: number;
+  groups: DupeGroup[];
+}
+
+export interface DupesOptions {
+  algo?: ChecksumAlg;
+  extensions?: string[];
+  minSize?: number;
+  archives?: boolean;
+}
+
+// Finds the duplicate files of the directory at url.
+export async function find(url: string, opts: DupesOptions = {}) {
+  url = removePrefix(url);
+
+  const params = new URLSearchParams();
+  if (opts.algo) {
+    params.set("algo", opts.algo);
+  }
+  if (opts.extensions && opts.extensions.length > 0) {
+    params.set("ext", opts.extensions.join(","));
+  }
+  if (opts.minSize) {
+    params.set("minSize", String(opts.minSize));
+  }
+  if (opts.archives) {
+    params.set("archives", "true");
+  }
+
+  return fetchJSON<DupesReport>(`/api/dupes${url}?${params}`);
+}
@@ -0,0 +1,25 @@
+import { baseURL } from "@/utils/constants";
+
+export interface FsEvent {
+  type: "create" | "modify" | "delete" | "rename";
+  path: string;
+  dst?: string;
+  isDir: boolean;
+  source: "server" | "watcher";
+  time: string;
+}
+
+// subscribe calls onEvent for every change in the user's scope, and with
+// null when events were missed. It returns a function that unsubscribes.
+export function subscribe(onEvent: (event: FsEvent | null) => void) {
+  const source = new EventSource(`${baseURL}/api/events`);
+
+  for (const type of ["create", "modify", "delete", "rename"]) {
+    source.addEventListener(type, (msg) => {
+      onEvent(JSON.parse((msg as MessageEvent).data));
+    });
+  }
+  source.addEventListener("dropped", () => onEvent(null));
+
+  return () => source.close();
+}
@@ -29,6 +29,19 @@ export async function fetch(url: string) {
   return data;
 }
 
+// Lists the entries of the directory dir inside the archive at url.
+// The paths of the entries are relative to the root of the archive.
+export async function fetchArchive(url: string, dir = "") {
+  url = removePrefix(url);
+
+  const res = await fetchURL(
+    `/api/resources${url}?archive=${encodeURIComponent(dir)}`,
+    {}
+  );
+
+  return (await res.json()) as Resource;
+}
+
 async function resourceAction(url: string, method: ApiMethod, content?: any) {
   url = removePrefix(url);
 
@@ -45,8 +58,11 @@ async function resourceAction(url: string, method: ApiMethod, content?: any) {
   return res;
 }
 
-export async function remove(url: string) {
-  return resourceAction(url, "DELETE");
+export async function remove(url: string, permanent = false) {
+  return resourceAction(
+    permanent ? `${url}?permanent=true` : url,
+    "DELETE"
+  );
 }
 
 export async function put(url: string, content = "") {
@@ -178,6 +194,53 @@ export function copy(items: any[], overwrite = false, rename = false) {
   return moveCopy(items, true, overwrite, rename);
 }
 
+// Extracts the archive at url into the directory to. The extraction
+// runs as a background job, see api/jobs.
+export async function extract(
+  url: string,
+  to: string,
+  overwrite = false,
+  rename = false
+) {
+  const dst = encodeURIComponent(removePrefix(to));
+  const res = await resourceAction(
+    `${url}?action=extract&destination=${dst}&override=${overwrite}&rename=${rename}&async=true`,
+    "PATCH"
+  );
+  return res.json();
+}
+
+// Creates the archive to from the given files of the directory at
+// url, or of url itself when files is empty. algo is one of the
+// download formats or "zipstore", a zip whose files aren't
+// compressed. The compression runs as a background job.
+export async function compress(
+  url: string,
+  to: string,
+  files: string[] = [],
+  algo = "zip",
+  level = 0,
+  exclude: string[] = [],
+  overwrite = false,
+  rename = false
+) {
+  const dst = encodeURIComponent(removePrefix(to));
+  let query = `action=compress&destination=${dst}&algo=${algo}&override=${overwrite}&rename=${rename}&async=true`;
+
+  if (files.length > 0) {
+    query += `&files=${encodeURIComponent(files.join(","))}`;
+  }
+  if (level > 0) {
+    query += `&level=${level}`;
+  }
+  if (exclude.length > 0) {
+    query += `&exclude=${encodeURIComponent(exclude.join(","))}`;
+  }
+
+  const res = await resourceAction(`${url}?${query}`, "PATCH");
+  return res.json();
+}
+
 export async function checksum(url: string, algo: ChecksumAlg) {
   const data = await resourceAction(`${url}?checksum=${algo}`, "GET");
   return (await data.json()).checksums[algo];
@@ -200,6 +263,33 @@ export function getPreviewURL(file: ResourceItem, size: string) {
   return createURL("api/preview/" + size + file.path, params);
 }
 
+export function getArchiveEntryURL(
+  file: ResourceItem,
+  entry: string,
+  inline: any
+) {
+  const params = {
+    archive: entry,
+    ...(inline && { inline: "true" }),
+  };
+
+  return createURL("api/raw" + file.path, params);
+}
+
+export function getArchivePreviewURL(
+  file: ResourceItem,
+  entry: string,
+  size: string
+) {
+  const params = {
+    archive: entry,
+    inline: "true",
+    key: Date.parse(file.modified),
+  };
+
+  return createURL("api/preview/" + size + file.path, params);
+}
+
 export function getSubtitlesURL(file: ResourceItem) {
   const params = {
     inline: "true",
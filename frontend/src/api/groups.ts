@@ -0,0 +1,35 @@
+import { fetchURL, fetchJSON, StatusError } from "./utils";
+
+export async function getAll() {
+  return fetchJSON<IGroup[]>(`/api/groups`, {});
+}
+
+export async function get(id: number) {
+  return fetchJSON<IGroup>(`/api/groups/${id}`, {});
+}
+
+export async function create(group: IGroup) {
+  const res = await fetchURL(`/api/groups`, {
+    method: "POST",
+    body: JSON.stringify(group),
+  });
+
+  if (res.status === 201) {
+    return res.headers.get("Location");
+  }
+
+  throw new StatusError(await res.text(), res.status);
+}
+
+export async function update(group: IGroup) {
+  await fetchURL(`/api/groups/${group.id}`, {
+    method: "PUT",
+    body: JSON.stringify(group),
+  });
+}
+
+export async function remove(id: number) {
+  await fetchURL(`/api/groups/${id}`, {
+    method: "DELETE",
+  });
+}
@@ -5,5 +5,31 @@ import * as settings from "./settings";
 import * as pub from "./pub";
 import search from "./search";
 import commands from "./commands";
+import * as events from "./events";
+import * as trash from "./trash";
+import * as jobs from "./jobs";
+import * as romcheck from "./romcheck";
+import * as manifest from "./manifest";
+import * as dupes from "./dupes";
+import * as searchindex from "./searchindex";
+import * as rules from "./rules";
+import * as groups from "./groups";
 
-export { files, share, users, settings, pub, commands, search };
+export {
+  files,
+  share,
+  users,
+  settings,
+  pub,
+  commands,
+  search,
+  events,
+  trash,
+  jobs,
+  romcheck,
+  manifest,
+  dupes,
+  searchindex,
+  rules,
+  groups,
+};
@@ -0,0 +1,56 @@
+import { fetchURL, fetchJSON } from "./utils";
+import type { ManifestAlgorithm, ManifestReport } from "./manifest";
+
+export type JobState = "queued" | "running" | "done" | "failed" | "cancelled";
+
+export interface Job {
+  id: number;
+  userId: number;
+  action:
+    | "copy"
+    | "rename"
+    | "delete"
+    | "extract"
+    | "compress"
+    | "manifest"
+    | "verify";
+  src: string;
+  dst?: string;
+  isDir: boolean;
+  override: boolean;
+  state: JobState;
+  error?: string;
+  attempts: number;
+  filesTotal: number;
+  filesDone: number;
+  bytesTotal: number;
+  bytesDone: number;
+  eta: number;
+  created: string;
+  started: string;
+  finished: string;
+  sources?: string[];
+  algorithm?: ManifestAlgorithm;
+  verification?: ManifestReport;
+}
+
+export async function list(all = false) {
+  return fetchJSON<Job[]>(all ? "/api/jobs?all=true" : "/api/jobs");
+}
+
+export async function get(id: number) {
+  return fetchJSON<Job>(`/api/jobs/${id}`);
+}
+
+// Cancels an active job or removes a finished one.
+export async function remove(id: number) {
+  await fetchURL(`/api/jobs/${id}`, {
+    method: "DELETE",
+  });
+}
+
+export async function resume(id: number) {
+  await fetchURL(`/api/jobs/${id}/resume`, {
+    method: "POST",
+  });
+}
@@ -0,0 +1,49 @@
+import { fetchJSON, fetchURL, removePrefix } from "./utils";
+import type { Job } from "./jobs";
+
+export type ManifestAlgorithm = "sfv" | "md5" | "sha256";
+
+export interface ManifestResult {
+  path: string;
+  status: "ok" | "mismatch" | "missing";
+  expected: string;
+  actual?: string;
+}
+
+export interface ManifestReport {
+  manifest: string;
+  algorithm: ManifestAlgorithm;
+  ok: number;
+  mismatch: number;
+  missing: number;
+  results: ManifestResult[];
+}
+
+// Writes a manifest of the checksums of the files of the directory at
+// url, to "to" or to the default name of the algorithm in the
+// directory. It runs as a background job.
+export async function generate(
+  url: string,
+  algo: ManifestAlgorithm,
+  to = "",
+  overwrite = false
+) {
+  url = removePrefix(url);
+  const dst = encodeURIComponent(removePrefix(to));
+  let query = `algo=${algo}&override=${overwrite}&async=true`;
+  if (to !== "") {
+    query += `&destination=${dst}`;
+  }
+
+  const res = await fetchURL(`/api/manifest${url}?${query}`, {
+    method: "POST",
+  });
+  return (await res.json()) as Job;
+}
+
+// Verifies the manifest at url as a background job. The report is
+// kept in the verification field of the job once it's done.
+export async function verify(url: string) {
+  url = removePrefix(url);
+  return fetchJSON<Job>(`/api/manifest${url}?async=true`);
+}
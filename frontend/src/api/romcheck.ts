@@ -0,0 +1,48 @@
+import { fetchJSON, removePrefix } from "./utils";
+
+export interface DatFile {
+  file: string;
+  name: string;
+  description: string;
+  version: string;
+  games: number;
+}
+
+export type RomcheckStatus = "complete" | "missing" | "bad-dump" | "unknown";
+
+export interface RomResult {
+  name: string;
+  status: RomcheckStatus;
+  path?: string;
+  crc?: string;
+}
+
+export interface RomcheckResult {
+  game?: string;
+  status: RomcheckStatus;
+  path?: string;
+  roms?: RomResult[];
+}
+
+export interface RomcheckReport {
+  dat: string;
+  path: string;
+  complete: number;
+  missing: number;
+  badDump: number;
+  unknown: number;
+  results: RomcheckResult[];
+}
+
+// Lists the DAT files configured on the server.
+export async function dats() {
+  return fetchJSON<DatFile[]>("/api/romcheck");
+}
+
+// Verifies the ROM directory at url against the DAT file dat.
+export async function check(url: string, dat: string) {
+  url = removePrefix(url);
+  return fetchJSON<RomcheckReport>(
+    `/api/romcheck${url}?dat=${encodeURIComponent(dat)}`
+  );
+}
@@ -0,0 +1,31 @@
+import { fetchJSON } from "./utils";
+
+export interface RuleMatch {
+  source: "global" | "user";
+  index: number;
+  rule: IRule;
+}
+
+export interface RuleDecision {
+  allow: boolean;
+  // Index in matches of the rule that decided, or -1 for the default.
+  rule: number;
+}
+
+export interface RulesExplanation {
+  path: string;
+  dotfile: boolean;
+  matches: RuleMatch[];
+  visible: RuleDecision;
+  actions: Record<RuleAction, RuleDecision>;
+}
+
+// Tells which rules of a user, the current one by default, match a path,
+// and what they decide.
+export async function explain(path: string, user?: number | string) {
+  let url = `/api/rules/explain?path=${encodeURIComponent(path)}`;
+  if (user !== undefined) {
+    url += `&user=${encodeURIComponent(user)}`;
+  }
+  return fetchJSON<RulesExplanation>(url);
+}
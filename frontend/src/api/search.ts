@@ -1,7 +1,20 @@
 import { fetchURL, removePrefix } from "./utils";
 import url from "../utils/url";
 
-export default async function search(base: string, query: string) {
+export interface SearchSummary {
+  count: number;
+  // Set when the search stopped at the limit or the timeout.
+  truncated: boolean;
+}
+
+// Searches the directory at base, calling onResult with the results as
+// they're streamed. The search stops when signal is aborted.
+export default async function search(
+  base: string,
+  query: string,
+  onResult: (item: UploadItem) => void,
+  signal?: AbortSignal
+): Promise<SearchSummary> {
   base = removePrefix(base);
   query = encodeURIComponent(query);
 
@@ -9,19 +22,55 @@ export default async function search(base: string, query: string) {
     base += "/";
   }
 
-  const res = await fetchURL(`/api/search${base}?query=${query}`, {});
+  const res = await fetchURL(`/api/search${base}?query=${query}`, {
+    signal,
+  });
 
-  let data = await res.json();
+  let summary: SearchSummary = { count: 0, truncated: false };
+  const handle = (message: string) => {
+    let event = "";
+    let data = "";
+    for (const line of message.split("\n")) {
+      if (line.startsWith("event: ")) {
+        event = line.slice("event: ".length);
+      } else if (line.startsWith("data: ")) {
+        data = line.slice("data: ".length);
+      }
+    }
 
-  data = data.map((item: UploadItem) => {
-    item.url = `/files${base}` + url.encodePath(item.path);
+    switch (event) {
+      case "result": {
+        const item: UploadItem = JSON.parse(data);
+        item.url = `/files${base}` + url.encodePath(item.path);
+        if (item.dir) {
+          item.url += "/";
+        }
+        onResult(item);
+        break;
+      }
+      case "done":
+        summary = JSON.parse(data);
+        break;
+      case "error":
+        throw new Error(JSON.parse(data));
+    }
+  };
 
-    if (item.dir) {
-      item.url += "/";
+  const reader = res.body!.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) {
+      break;
     }
 
-    return item;
-  });
+    buffer += value;
+    let end;
+    while ((end = buffer.indexOf("\n\n")) >= 0) {
+      handle(buffer.slice(0, end));
+      buffer = buffer.slice(end + 2);
+    }
+  }
 
-  return data;
+  return summary;
 }
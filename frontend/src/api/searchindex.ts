@@ -0,0 +1,27 @@
+import { fetchURL, fetchJSON } from "./utils";
+
+export type SearchIndexState = "stale" | "building" | "ready" | "failed";
+
+export interface SearchIndexStatus {
+  root: string;
+  state: SearchIndexState;
+  error?: string;
+  entries: number;
+  builtAt: string;
+  // Nanoseconds, like Go durations.
+  duration: number;
+  updatedAt: string;
+  crawling: boolean;
+}
+
+export async function status() {
+  return fetchJSON<SearchIndexStatus>(`/api/searchindex`);
+}
+
+// Crawls the root again in the background.
+export async function rebuild() {
+  const res = await fetchURL(`/api/searchindex`, {
+    method: "POST",
+  });
+  return (await res.json()) as SearchIndexStatus;
+}
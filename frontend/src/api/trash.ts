@@ -0,0 +1,35 @@
+import { fetchURL, fetchJSON } from "./utils";
+
+export interface TrashItem {
+  id: string;
+  path: string;
+  isDir: boolean;
+  size: number;
+  deletedAt: string;
+}
+
+export async function list() {
+  return fetchJSON<TrashItem[]>("/api/trash");
+}
+
+export async function restore(id: string, destination = "") {
+  let url = `/api/trash/${encodeURIComponent(id)}/restore`;
+  if (destination !== "") {
+    url += `?destination=${encodeURIComponent(destination)}`;
+  }
+  return fetchJSON<TrashItem>(url, {
+    method: "POST",
+  });
+}
+
+export async function purge(id: string) {
+  await fetchURL(`/api/trash/${encodeURIComponent(id)}`, {
+    method: "DELETE",
+  });
+}
+
+export async function empty() {
+  await fetchURL("/api/trash", {
+    method: "DELETE",
+  });
+}
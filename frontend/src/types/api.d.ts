@@ -10,6 +10,7 @@ interface ApiOpts {
   method?: ApiMethod;
   headers?: object;
   body?: any;
+  signal?: AbortSignal;
 }
 
 interface TusSettings {
@@ -17,7 +18,7 @@ interface TusSettings {
   chunkSize: number;
 }
 
-type ChecksumAlg = "md5" | "sha1" | "sha256" | "sha512";
+type ChecksumAlg = "crc32" | "md5" | "sha1" | "sha256" | "sha512";
 
 interface Share {
   hash: string;
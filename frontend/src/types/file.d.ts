@@ -9,6 +9,7 @@ interface ResourceBase {
   isSymlink: boolean;
   type: ResourceType;
   url: string;
+  crc32?: string;
 }
 
 interface Resource extends ResourceBase {
@@ -35,6 +36,7 @@ type ResourceType =
   | "pdf"
   | "text"
   | "blob"
+  | "archive"
   | "textImmutable";
 
 type DownloadFormat =
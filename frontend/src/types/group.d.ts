@@ -0,0 +1,11 @@
+interface IGroup {
+  id: number;
+  name: string;
+  perm: Permissions;
+  rules: IRule[];
+  commands: string[];
+  // Given to the users created in the group without a scope.
+  scope: string;
+  // Unix time of the last save, set by the server.
+  updated?: number;
+}
@@ -20,6 +20,7 @@ interface SettingsDefaults {
   commands: any[];
   hideDotfiles: boolean;
   dateFormat: boolean;
+  quota?: Quota;
 }
 
 interface SettingsBranding {
@@ -37,13 +38,19 @@ interface SettingsTus {
 }
 
 interface SettingsCommand {
+  after_compress?: string[];
   after_copy?: string[];
   after_delete?: string[];
+  after_extract?: string[];
+  after_manifest?: string[];
   after_rename?: string[];
   after_save?: string[];
   after_upload?: string[];
+  before_compress?: string[];
   before_copy?: string[];
   before_delete?: string[];
+  before_extract?: string[];
+  before_manifest?: string[];
   before_rename?: string[];
   before_save?: string[];
   before_upload?: string[];
@@ -16,6 +16,16 @@ interface UploadItem {
   dir?: boolean;
   overwrite?: boolean;
   type?: ResourceType;
+  // Search results only.
+  size?: number;
+  modTime?: string;
+  // Matching lines of content searches.
+  matches?: SearchMatch[];
+}
+
+interface SearchMatch {
+  line: number;
+  text: string;
 }
 
 interface UploadEntry {
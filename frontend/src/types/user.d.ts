@@ -7,6 +7,8 @@ interface IUser {
   perm: Permissions;
   commands: string[];
   rules: IRule[];
+  groups?: number[];
+  quota?: Quota;
   lockPassword: boolean;
   hideDotfiles: boolean;
   singleClick: boolean;
@@ -26,6 +28,8 @@ interface IUserForm {
   perm?: Permissions;
   commands?: string[];
   rules?: IRule[];
+  groups?: number[];
+  quota?: Quota;
   lockPassword?: boolean;
   hideDotfiles?: boolean;
   singleClick?: boolean;
@@ -47,6 +51,11 @@ interface Permissions {
   upload: boolean;
 }
 
+interface Quota {
+  bytes: number;
+  files: number;
+}
+
 interface Sorting {
   by: string;
   asc: boolean;
@@ -56,9 +65,13 @@ interface IRule {
   allow: boolean;
   path: string;
   regex: boolean;
+  glob?: boolean;
   regexp: IRegexp;
+  actions?: RuleAction[];
 }
 
+type RuleAction = "read" | "create" | "modify" | "delete";
+
 interface IRegexp {
   raw: string;
 }
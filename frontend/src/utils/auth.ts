@@ -115,6 +115,17 @@ export async function signup(username: string, password: string) {
 }
 
 export function logout() {
+  const jwt = localStorage.getItem("jwt");
+  if (jwt) {
+    // Let the server record the end of support sessions.
+    fetch(`${baseURL}/api/logout`, {
+      method: "POST",
+      headers: {
+        "X-Auth": jwt,
+      },
+    }).catch(() => {});
+  }
+
   document.cookie = "auth=; Max-Age=0; Path=/; SameSite=Strict;";
 
   const authStore = useAuthStore();
@@ -0,0 +1,73 @@
+package groups
+
+import (
+	"slices"
+
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// Group holds the permissions, rules and commands shared by the users
+// that belong to it, so they don't have to be set on each of them.
+type Group struct {
+	ID       uint              `storm:"id,increment" json:"id"`
+	Name     string            `storm:"unique" json:"name"`
+	Perm     users.Permissions `json:"perm"`
+	Rules    []rules.Rule      `json:"rules"`
+	Commands []string          `json:"commands"`
+	// Scope is given to the users created in the group without a
+	// scope of their own.
+	Scope string `json:"scope"`
+	// Updated is the timestamp of the last time the group was saved.
+	// Sessions of its members issued before it are renewed.
+	Updated int64 `json:"updated"`
+}
+
+// Apply merges groups into the user: it's granted the permissions
+// and commands of every group on top of its own, and the rules of the
+// groups come before its own, which take precedence over them.
+func Apply(u *users.User, groups []*Group) {
+	u.GroupRules = []rules.Rule{}
+	for _, g := range groups {
+		u.Perm = union(u.Perm, g.Perm)
+		u.GroupRules = append(u.GroupRules, g.Rules...)
+		for _, command := range g.Commands {
+			if !slices.Contains(u.Commands, command) {
+				u.Commands = append(u.Commands, command)
+			}
+		}
+	}
+}
+
+func union(a, b users.Permissions) users.Permissions {
+	return users.Permissions{
+		Admin:    a.Admin || b.Admin,
+		Execute:  a.Execute || b.Execute,
+		Create:   a.Create || b.Create,
+		Rename:   a.Rename || b.Rename,
+		Modify:   a.Modify || b.Modify,
+		Delete:   a.Delete || b.Delete,
+		Share:    a.Share || b.Share,
+		Download: a.Download || b.Download,
+	}
+}
+
+// Scope returns the scope of the first of the groups that has one.
+func Scope(groups []*Group) string {
+	for _, g := range groups {
+		if g.Scope != "" {
+			return g.Scope
+		}
+	}
+	return ""
+}
+
+// RuleSources returns the global rules, those of the groups and those
+// of the user, by order of precedence, to explain them.
+func RuleSources(global []rules.Rule, u *users.User, groups []*Group) []rules.Source {
+	sources := []rules.Source{{Name: "global", Rules: global}}
+	for _, g := range groups {
+		sources = append(sources, rules.Source{Name: "group " + g.Name, Rules: g.Rules})
+	}
+	return append(sources, rules.Source{Name: "user", Rules: u.Rules})
+}
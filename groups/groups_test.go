@@ -0,0 +1,44 @@
+package groups
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestApply(t *testing.T) {
+	u := &users.User{
+		Perm:     users.Permissions{Create: true},
+		Commands: []string{"ls"},
+		Rules:    []rules.Rule{{Path: "/saves/kid", Allow: true}},
+	}
+	list := []*Group{
+		{Name: "family", Perm: users.Permissions{Download: true}, Commands: []string{"ls", "df"}, Rules: []rules.Rule{{Path: "/saves"}}},
+		{Name: "admins", Perm: users.Permissions{Admin: true}, Scope: "/family"},
+	}
+	Apply(u, list)
+
+	if u.Perm != (users.Permissions{Create: true, Download: true, Admin: true}) {
+		t.Errorf("unexpected permissions %+v", u.Perm)
+	}
+	if !slices.Equal(u.Commands, []string{"ls", "df"}) {
+		t.Errorf("unexpected commands %v", u.Commands)
+	}
+	if len(u.GroupRules) != 1 || len(u.Rules) != 1 {
+		t.Errorf("unexpected rules %v and %v", u.GroupRules, u.Rules)
+	}
+	// The rules of the user take precedence over those of its groups.
+	if !rules.Allowed("/saves/kid/zelda.srm", "", u.GroupRules, u.Rules) || rules.Allowed("/saves/mom", "", u.GroupRules, u.Rules) {
+		t.Error("unexpected rules precedence")
+	}
+	if Scope(list) != "/family" {
+		t.Errorf("unexpected scope %q", Scope(list))
+	}
+
+	sources := RuleSources(nil, u, list)
+	if len(sources) != 4 || sources[1].Name != "group family" || sources[3].Name != "user" { //nolint:gomnd
+		t.Errorf("unexpected sources %+v", sources)
+	}
+}
@@ -0,0 +1,134 @@
+package groups
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// StorageBackend is the interface to implement for a groups storage.
+type StorageBackend interface {
+	GetBy(interface{}) (*Group, error)
+	Gets() ([]*Group, error)
+	Save(g *Group) error
+	DeleteByID(uint) error
+}
+
+// Storage is a groups storage.
+type Storage struct {
+	back  StorageBackend
+	users users.Store
+}
+
+// NewStorage creates a groups storage from a backend. The users store
+// is needed to remove the deleted groups from their members.
+func NewStorage(back StorageBackend, userStore users.Store) *Storage {
+	return &Storage{
+		back:  back,
+		users: userStore,
+	}
+}
+
+// Get gets a group by its name or ID. The provided id must be a string
+// for name lookup or a uint for id lookup.
+func (s *Storage) Get(id interface{}) (*Group, error) {
+	return s.back.GetBy(id)
+}
+
+// Gets gets a list of all groups.
+func (s *Storage) Gets() ([]*Group, error) {
+	list, err := s.back.Gets()
+	if errors.Is(err, fbErrors.ErrNotExist) {
+		return []*Group{}, nil
+	}
+	return list, err
+}
+
+// Save creates or updates a group.
+func (s *Storage) Save(g *Group) error {
+	if g.Name == "" {
+		return fbErrors.ErrEmptyGroupName
+	}
+	if g.Rules == nil {
+		g.Rules = []rules.Rule{}
+	}
+	if g.Commands == nil {
+		g.Commands = []string{}
+	}
+	if err := rules.Compile(g.Rules); err != nil {
+		return err
+	}
+
+	g.Updated = time.Now().Unix()
+	return s.back.Save(g)
+}
+
+// Delete deletes a group and removes it from its members, which
+// updates them.
+func (s *Storage) Delete(id uint) error {
+	if _, err := s.back.GetBy(id); err != nil {
+		return err
+	}
+
+	list, err := s.users.Gets("")
+	if err != nil && !errors.Is(err, fbErrors.ErrNotExist) {
+		return err
+	}
+	for _, u := range list {
+		i := slices.Index(u.Groups, id)
+		if i < 0 {
+			continue
+		}
+		u.Groups = slices.Delete(u.Groups, i, i+1)
+		if err := s.users.Update(u, "Groups"); err != nil {
+			return err
+		}
+	}
+
+	return s.back.DeleteByID(id)
+}
+
+// Of returns the groups the user belongs to. Groups that no longer
+// exist are left out.
+func (s *Storage) Of(u *users.User) ([]*Group, error) {
+	list := []*Group{}
+	for _, id := range u.Groups {
+		g, err := s.back.GetBy(id)
+		if errors.Is(err, fbErrors.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		list = append(list, g)
+	}
+	return list, nil
+}
+
+// Apply merges the groups the user belongs to into it. See Apply.
+func (s *Storage) Apply(u *users.User) error {
+	list, err := s.Of(u)
+	if err != nil {
+		return err
+	}
+	Apply(u, list)
+	return nil
+}
+
+// LastUpdate gets the timestamp of the last update of any of the
+// groups the user belongs to, or 0 if they can't be read.
+func (s *Storage) LastUpdate(u *users.User) int64 {
+	list, err := s.Of(u)
+	if err != nil {
+		return 0
+	}
+
+	var last int64
+	for _, g := range list {
+		last = max(last, g.Updated)
+	}
+	return last
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/img"
+)
+
+// archiveGetHandler answers /api/resources/path/to/file.zip?archive=dir
+// with the archive, listing the entries of its directory dir.
+func archiveGetHandler(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	file, err := files.NewFileInfo(&files.FileOptions{
+		Fs:      d.user.Fs,
+		Path:    r.URL.Path,
+		Modify:  d.user.Perm.Modify,
+		Expand:  false,
+		Checker: d,
+	})
+	if err != nil {
+		return errToStatus(err), err
+	}
+	if file.IsDir || !archive.IsArchive(file.Name) {
+		return http.StatusBadRequest, fmt.Errorf("%s is not a supported archive: %w", file.Path, fbErrors.ErrInvalidRequestParams)
+	}
+
+	file.Type = "archive"
+	if err := file.ReadArchive(r.URL.Query().Get("archive")); err != nil {
+		return errToStatus(err), err
+	}
+
+	file.Listing.Sorting = d.user.Sorting
+	file.Listing.ApplySort()
+	return renderJSON(w, r, file)
+}
+
+// rawArchiveEntryHandler streams the entry of the archive file,
+// without extracting it.
+func rawArchiveEntryHandler(w http.ResponseWriter, r *http.Request, file *files.FileInfo, entry string) (int, error) {
+	written := false
+	err := archive.Find(file.Fs, file.Path, entry, func(e *archive.Entry, rd io.Reader) error {
+		if e.IsDir {
+			return fmt.Errorf("%s is a directory: %w", e.Name, fbErrors.ErrInvalidRequestParams)
+		}
+
+		name := path.Base(e.Name)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		setContentDispositionName(w, r, name)
+		w.Header().Add("Content-Security-Policy", `script-src 'none';`)
+		w.Header().Set("Cache-Control", "private")
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(e.Size, 10))
+		if !e.ModTime.IsZero() {
+			w.Header().Set("Last-Modified", e.ModTime.UTC().Format(http.TimeFormat))
+		}
+		written = true
+		if r.Method == http.MethodHead {
+			return nil
+		}
+
+		counter := &byteCounter{ResponseWriter: w}
+		_, err := io.Copy(counter, rd)
+		downloadedBytes.Add(float64(counter.n))
+		return err
+	})
+
+	if err != nil && written {
+		// The headers are gone, all we can do is cutting the body short.
+		log.Printf("Failed to stream %s from %s: %v", entry, file.Path, err)
+		return 0, nil
+	}
+	if err != nil {
+		return errToStatus(err), err
+	}
+	return 0, nil
+}
+
+// handleArchivePreview serves previews of images stored in archives.
+func handleArchivePreview(
+	w http.ResponseWriter,
+	r *http.Request,
+	imgSvc ImgService,
+	fileCache FileCache,
+	file *files.FileInfo,
+	entry string,
+	previewSize PreviewSize,
+	enableThumbnails, resizePreview bool,
+) (int, error) {
+	ext := path.Ext(entry)
+	if file.IsDir || !archive.IsArchive(file.Name) {
+		return http.StatusBadRequest, fmt.Errorf("%s is not a supported archive: %w", file.Path, fbErrors.ErrInvalidRequestParams)
+	}
+	if !strings.HasPrefix(mime.TypeByExtension(ext), "image/") {
+		return http.StatusNotImplemented, fmt.Errorf("can't create preview for %s", entry)
+	}
+
+	if (previewSize == PreviewSizeBig && !resizePreview) ||
+		(previewSize == PreviewSizeThumb && !enableThumbnails) {
+		return rawArchiveEntryHandler(w, r, file, entry)
+	}
+
+	format, err := imgSvc.FormatFromExtension(ext)
+	// Unsupported extensions directly return the raw data
+	if errors.Is(err, img.ErrUnsupportedFormat) || format == img.FormatGif {
+		return rawArchiveEntryHandler(w, r, file, entry)
+	}
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	cacheKey := archivePreviewCacheKey(file, entry, previewSize)
+	resizedImage, ok, err := fileCache.Load(r.Context(), cacheKey)
+	if err != nil {
+		return errToStatus(err), err
+	}
+	if ok {
+		previewCache.Inc(previewSize.String(), "hit")
+	} else {
+		previewCache.Inc(previewSize.String(), "miss")
+		err = archive.Find(file.Fs, file.Path, entry, func(_ *archive.Entry, rd io.Reader) error {
+			resizedImage, err = resizeImage(imgSvc, rd, previewSize)
+			return err
+		})
+		if err != nil {
+			return errToStatus(err), err
+		}
+
+		go func() {
+			if err := fileCache.Store(context.Background(), cacheKey, resizedImage); err != nil {
+				fmt.Printf("failed to cache resized image: %v", err)
+			}
+		}()
+	}
+
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, path.Base(entry), file.ModTime, bytes.NewReader(resizedImage))
+
+	return 0, nil
+}
+
+func archivePreviewCacheKey(f *files.FileInfo, entry string, previewSize PreviewSize) string {
+	return fmt.Sprintf("%x%x%x%x", f.RealPath(), entry, f.ModTime.Unix(), previewSize)
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/diskcache"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/img"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestArchiveBrowsing(t *testing.T) {
+	var snap bytes.Buffer
+	picture := image.NewRGBA(image.Rect(0, 0, 512, 512))
+	picture.Set(1, 1, color.White)
+	if err := png.Encode(&snap, picture); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string][]byte{
+		"roms/pacman.zip":  []byte("pacman"),
+		"snap/pacman.png":  snap.Bytes(),
+		"roms/mspacman.7z": []byte("mspacman"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "mame.zip"), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Download: true},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/resources/mame.zip?archive=roms", nil)
+	status, body := ts.serve(resourceGetHandler, "/api/resources", req)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var listing files.FileInfo
+	if err := json.Unmarshal(body, &listing); err != nil {
+		t.Fatal(err)
+	}
+	if listing.Type != "archive" || listing.Listing == nil || listing.NumFiles != 2 {
+		t.Fatalf("unexpected listing %s", body)
+	}
+	for _, item := range listing.Items {
+		if item.Path == "roms/pacman.zip" && (item.Type != "archive" || item.CRC32 != "a1c2e4b8" || item.Size != 6) {
+			t.Errorf("unexpected item %+v", item)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/resources/mame.zip?archive=nope", nil)
+	if status, _ = ts.serve(resourceGetHandler, "/api/resources", req); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing directory, got %d", status)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/raw/mame.zip?archive=roms/pacman.zip", nil)
+	status, body = ts.serve(rawHandler, "/api/raw", req)
+	if status != http.StatusOK || string(body) != "pacman" {
+		t.Errorf("unexpected raw entry %d %q", status, body)
+	}
+
+	req = mux.SetURLVars(
+		httptest.NewRequest(http.MethodGet, "/api/preview/thumb/mame.zip?archive=snap/pacman.png", nil),
+		map[string]string{"size": "thumb", "path": "mame.zip"},
+	)
+	preview := previewHandler(img.New(1), diskcache.NewNoOp(), true, true)
+	status, body = ts.serve(preview, "", req)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("thumbnail isn't a jpeg: %v", err)
+	}
+	if size := thumb.Bounds().Size(); size.X != 256 || size.Y != 256 {
+		t.Errorf("unexpected thumbnail size %v", size)
+	}
+}
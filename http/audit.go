@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tomasen/realip"
+
+	"github.com/filebrowser/filebrowser/v2/audit"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/storage"
+)
+
+// auditPruneInterval is how often entries older than audit.MaxAge are
+// deleted from the audit log.
+const auditPruneInterval = time.Hour
+
+// pruneAudit deletes the old entries of the audit log now and then
+// every auditPruneInterval until ctx is done.
+func pruneAudit(ctx context.Context, store *storage.Storage) {
+	ticker := time.NewTicker(auditPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := store.Audit.Prune(audit.MaxAge); err != nil {
+			log.Printf("Failed to prune the audit log: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordAudit saves the request made by a support session to the audit log.
+func recordAudit(r *http.Request, d *data, path string, status int) {
+	if d.session == "" {
+		return
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	entry := &audit.Entry{
+		Session:    d.session,
+		Action:     d.auditAction,
+		Method:     r.Method,
+		Path:       path,
+		Dst:        r.URL.Query().Get("destination"),
+		Status:     status,
+		RemoteAddr: realip.FromRequest(r),
+	}
+	if entry.Action == "" {
+		entry.Action = audit.ActionRequest
+	}
+	if d.user != nil {
+		entry.Username = d.user.Username
+	}
+
+	if err := d.store.Audit.Save(entry); err != nil {
+		log.Printf("failed to save audit entry: %v", err)
+	}
+}
+
+func generateSessionID() (string, error) {
+	b := make([]byte, 8) //nolint:gomnd
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+var logoutHandler = withUser(func(_ http.ResponseWriter, _ *http.Request, d *data) (int, error) {
+	if d.session != "" {
+		d.auditAction = audit.ActionLogout
+	}
+	return http.StatusOK, nil
+})
+
+var supportAuditHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{Session: query.Get("session")}
+
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		filter.Since = since
+	}
+
+	if v := query.Get("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		filter.Until = until
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid limit %q: %w", v, fbErrors.ErrInvalidRequestParams)
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := d.store.Audit.Find(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return renderJSON(w, r, entries)
+})
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	"github.com/pquerna/otp/totp"
+
+	"github.com/filebrowser/filebrowser/v2/audit"
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/storage/bolt"
+	"github.com/filebrowser/filebrowser/v2/support"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestSupportSessionAudit(t *testing.T) {
+	db, err := storm.Open(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := bolt.NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to get storage: %v", err)
+	}
+	if err := storage.Users.Save(&users.User{Username: "rcadeadmin", Password: "pw"}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	if err := storage.Settings.Save(&settings.Settings{Key: []byte("key")}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+
+	bin := filepath.Join(t.TempDir(), "cloudflared")
+	script := "#!/bin/sh\n" +
+		"echo 'INF |  https://fake.trycloudflare.com  |' >&2\n" +
+		"echo 'INF Registered tunnel connection' >&2\n" +
+		"exec sleep 60\n"
+	if err := os.WriteFile(bin, []byte(script), 0700); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	tunnels := support.NewTunnelManager(support.Config{BinaryPath: bin})
+	status, err := tunnels.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tunnels.Stop)
+
+	secret, err := storage.Settings.SupportSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &settings.Server{}
+	serve := func(fn handleFunc, r *http.Request) (int, string) {
+		recorder := httptest.NewRecorder()
+		handle(fn, "", storage, server).ServeHTTP(recorder, r)
+		result := recorder.Result()
+		defer result.Body.Close()
+		body, _ := io.ReadAll(result.Body)
+		return result.StatusCode, string(body)
+	}
+
+	body := `{"supportCode":"` + status.SessionCode + `","totp":"` + code + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/supportlogin", strings.NewReader(body))
+	st, token := serve(supportLoginHandler(time.Hour, tunnels), req)
+	if st != http.StatusOK {
+		t.Fatalf("support login failed with status %d", st)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/renew", http.NoBody)
+	req.Header.Set("X-Auth", token)
+	if st, token = serve(renewHandler(time.Hour), req); st != http.StatusOK {
+		t.Fatalf("renew failed with status %d", st)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/logout", http.NoBody)
+	req.Header.Set("X-Auth", token)
+	if st, _ = serve(logoutHandler, req); st != http.StatusOK {
+		t.Fatalf("logout failed with status %d", st)
+	}
+
+	entries, err := storage.Audit.Find(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		action audit.Action
+		path   string
+	}{
+		{audit.ActionLogout, "/api/logout"},
+		{audit.ActionRequest, "/api/renew"},
+		{audit.ActionLogin, "/api/supportlogin"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d audit entries, got %d", len(want), len(entries))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Action != w.action || e.Path != w.path || e.Username != "rcadeadmin" || e.Session != entries[0].Session {
+			t.Errorf("unexpected audit entry %d: %+v", i, e)
+		}
+	}
+
+	if _, err := storage.Settings.RotateSupportSecret(); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/supportlogin", strings.NewReader(body))
+	if st, _ = serve(supportLoginHandler(time.Hour, tunnels), req); st != http.StatusForbidden {
+		t.Errorf("login with a rotated secret returned status %d", st)
+	}
+}
+
+func TestSupportAuditLog(t *testing.T) {
+	ts := newTestServer(t, t.TempDir(), &users.User{
+		Username: "admin",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Admin: true},
+	})
+
+	// Entries past the age limit are pruned.
+	for _, e := range []*audit.Entry{
+		{Session: "old", Time: time.Now().Add(-audit.MaxAge - time.Hour)},
+		{Session: "new"},
+	} {
+		if err := ts.storage.Audit.Save(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ts.storage.Audit.Prune(audit.MaxAge); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ts.storage.Audit.Find(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Session != "new" {
+		t.Errorf("expected only the new entry to be kept, got %+v", entries)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/support/audit?limit=-1", nil)
+	if status, body := ts.serve(supportAuditHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative limit, got %d: %s", status, body)
+	}
+}
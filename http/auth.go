@@ -13,7 +13,9 @@ import (
 	"github.com/golang-jwt/jwt/v4/request"
 	"github.com/pquerna/otp/totp"
 
+	"github.com/filebrowser/filebrowser/v2/audit"
 	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/support"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
@@ -40,7 +42,8 @@ type supportLogin struct {
 }
 
 type authToken struct {
-	User userInfo `json:"user"`
+	User    userInfo `json:"user"`
+	Support string   `json:"support,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -84,17 +87,20 @@ func withUser(fn handleFunc) handleFunc {
 			return http.StatusUnauthorized, nil
 		}
 
+		d.user, err = effectiveUser(d.store, d.server.Root, tk.User.ID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		// Tokens carry the permissions and commands of the user, which
+		// also come from its groups.
 		expired := !tk.VerifyExpiresAt(time.Now().Add(time.Hour), true)
-		updated := tk.IssuedAt != nil && tk.IssuedAt.Unix() < d.store.Users.LastUpdate(tk.User.ID)
+		updated := tk.IssuedAt != nil && tk.IssuedAt.Unix() < max(d.store.Users.LastUpdate(tk.User.ID), d.store.Groups.LastUpdate(d.user))
 
 		if expired || updated {
 			w.Header().Add("X-Renew-Token", "true")
 		}
-
-		d.user, err = d.store.Users.Get(d.server.Root, tk.User.ID)
-		if err != nil {
-			return http.StatusInternalServerError, err
-		}
+		d.session = tk.Support
 		return fn(w, r, d)
 	}
 }
@@ -124,11 +130,15 @@ func loginHandler(tokenExpireTime time.Duration) handleFunc {
 			return http.StatusInternalServerError, err
 		}
 
+		if err := d.store.Groups.Apply(user); err != nil {
+			return http.StatusInternalServerError, err
+		}
+
 		return printToken(w, r, d, user, tokenExpireTime)
 	}
 }
 
-func supportLoginHandler(tokenExpireTime time.Duration) handleFunc {
+func supportLoginHandler(tokenExpireTime time.Duration, tunnels *support.TunnelManager) handleFunc {
 	return func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
 		cred := &supportLogin{}
 
@@ -137,21 +147,33 @@ func supportLoginHandler(tokenExpireTime time.Duration) handleFunc {
 			return http.StatusInternalServerError, err
 		}
 
-		if cred.SupportCode != supportSessionCode {
+		code := tunnels.SessionCode()
+		if code == "" || cred.SupportCode != code {
 			return http.StatusForbidden, nil
 		}
 
-		secret := "GUZYFEFT5YC3QN4V5K2LERPSIJHUVVZV"
+		secret, err := d.store.Settings.SupportSecret()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
 
 		valid := totp.Validate(cred.TOTP, secret)
 		if !valid {
 			return http.StatusForbidden, nil
 		}
 
-		user, err := d.store.Users.Get(d.server.Root, "rcadeadmin")
+		user, err := effectiveUser(d.store, d.server.Root, "rcadeadmin")
 		if err != nil {
 			return http.StatusInternalServerError, err
 		}
+
+		d.user = user
+		d.session, err = generateSessionID()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		d.auditAction = audit.ActionLogin
+
 		return printToken(w, r, d, user, tokenExpireTime)
 	}
 }
@@ -236,6 +258,7 @@ func printToken(w http.ResponseWriter, _ *http.Request, d *data, user *users.Use
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExpirationTime)),
 			Issuer:    "File Browser",
 		},
+		Support: d.session,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// parseCompressOptions reads the algo, level and exclude query
+// parameters of a compression. exclude is a comma separated list of
+// glob patterns.
+func parseCompressOptions(r *http.Request) (*archive.CreateOptions, error) {
+	format, store, err := parseQueryFormat(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", err, fbErrors.ErrInvalidRequestParams)
+	}
+	opts := &archive.CreateOptions{Format: format, Store: store}
+
+	if level := r.URL.Query().Get("level"); level != "" {
+		opts.Level, err = strconv.Atoi(level)
+		if err != nil || opts.Level < 1 || opts.Level > archive.MaxLevel {
+			return nil, fmt.Errorf("invalid compression level %q: %w", level, fbErrors.ErrInvalidRequestParams)
+		}
+	}
+
+	for _, pattern := range strings.Split(r.URL.Query().Get("exclude"), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, fbErrors.ErrInvalidRequestParams)
+		}
+		opts.Exclude = append(opts.Exclude, pattern)
+	}
+
+	return opts, nil
+}
+
+// withArchiveExtension appends the extension of format to dst unless
+// it already ends with it.
+func withArchiveExtension(dst string, format archive.Format) string {
+	ext := format.Extension()
+	if strings.HasSuffix(strings.ToLower(dst), ext) {
+		return dst
+	}
+	return dst + ext
+}
+
+// compressFiles archives the files listed in the files query
+// parameter, relative to src, or src itself, into dst.
+func compressFiles(
+	w http.ResponseWriter,
+	r *http.Request,
+	src, dst string,
+	override bool,
+	opts *archive.CreateOptions,
+	d *data,
+	hub *events.Hub,
+	jobManager *jobs.Manager,
+	quotas *quota.Tracker,
+) (int, error) {
+	if !d.user.Perm.Create {
+		return http.StatusForbidden, nil
+	}
+
+	file, err := files.NewFileInfo(&files.FileOptions{
+		Fs:         d.user.Fs,
+		Path:       src,
+		Modify:     d.user.Perm.Modify,
+		Expand:     false,
+		ReadHeader: false,
+		Checker:    d,
+	})
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	sources, err := parseQueryFiles(r, file, d.user)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for i, source := range sources {
+		sources[i] = path.Clean("/" + source)
+		if !d.Check(sources[i]) {
+			return http.StatusForbidden, nil
+		}
+	}
+
+	dst = path.Clean("/" + dst)
+	if err := reserveArchive(quotas, d, sources); err != nil {
+		return errToStatus(err), err
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		return enqueueJob(w, d, jobManager, &jobs.Job{
+			Action:   jobs.Compress,
+			Src:      file.Path,
+			Dst:      dst,
+			IsDir:    file.IsDir,
+			Override: override,
+			Sources:  sources,
+			Archive:  opts,
+		})
+	}
+
+	opts.Checker = rules.ForAction(d, rules.Read)
+	err = d.RunHook(func() error {
+		if err := archive.Create(r.Context(), d.user.Fs, sources, dst, *opts); err != nil {
+			return err
+		}
+
+		publishEvent(hub, d, events.Create, dst, "", false)
+		return nil
+	}, "compress", src, dst, d.user)
+
+	// The archive was accounted for by the size of its sources.
+	invalidateUsage(quotas, d)
+	return errToStatus(err), err
+}
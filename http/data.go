@@ -1,16 +1,25 @@
 package http
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/spf13/afero"
 	"github.com/tomasen/realip"
 
+	"github.com/filebrowser/filebrowser/v2/audit"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
 	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/runner"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/trash"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
@@ -23,28 +32,145 @@ type data struct {
 	store    *storage.Storage
 	user     *users.User
 	raw      interface{}
+
+	// session identifies the support session the request was made
+	// with, if any. Its requests are recorded in the audit log.
+	session     string
+	auditAction audit.Action
+
+	// bins caches trashBins.
+	bins []string
 }
 
 // Check implements rules.Checker.
 func (d *data) Check(path string) bool {
+	// The trash is only reachable through its own API.
+	if trash.IsTrashPath(path, d.trashBins()...) {
+		return false
+	}
+
 	if d.user.HideDotfiles && rules.MatchHidden(path) {
 		return false
 	}
 
-	allow := true
-	for _, rule := range d.settings.Rules {
-		if rule.Matches(path) {
-			allow = rule.Allow
+	return rules.Allowed(path, "", d.ruleLists()...)
+}
+
+// CheckAction implements rules.ActionChecker.
+func (d *data) CheckAction(path string, action rules.Action) bool {
+	return d.Check(path) && rules.Allowed(path, action, d.ruleLists()...)
+}
+
+// trashBins returns the trash bins in the scope of the user, relative
+// to it: its own and those of the users whose scope is in its scope.
+// Only its own is known without a store.
+func (d *data) trashBins() []string {
+	if d.bins != nil {
+		return d.bins
+	}
+
+	d.bins = []string{trash.BinPath(d.user.ID)}
+	root, ok := scopeRoot(d)
+	if !ok || d.store == nil || d.server == nil {
+		return d.bins
+	}
+	list, err := d.store.Users.Gets(d.server.Root)
+	if err != nil {
+		log.Printf("Failed to list the trash bins of %s: %v", d.user.Username, err)
+		return d.bins
+	}
+	for _, u := range list {
+		if u.ID == d.user.ID {
+			continue
+		}
+		if rel, ok := relativeTo(root, u.FullPath(trash.BinPath(u.ID))); ok {
+			d.bins = append(d.bins, rel)
+		}
+	}
+	return d.bins
+}
+
+// ruleLists returns the rules that apply to the user, from the lowest
+// precedence to the highest.
+func (d *data) ruleLists() [][]rules.Rule {
+	return [][]rules.Rule{d.settings.Rules, d.user.GroupRules, d.user.Rules}
+}
+
+// checkTree reports whether action can be done on path and, if it's a
+// directory, on everything it holds.
+func (d *data) checkTree(path string, action rules.Action) (bool, error) {
+	if !d.CheckAction(path, action) {
+		return false, nil
+	}
+
+	// Most of the time there's nothing else to look at.
+	if !d.mayDeny(action) {
+		return true, nil
+	}
+
+	// afero.Walk doesn't know about filepath.SkipAll.
+	errDenied := errors.New("denied")
+	err := afero.Walk(d.user.Fs, path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
+		if !d.Check(p) {
+			// Hidden files are left alone.
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !rules.Allowed(p, action, d.ruleLists()...) {
+			return errDenied
+		}
+		return nil
+	})
+	if errors.Is(err, errDenied) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkTargets reports whether what's at src, and in it if it's a
+// directory, can be written to the same place under dst: created where
+// there's nothing yet and modified otherwise.
+func (d *data) checkTargets(src, dst string) (bool, error) {
+	if !d.mayDeny(rules.Create) && !d.mayDeny(rules.Modify) {
+		return true, nil
 	}
 
-	for _, rule := range d.user.Rules {
-		if rule.Matches(path) {
-			allow = rule.Allow
+	errDenied := errors.New("denied")
+	err := afero.Walk(d.user.Fs, src, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, strings.TrimPrefix(p, src))
+		action := rules.Create
+		if _, err := d.user.Fs.Stat(target); err == nil {
+			action = rules.Modify
 		}
+		if !d.CheckAction(target, action) {
+			return errDenied
+		}
+		return nil
+	})
+	if errors.Is(err, errDenied) {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	return allow
+// mayDeny reports whether any of the rules of the user denies action.
+func (d *data) mayDeny(action rules.Action) bool {
+	for _, list := range d.ruleLists() {
+		for i := range list {
+			if list[i].Decides(action) && !list[i].Allow {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func handle(fn handleFunc, prefix string, store *storage.Storage, server *settings.Server) http.Handler {
@@ -59,12 +185,15 @@ func handle(fn handleFunc, prefix string, store *storage.Storage, server *settin
 			return
 		}
 
-		status, err := fn(w, r, &data{
+		d := &data{
 			Runner:   &runner.Runner{Enabled: server.EnableExec, Settings: settings},
 			store:    store,
 			settings: settings,
 			server:   server,
-		})
+		}
+
+		status, err := fn(w, r, d)
+		recordAudit(r, d, prefix+r.URL.Path, status)
 
 		if status >= 400 || err != nil {
 			clientIP := realip.FromRequest(r)
@@ -73,6 +202,10 @@ func handle(fn handleFunc, prefix string, store *storage.Storage, server *settin
 
 		if status != 0 {
 			txt := http.StatusText(status)
+			// Tell what didn't fit, so that users know how much to free.
+			if errors.Is(err, fbErrors.ErrQuotaExceeded) {
+				txt += ": " + err.Error()
+			}
 			http.Error(w, strconv.Itoa(status)+" "+txt, status)
 			return
 		}
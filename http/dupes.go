@@ -0,0 +1,65 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/filebrowser/filebrowser/v2/dupes"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// dupesHandler looks for duplicate files in the directory at the path
+// of the request. It takes the checksum algorithm as algo, a comma
+// separated list of extensions as ext, the minimum size in bytes as
+// minSize and, with archives=true, looks inside archives too.
+var dupesHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if !d.CheckAction(r.URL.Path, rules.Read) {
+		return http.StatusForbidden, nil
+	}
+
+	query := r.URL.Query()
+	opts := dupes.Options{
+		Algorithm: query.Get("algo"),
+		Archives:  query.Get("archives") == "true",
+		Checker:   rules.ForAction(d, rules.Read),
+	}
+	for _, ext := range strings.Split(query.Get("ext"), ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			opts.Extensions = append(opts.Extensions, ext)
+		}
+	}
+	if minSize := query.Get("minSize"); minSize != "" {
+		var err error
+		opts.MinSize, err = strconv.ParseInt(minSize, 10, 64)
+		if err != nil || opts.MinSize < 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid minimum size %q: %w", minSize, fbErrors.ErrInvalidRequestParams)
+		}
+	}
+
+	dir, err := files.NewFileInfo(&files.FileOptions{
+		Fs:      d.user.Fs,
+		Path:    r.URL.Path,
+		Modify:  d.user.Perm.Modify,
+		Expand:  false,
+		Checker: d,
+	})
+	if err != nil {
+		return errToStatus(err), err
+	}
+	if !dir.IsDir {
+		return http.StatusBadRequest, fmt.Errorf("%s is not a directory: %w", dir.Path, fbErrors.ErrInvalidRequestParams)
+	}
+
+	report, err := dupes.Find(r.Context(), d.user.Fs, dir.Path, opts)
+	if errors.Is(err, fbErrors.ErrInvalidOption) {
+		return http.StatusBadRequest, err
+	} else if err != nil {
+		return errToStatus(err), err
+	}
+	return renderJSON(w, r, report)
+})
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/dupes"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestDupesHandler(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.sfc", "b.sfc", ".c.sfc"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("zelda"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := newTestServer(t, root, &users.User{Username: "player", Password: "pw", Scope: ".", HideDotfiles: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/dupes/?ext=sfc&algo=md5", nil)
+	status, body := ts.serve(dupesHandler, "/api/dupes", req)
+	var report dupes.Report
+	if err := json.Unmarshal(body, &report); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	// The dotfile is hidden from the user.
+	if len(report.Groups) != 1 || len(report.Groups[0].Files) != 2 || report.Reclaimable != 5 {
+		t.Errorf("unexpected report %s", body)
+	}
+
+	for _, url := range []string{"/api/dupes/?algo=crc64", "/api/dupes/?minSize=-1", "/api/dupes/a.sfc"} {
+		req = httptest.NewRequest(http.MethodGet, url, nil)
+		if status, _ = ts.serve(dupesHandler, "/api/dupes", req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, status)
+		}
+	}
+}
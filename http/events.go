@@ -0,0 +1,152 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+)
+
+const eventsKeepAlive = 30 * time.Second
+
+// publishEvent publishes a change made through the user's scope.
+func publishEvent(hub *events.Hub, d *data, typ events.Type, path, dst string, isDir bool) {
+	root, ok := scopeRoot(d)
+	if !ok {
+		return
+	}
+
+	e := events.Event{
+		Type:   typ,
+		Path:   filepath.Join(root, path),
+		IsDir:  isDir,
+		Source: events.SourceServer,
+	}
+	if dst != "" {
+		e.Dst = filepath.Join(root, dst)
+	}
+
+	hub.Publish(e)
+}
+
+// scopeRoot returns the absolute path of the user's scope on the host.
+func scopeRoot(d *data) (string, bool) {
+	if _, ok := d.user.Fs.(*afero.BasePathFs); !ok {
+		return "", false
+	}
+	return d.user.FullPath("/"), true
+}
+
+// userEvent translates an event to the paths of the user's scope,
+// dropping it if the user isn't allowed to see it.
+func userEvent(d *data, root string, e events.Event) (events.Event, bool) {
+	src, srcOk := relativeTo(root, e.Path)
+	srcOk = srcOk && d.Check(src)
+
+	dst, dstOk := "", false
+	if e.Dst != "" {
+		dst, dstOk = relativeTo(root, e.Dst)
+		dstOk = dstOk && d.Check(dst)
+	}
+
+	switch {
+	case srcOk && (e.Dst == "" || dstOk):
+		e.Path, e.Dst = src, dst
+	case srcOk && e.Type == events.Rename:
+		// Moved out of sight.
+		e.Type, e.Path, e.Dst = events.Delete, src, ""
+	case dstOk:
+		// Moved or copied into sight.
+		e.Type, e.Path, e.Dst = events.Create, dst, ""
+	default:
+		return e, false
+	}
+
+	return e, true
+}
+
+// relativeTo returns path relative to root, if it's inside of it.
+func relativeTo(root, path string) (string, bool) {
+	if root == string(filepath.Separator) {
+		return filepath.ToSlash(path), true
+	}
+	if path == root {
+		return "/", true
+	}
+
+	rel, ok := strings.CutPrefix(path, root+string(filepath.Separator))
+	if !ok {
+		return "", false
+	}
+	return "/" + filepath.ToSlash(rel), true
+}
+
+func eventsHandler(hub *events.Hub) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		root, ok := scopeRoot(d)
+		if !ok {
+			return http.StatusNotImplemented, nil
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return http.StatusNotImplemented, nil
+		}
+
+		sub := hub.Subscribe(events.DefaultBuffer)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return 0, nil
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return 0, nil
+				}
+			case e, ok := <-sub.Events():
+				if !ok {
+					return 0, nil
+				}
+
+				// Let the client know it missed events, so that
+				// it can reload whatever it's showing.
+				if n := sub.Dropped(); n > 0 {
+					if _, err := fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", n); err != nil {
+						return 0, nil
+					}
+				}
+
+				e, ok = userEvent(d, root, e)
+				if !ok {
+					continue
+				}
+
+				data, err := json.Marshal(e)
+				if err != nil {
+					return 0, nil
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+					return 0, nil
+				}
+			}
+
+			flusher.Flush()
+		}
+	})
+}
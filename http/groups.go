@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// effectiveUser gets a user along with what it gets from its groups.
+func effectiveUser(store *storage.Storage, root string, id interface{}) (*users.User, error) {
+	u, err := store.Users.Get(root, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Groups.Apply(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func withGroup(fn func(w http.ResponseWriter, r *http.Request, d *data, g *groups.Group) (int, error)) handleFunc {
+	return withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		id, err := getUserID(r)
+		if err != nil {
+			return http.StatusNotFound, nil
+		}
+
+		g, err := d.store.Groups.Get(id)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		return fn(w, r, d, g)
+	})
+}
+
+// saveGroupStatus returns the status of saving a group.
+func saveGroupStatus(err error) int {
+	switch {
+	case errors.Is(err, fbErrors.ErrEmptyGroupName), errors.Is(err, fbErrors.ErrInvalidOption):
+		return http.StatusBadRequest
+	default:
+		return errToStatus(err)
+	}
+}
+
+var groupsGetHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	list, err := d.store.Groups.Gets()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return renderJSON(w, r, list)
+})
+
+var groupGetHandler = withGroup(func(w http.ResponseWriter, r *http.Request, _ *data, g *groups.Group) (int, error) {
+	return renderJSON(w, r, g)
+})
+
+var groupPostHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	g := &groups.Group{}
+	if err := json.NewDecoder(r.Body).Decode(g); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	g.ID = 0
+	if err := d.store.Groups.Save(g); err != nil {
+		return saveGroupStatus(err), err
+	}
+
+	w.Header().Set("Location", "/settings/groups/"+strconv.FormatUint(uint64(g.ID), 10))
+	return http.StatusCreated, nil
+})
+
+// groupPutHandler replaces a group. The tokens of its members are
+// renewed, so that they get the new permissions and commands.
+var groupPutHandler = withGroup(func(_ http.ResponseWriter, r *http.Request, d *data, g *groups.Group) (int, error) {
+	req := &groups.Group{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	req.ID = g.ID
+	if err := d.store.Groups.Save(req); err != nil {
+		return saveGroupStatus(err), err
+	}
+	return http.StatusOK, nil
+})
+
+var groupDeleteHandler = withGroup(func(_ http.ResponseWriter, _ *http.Request, d *data, g *groups.Group) (int, error) {
+	if err := d.store.Groups.Delete(g.ID); err != nil {
+		return errToStatus(err), err
+	}
+	return http.StatusOK, nil
+})
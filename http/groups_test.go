@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestGroups(t *testing.T) {
+	ts := newTestServer(t, t.TempDir(), &users.User{Username: "kid", Password: "pw", Scope: "."})
+
+	family := &groups.Group{Name: "family", Perm: users.Permissions{Admin: true, Download: true}, Commands: []string{"ls"}}
+	if err := ts.storage.Groups.Save(family); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/groups", nil)
+	if status, _ := ts.serve(groupsGetHandler, "", req); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for users, got %d", status)
+	}
+
+	// Users get what their groups have.
+	ts.user.Groups = []uint{family.ID}
+	if err := ts.storage.Users.Update(ts.user, "Groups"); err != nil {
+		t.Fatal(err)
+	}
+	status, body := ts.serve(groupsGetHandler, "", req)
+	var list []*groups.Group
+	if err := json.Unmarshal(body, &list); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	if len(list) != 1 || list[0].Name != "family" {
+		t.Errorf("unexpected groups %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/groups", strings.NewReader(`{"name":"friends","rules":[{"glob":true,"path":"["}]}`))
+	if status, _ = ts.serve(groupPostHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid rule, got %d", status)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/groups", strings.NewReader(`{"name":"family"}`))
+	if status, _ = ts.serve(groupPostHandler, "", req); status != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate name, got %d", status)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/groups", strings.NewReader(`{"name":"friends","perm":{"share":true}}`))
+	if status, body = ts.serve(groupPostHandler, "", req); status != http.StatusCreated {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	friends, err := ts.storage.Groups.Get("friends")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = groupRequest(http.MethodPut, family.ID, strings.NewReader(`{"name":"family","perm":{"admin":true},"commands":["df"]}`))
+	if status, body = ts.serve(groupPutHandler, "", req); status != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	u, err := effectiveUser(ts.storage, "", ts.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Perm.Admin || u.Perm.Download || !slices.Equal(u.Commands, []string{"df"}) {
+		t.Errorf("expected the updated group to apply, got %+v and %v", u.Perm, u.Commands)
+	}
+
+	// The time of the update is kept with the group, so sessions
+	// issued before it are renewed even after a restart.
+	saved, err := ts.storage.Groups.Get(family.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Updated == 0 || ts.storage.Groups.LastUpdate(u) != saved.Updated {
+		t.Errorf("expected the update time to be saved, got %d and %d", saved.Updated, ts.storage.Groups.LastUpdate(u))
+	}
+
+	// Deleted groups are removed from their members.
+	ts.user.Groups = []uint{family.ID, friends.ID}
+	if err := ts.storage.Users.Update(ts.user, "Groups"); err != nil {
+		t.Fatal(err)
+	}
+	req = groupRequest(http.MethodDelete, friends.ID, nil)
+	if status, _ = ts.serve(groupDeleteHandler, "", req); status != http.StatusOK {
+		t.Fatalf("unexpected status %d", status)
+	}
+	u, err = ts.storage.Users.Get("", ts.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(u.Groups, []uint{family.ID}) {
+		t.Errorf("expected the group to be removed, got %v", u.Groups)
+	}
+	req = groupRequest(http.MethodGet, friends.ID, nil)
+	if status, _ = ts.serve(groupGetHandler, "", req); status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", status)
+	}
+}
+
+func groupRequest(method string, id uint, body io.Reader) *http.Request {
+	value := strconv.FormatUint(uint64(id), 10)
+	return mux.SetURLVars(httptest.NewRequest(method, "/api/groups/"+value, body), map[string]string{"id": value})
+}
@@ -1,13 +1,18 @@
 package http
 
 import (
+	"context"
 	"io/fs"
+	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/quota"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/support"
 )
 
 type modifyRequest struct {
@@ -15,7 +20,11 @@ type modifyRequest struct {
 	Which []string `json:"which"` // Answer to: which fields?
 }
 
+// NewHandler returns the handler of the server. The background work it
+// starts, such as watching the root and running jobs, stops when ctx
+// is done.
 func NewHandler(
+	ctx context.Context,
 	imgSvc ImgService,
 	fileCache FileCache,
 	store *storage.Storage,
@@ -31,6 +40,7 @@ func NewHandler(
 			next.ServeHTTP(w, r)
 		})
 	})
+	r.Use(instrumentRoute)
 	index, static := getStaticHandlers(store, server, assetsFs)
 
 	// NOTE: This fixes the issue where it would redirect if people did not put a
@@ -43,6 +53,7 @@ func NewHandler(
 	}
 
 	r.HandleFunc("/health", healthHandler)
+	r.Handle("/metrics", monkey(metricsHandler, "")).Methods("GET")
 	r.HandleFunc("/splash", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "/rcade/resources/splash/RCadeSplash.jpg")
 	})
@@ -51,11 +62,36 @@ func NewHandler(
 
 	api := r.PathPrefix("/api").Subrouter()
 
+	tunnels := support.NewTunnelManager(support.Config{
+		BinaryPath:     "/tmp/cloudflared",
+		Download:       support.DownloadCloudflared,
+		LocalURL:       "http://localhost:" + server.Port,
+		SessionTimeout: server.GetSupportSessionTimeout(support.DefaultSessionTimeout),
+	})
+
+	hub := events.NewHub()
+	if limit := server.GetWatchLimit(events.DefaultLimit); limit >= 0 {
+		if err := events.Watch(ctx, server.Root, hub, limit); err != nil {
+			log.Printf("Failed to watch %s for changes: %v", server.Root, err)
+		}
+	}
+
+	searchIndex := openSearchIndex(ctx, server, hub)
+
+	jobManager := newJobManager(store, server, hub)
+	if err := jobManager.Start(ctx); err != nil {
+		log.Printf("Failed to resume jobs: %v", err)
+	}
+
+	quotas := quota.NewTracker()
+	quotas.Start(ctx, hub)
+
 	tokenExpirationTime := server.GetTokenExpirationTime(DefaultTokenExpirationTime)
 	api.Handle("/login", monkey(loginHandler(tokenExpirationTime), ""))
-	api.Handle("/supportlogin", monkey(supportLoginHandler(tokenExpirationTime), ""))
+	api.Handle("/supportlogin", monkey(supportLoginHandler(tokenExpirationTime, tunnels), ""))
 	api.Handle("/signup", monkey(signupHandler, ""))
 	api.Handle("/renew", monkey(renewHandler(tokenExpirationTime), ""))
+	api.Handle("/logout", monkey(logoutHandler, "")).Methods("POST")
 
 	users := api.PathPrefix("/users").Subrouter()
 	users.Handle("", monkey(usersGetHandler, "")).Methods("GET")
@@ -64,19 +100,40 @@ func NewHandler(
 	users.Handle("/{id:[0-9]+}", monkey(userGetHandler, "")).Methods("GET")
 	users.Handle("/{id:[0-9]+}", monkey(userDeleteHandler, "")).Methods("DELETE")
 
+	groups := api.PathPrefix("/groups").Subrouter()
+	groups.Handle("", monkey(groupsGetHandler, "")).Methods("GET")
+	groups.Handle("", monkey(groupPostHandler, "")).Methods("POST")
+	groups.Handle("/{id:[0-9]+}", monkey(groupPutHandler, "")).Methods("PUT")
+	groups.Handle("/{id:[0-9]+}", monkey(groupGetHandler, "")).Methods("GET")
+	groups.Handle("/{id:[0-9]+}", monkey(groupDeleteHandler, "")).Methods("DELETE")
+
 	api.PathPrefix("/resources/virtual").Handler(monkey(resourceVirtualGetHandler, "/api/resources/virtual")).Methods("GET")
 	api.PathPrefix("/resources").Handler(monkey(resourceGetHandler, "/api/resources")).Methods("GET")
-	api.PathPrefix("/resources").Handler(monkey(resourceDeleteHandler(fileCache), "/api/resources")).Methods("DELETE")
-	api.PathPrefix("/resources").Handler(monkey(resourcePostHandler(fileCache), "/api/resources")).Methods("POST")
-	api.PathPrefix("/resources").Handler(monkey(resourcePutHandler, "/api/resources")).Methods("PUT")
-	api.PathPrefix("/resources").Handler(monkey(resourcePatchHandler(fileCache), "/api/resources")).Methods("PATCH")
+	api.PathPrefix("/resources").Handler(monkey(resourceDeleteHandler(fileCache, hub, jobManager, false), "/api/resources")).Methods("DELETE")
+	api.PathPrefix("/resources").Handler(monkey(resourcePostHandler(fileCache, hub, quotas), "/api/resources")).Methods("POST")
+	api.PathPrefix("/resources").Handler(monkey(resourcePutHandler(hub, quotas), "/api/resources")).Methods("PUT")
+	api.PathPrefix("/resources").Handler(monkey(resourcePatchHandler(fileCache, hub, jobManager, quotas), "/api/resources")).Methods("PATCH")
 
-	api.PathPrefix("/tus").Handler(monkey(tusPostHandler(), "/api/tus")).Methods("POST")
+	api.PathPrefix("/tus").Handler(monkey(tusPostHandler(hub, quotas), "/api/tus")).Methods("POST")
 	api.PathPrefix("/tus").Handler(monkey(tusHeadHandler(), "/api/tus")).Methods("HEAD", "GET")
-	api.PathPrefix("/tus").Handler(monkey(tusPatchHandler(), "/api/tus")).Methods("PATCH")
-	api.PathPrefix("/tus").Handler(monkey(resourceDeleteHandler(fileCache), "/api/tus")).Methods("DELETE")
+	api.PathPrefix("/tus").Handler(monkey(tusPatchHandler(hub, quotas), "/api/tus")).Methods("PATCH")
+	// Cancelled uploads don't go to the trash.
+	api.PathPrefix("/tus").Handler(monkey(resourceDeleteHandler(fileCache, hub, nil, true), "/api/tus")).Methods("DELETE")
+
+	api.Handle("/events", monkey(eventsHandler(hub), "")).Methods("GET")
+
+	api.Handle("/jobs", monkey(jobsGetHandler, "")).Methods("GET")
+	api.Handle("/jobs/{id:[0-9]+}", monkey(jobGetHandler, "")).Methods("GET")
+	api.Handle("/jobs/{id:[0-9]+}", monkey(jobDeleteHandler(jobManager), "")).Methods("DELETE")
+	api.Handle("/jobs/{id:[0-9]+}/resume", monkey(jobResumeHandler(jobManager), "")).Methods("POST")
+
+	go pruneTrashes(ctx, store, server.Root)
+	api.Handle("/trash", monkey(trashListHandler, "")).Methods("GET")
+	api.Handle("/trash", monkey(trashDeleteHandler, "")).Methods("DELETE")
+	api.Handle("/trash/{id}", monkey(trashDeleteHandler, "")).Methods("DELETE")
+	api.Handle("/trash/{id}/restore", monkey(trashRestoreHandler(fileCache, hub, quotas), "")).Methods("POST")
 
-	api.PathPrefix("/usage").Handler(monkey(diskUsage, "/api/usage")).Methods("GET")
+	api.PathPrefix("/usage").Handler(monkey(diskUsage(quotas), "/api/usage")).Methods("GET")
 
 	api.Path("/shares").Handler(monkey(shareListHandler, "/api/shares")).Methods("GET")
 	api.PathPrefix("/share").Handler(monkey(shareGetsHandler, "/api/share")).Methods("GET")
@@ -85,20 +142,30 @@ func NewHandler(
 
 	api.Handle("/settings", monkey(settingsGetHandler, "")).Methods("GET")
 	api.Handle("/settings", monkey(settingsPutHandler, "")).Methods("PUT")
+	api.Handle("/rules/explain", monkey(rulesExplainHandler, "")).Methods("GET")
 
 	api.Handle("/support", monkey(supportFileHandler, "")).Methods("GET")
 	api.Handle("/support/remount", monkey(supportRemountHandler, "")).Methods("GET")
-	api.Handle("/support/start", monkey(supportStartSessionHandler, "")).Methods("GET")
-	api.Handle("/support/status", monkey(supportSessionStatusHandler, "")).Methods("GET")
-	api.Handle("/support/stop", monkey(supportStopSessionHandler, "")).Methods("GET")
+	api.Handle("/support/start", monkey(supportStartSessionHandler(tunnels), "")).Methods("GET")
+	api.Handle("/support/status", monkey(supportSessionStatusHandler(tunnels), "")).Methods("GET")
+	api.Handle("/support/stop", monkey(supportStopSessionHandler(tunnels), "")).Methods("GET")
+	go pruneAudit(ctx, store)
+	api.Handle("/support/audit", monkey(supportAuditHandler, "")).Methods("GET")
 
 	api.Handle("/sysinfo", monkey(supportSysinfoHandler, "")).Methods("GET")
 
+	api.PathPrefix("/raw/virtual").Handler(monkey(rawVirtualHandler, "/api/raw/virtual")).Methods("GET")
 	api.PathPrefix("/raw").Handler(monkey(rawHandler, "/api/raw")).Methods("GET")
 	api.PathPrefix("/preview/{size}/{path:.*}").
 		Handler(monkey(previewHandler(imgSvc, fileCache, server.EnableThumbnails, server.ResizePreview), "/api/preview")).Methods("GET")
 	api.PathPrefix("/command").Handler(monkey(commandsHandler, "/api/command")).Methods("GET")
-	api.PathPrefix("/search").Handler(monkey(searchHandler, "/api/search")).Methods("GET")
+	api.PathPrefix("/search").Handler(monkey(searchHandler(searchIndex), "/api/search")).Methods("GET")
+	api.Handle("/searchindex", monkey(searchIndexGetHandler(searchIndex), "")).Methods("GET")
+	api.Handle("/searchindex", monkey(searchIndexPostHandler(searchIndex), "")).Methods("POST")
+	api.PathPrefix("/manifest").Handler(monkey(manifestGetHandler(jobManager), "/api/manifest")).Methods("GET")
+	api.PathPrefix("/manifest").Handler(monkey(manifestPostHandler(hub, jobManager, quotas), "/api/manifest")).Methods("POST")
+	api.PathPrefix("/dupes").Handler(monkey(dupesHandler, "/api/dupes")).Methods("GET")
+	api.PathPrefix("/romcheck").Handler(monkey(romcheckHandler, "/api/romcheck")).Methods("GET")
 	api.PathPrefix("/subtitle").Handler(monkey(subtitleHandler, "/api/subtitle")).Methods("GET")
 
 	public := api.PathPrefix("/public").Subrouter()
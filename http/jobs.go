@@ -0,0 +1,228 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/runner"
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// newJobManager creates the manager running the file operations
+// requested with async=true. Jobs fire the same hooks as their
+// synchronous counterparts and publish their changes once done.
+func newJobManager(store *storage.Storage, server *settings.Server, hub *events.Hub) *jobs.Manager {
+	return jobs.NewManager(store.Jobs, jobs.Config{
+		User: func(id uint) (*users.User, error) {
+			return effectiveUser(store, server.Root, id)
+		},
+		Checker: func(user *users.User) (rules.ActionChecker, error) {
+			set, err := store.Settings.Get()
+			if err != nil {
+				return nil, err
+			}
+			return &data{user: user, settings: set, store: store, server: server}, nil
+		},
+		Authorize: func(j *jobs.Job, user *users.User) error {
+			set, err := store.Settings.Get()
+			if err != nil {
+				return err
+			}
+			return authorizeJob(j, &data{user: user, settings: set, store: store, server: server})
+		},
+		Hook: func(j *jobs.Job, user *users.User, fn func() error) error {
+			set, err := store.Settings.Get()
+			if err != nil {
+				return err
+			}
+			r := &runner.Runner{Enabled: server.EnableExec, Settings: set}
+			return r.RunHook(fn, string(j.Action), j.Src, j.Dst, user)
+		},
+		Finished: func(j *jobs.Job, user *users.User) {
+			if j.State != jobs.Done {
+				return
+			}
+
+			d := &data{user: user}
+			switch j.Action {
+			case jobs.Copy:
+				publishEvent(hub, d, events.Create, j.Dst, "", j.IsDir)
+			case jobs.Compress, jobs.Manifest:
+				publishEvent(hub, d, events.Create, j.Dst, "", false)
+			case jobs.Extract:
+				publishEvent(hub, d, events.Create, j.Dst, "", true)
+			case jobs.Move:
+				publishEvent(hub, d, events.Rename, j.Src, j.Dst, j.IsDir)
+			case jobs.Delete:
+				publishEvent(hub, d, events.Delete, j.Src, "", j.IsDir)
+				if set, err := store.Settings.Get(); err == nil {
+					pruneTrash(trashBin(d), set)
+				}
+			}
+		},
+	})
+}
+
+// authorizeJob checks that the user of d can do j, like the requests
+// queuing it do. Extractions, compressions and manifest generations
+// check what they read as they go, and verifications write nothing.
+func authorizeJob(j *jobs.Job, d *data) error {
+	var allowed bool
+	srcAction := rules.Delete
+	switch j.Action {
+	case jobs.Copy:
+		allowed = d.user.Perm.Create
+		srcAction = rules.Read
+	case jobs.Move:
+		allowed = d.user.Perm.Rename
+	case jobs.Delete:
+		allowed = d.user.Perm.Delete
+	case jobs.Extract, jobs.Compress, jobs.Manifest:
+		return authorizeJobDst(j, d)
+	default:
+		return nil
+	}
+	if !allowed || (j.Override && !d.user.Perm.Modify) {
+		return fbErrors.ErrPermissionDenied
+	}
+
+	// Earlier attempts may have moved or deleted the source already.
+	if _, err := d.user.Fs.Stat(j.Src); err == nil {
+		if ok, err := d.checkTree(j.Src, srcAction); err != nil {
+			return err
+		} else if !ok {
+			return fbErrors.ErrPermissionDenied
+		}
+		if j.Action != jobs.Delete {
+			if ok, err := d.checkTargets(j.Src, j.Dst); err != nil {
+				return err
+			} else if !ok {
+				return fbErrors.ErrPermissionDenied
+			}
+		}
+	}
+
+	if j.Action != jobs.Delete && !d.CheckAction(j.Dst, rules.Create) {
+		return fbErrors.ErrPermissionDenied
+	}
+	return nil
+}
+
+// authorizeJobDst checks that the user of d can create the
+// destination of j and, if it's overridden, modify what it holds.
+func authorizeJobDst(j *jobs.Job, d *data) error {
+	if !d.user.Perm.Create || (j.Override && !d.user.Perm.Modify) || !d.CheckAction(j.Dst, rules.Create) {
+		return fbErrors.ErrPermissionDenied
+	}
+
+	if j.Override {
+		if _, err := d.user.Fs.Stat(j.Dst); err == nil {
+			if ok, err := d.checkTree(j.Dst, rules.Modify); err != nil {
+				return err
+			} else if !ok {
+				return fbErrors.ErrPermissionDenied
+			}
+		}
+	}
+	return nil
+}
+
+// enqueueJob queues j for the user and answers with it.
+func enqueueJob(w http.ResponseWriter, d *data, jobManager *jobs.Manager, j *jobs.Job) (int, error) {
+	j.UserID = d.user.ID
+	if err := jobManager.Enqueue(j); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(j); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// withJob loads the job in the URL into d.raw. Users can only see
+// their own jobs, admins all of them.
+func withJob(fn handleFunc) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+		if err != nil {
+			return http.StatusNotFound, nil
+		}
+
+		j, err := d.store.Jobs.Get(uint(id))
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if j.UserID != d.user.ID && !d.user.Perm.Admin {
+			return http.StatusNotFound, nil
+		}
+
+		d.raw = j
+		return fn(w, r, d)
+	})
+}
+
+var jobsGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	filter := jobs.Filter{UserID: d.user.ID}
+	if r.URL.Query().Get("all") == "true" {
+		if !d.user.Perm.Admin {
+			return http.StatusForbidden, nil
+		}
+		filter.UserID = 0
+	}
+	if state := r.URL.Query().Get("state"); state != "" {
+		filter.States = []jobs.State{jobs.State(state)}
+	}
+
+	list, err := d.store.Jobs.Find(filter)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return renderJSON(w, r, list)
+})
+
+var jobGetHandler = withJob(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	return renderJSON(w, r, d.raw)
+})
+
+// jobDeleteHandler cancels a queued or running job, or removes a
+// finished one from the list.
+func jobDeleteHandler(jobManager *jobs.Manager) handleFunc {
+	return withJob(func(_ http.ResponseWriter, _ *http.Request, d *data) (int, error) {
+		j := d.raw.(*jobs.Job)
+
+		var err error
+		if j.State.Finished() {
+			err = jobManager.Remove(j.ID)
+		} else {
+			err = jobManager.Cancel(j.ID)
+		}
+		if err != nil {
+			return errToStatus(err), err
+		}
+
+		return http.StatusNoContent, nil
+	})
+}
+
+func jobResumeHandler(jobManager *jobs.Manager) handleFunc {
+	return withJob(func(_ http.ResponseWriter, _ *http.Request, d *data) (int, error) {
+		if err := jobManager.Resume(d.raw.(*jobs.Job).ID); err != nil {
+			return errToStatus(err), err
+		}
+
+		return http.StatusNoContent, nil
+	})
+}
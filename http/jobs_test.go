@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestAsyncCopyJob(t *testing.T) {
+	user := &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true, Rename: true, Delete: true},
+	}
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "roms"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "roms", "zelda.sfc"), []byte("zelda"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, user)
+	serve := ts.serve
+
+	jobManager := newJobManager(ts.storage, ts.server, events.NewHub())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := jobManager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/resources/roms?action=copy&destination=%2Fusb%2Froms&async=true", nil)
+	status, body := serve(resourcePatchHandler(nil, nil, jobManager, nil), "/api/resources", req)
+	if status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", status, body)
+	}
+
+	job := &jobs.Job{}
+	if err := json.Unmarshal(body, job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Action != jobs.Copy || job.Src != "/roms" || job.Dst != "/usb/roms" || !job.IsDir {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.State != jobs.Done {
+		if time.Now().After(deadline) {
+			t.Fatalf("job didn't finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		req = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/jobs/1", nil), map[string]string{"id": "1"})
+		status, body = serve(jobGetHandler, "", req)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, body)
+		}
+		if err := json.Unmarshal(body, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if job.FilesDone != 1 || job.BytesDone != 5 {
+		t.Errorf("unexpected progress %+v", job)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "usb", "roms", "zelda.sfc")); string(data) != "zelda" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/jobs?state=done", nil)
+	status, body = serve(jobsGetHandler, "", req)
+	var list []*jobs.Job
+	if err := json.Unmarshal(body, &list); err != nil || status != http.StatusOK || len(list) != 1 {
+		t.Fatalf("unexpected list %d %s", status, body)
+	}
+
+	req = mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/api/jobs/1", nil), map[string]string{"id": "1"})
+	if status, body = serve(jobDeleteHandler(jobManager), "", req); status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", status, body)
+	}
+	if _, err := ts.storage.Jobs.Get(job.ID); err == nil {
+		t.Error("job still exists after removal")
+	}
+}
+
+func TestAuthorizeJob(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "saves", "locked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "saves", "locked", "slot.sav"), []byte("save"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true, Rename: true},
+		Rules: []rules.Rule{
+			{Path: "/saves/locked", Actions: []rules.Action{rules.Delete}},
+			{Path: "/roms", Actions: []rules.Action{rules.Create}},
+		},
+	})
+	user, err := effectiveUser(ts.storage, root, ts.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := ts.storage.Settings.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &data{user: user, settings: set}
+
+	for _, c := range []struct {
+		job  jobs.Job
+		want error
+	}{
+		{jobs.Job{Action: jobs.Copy, Src: "/saves", Dst: "/backup"}, nil},
+		{jobs.Job{Action: jobs.Copy, Src: "/saves", Dst: "/backup", Override: true}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Move, Src: "/saves", Dst: "/backup"}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Delete, Src: "/saves/locked/slot.sav"}, fbErrors.ErrPermissionDenied},
+		// What earlier attempts already moved away isn't checked again.
+		{jobs.Job{Action: jobs.Move, Src: "/gone", Dst: "/backup"}, nil},
+		// Jobs reading files by themselves still need to write where
+		// they put their results.
+		{jobs.Job{Action: jobs.Extract, Src: "/saves.zip", Dst: "/roms/saves"}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Extract, Src: "/saves.zip", Dst: "/saves", Override: true}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Compress, Src: "/", Dst: "/roms/saves.zip"}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Compress, Src: "/", Dst: "/backup/saves.zip"}, nil},
+		{jobs.Job{Action: jobs.Manifest, Src: "/roms", Dst: "/roms/roms.md5"}, fbErrors.ErrPermissionDenied},
+		{jobs.Job{Action: jobs.Verify, Src: "/roms/roms.md5"}, nil},
+	} {
+		if err := authorizeJob(&c.job, d); !errors.Is(err, c.want) {
+			t.Errorf("%s %s: expected %v, got %v", c.job.Action, c.job.Src, c.want, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+	"github.com/filebrowser/filebrowser/v2/manifest"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// manifestPostHandler writes a manifest, in the format given by algo,
+// with the checksums of the files of the directory at the path of the
+// request. It's written to destination, or to the default name of the
+// format in the directory, which must be the directory or one of its
+// parents. With async=true it runs as a background job.
+func manifestPostHandler(hub *events.Hub, jobManager *jobs.Manager, quotas *quota.Tracker) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		if !d.user.Perm.Create || !d.CheckAction(r.URL.Path, rules.Read) {
+			return http.StatusForbidden, nil
+		}
+
+		alg, err := manifest.ParseAlgorithm(r.URL.Query().Get("algo"))
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		dir, err := files.NewFileInfo(&files.FileOptions{
+			Fs:      d.user.Fs,
+			Path:    r.URL.Path,
+			Modify:  d.user.Perm.Modify,
+			Expand:  false,
+			Checker: d,
+		})
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if !dir.IsDir {
+			return http.StatusBadRequest, fmt.Errorf("%s: %w", dir.Path, fbErrors.ErrInvalidRequestParams)
+		}
+		src := path.Clean("/" + dir.Path)
+
+		dst, err := url.QueryUnescape(r.URL.Query().Get("destination"))
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if dst == "" {
+			dst = path.Join(src, alg.DefaultName(src))
+		}
+		dst = path.Clean("/" + dst)
+		if !d.CheckAction(dst, rules.Create) {
+			return http.StatusForbidden, nil
+		}
+		if parent := path.Dir(dst); parent != src && !strings.HasPrefix(src, strings.TrimSuffix(parent, "/")+"/") {
+			return http.StatusBadRequest, fmt.Errorf("%s is not in %s or above it: %w", dst, src, fbErrors.ErrInvalidRequestParams)
+		}
+
+		override := r.URL.Query().Get("override") == "true"
+		var newFiles int64 = 1
+		if _, err := d.user.Fs.Stat(dst); err == nil {
+			if !override {
+				return http.StatusConflict, nil
+			}
+			if !d.user.Perm.Modify || !d.CheckAction(dst, rules.Modify) {
+				return http.StatusForbidden, nil
+			}
+			newFiles = 0
+		}
+
+		// The size of the manifest isn't known before it's written.
+		if _, err := checkQuota(quotas, d, 0, newFiles); err != nil {
+			return errToStatus(err), err
+		}
+
+		if r.URL.Query().Get("async") == "true" {
+			addUsage(quotas, d, 0, newFiles)
+			return enqueueJob(w, d, jobManager, &jobs.Job{
+				Action:    jobs.Manifest,
+				Src:       src,
+				Dst:       dst,
+				IsDir:     true,
+				Override:  override,
+				Algorithm: alg,
+			})
+		}
+
+		var m *manifest.Manifest
+		err = d.RunHook(func() error {
+			var err error
+			m, err = manifest.Generate(r.Context(), d.user.Fs, alg, src, dst, manifest.Options{Checker: rules.ForAction(d, rules.Read)})
+			return err
+		}, "manifest", src, dst, d.user)
+		invalidateUsage(quotas, d)
+		if err != nil {
+			return errToStatus(err), err
+		}
+
+		publishEvent(hub, d, events.Create, dst, "", false)
+		return renderJSON(w, r, m)
+	})
+}
+
+// manifestGetHandler verifies the manifest at the path of the request
+// and answers with the files that are missing or don't match their
+// checksum. With async=true the verification runs as a background job
+// whose report is kept in the job.
+func manifestGetHandler(jobManager *jobs.Manager) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		if !d.CheckAction(r.URL.Path, rules.Read) {
+			return http.StatusForbidden, nil
+		}
+
+		file, err := files.NewFileInfo(&files.FileOptions{
+			Fs:      d.user.Fs,
+			Path:    r.URL.Path,
+			Modify:  d.user.Perm.Modify,
+			Expand:  false,
+			Checker: d,
+		})
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if file.IsDir {
+			return http.StatusBadRequest, fmt.Errorf("%s: %w", file.Path, fbErrors.ErrIsDirectory)
+		}
+		name := path.Clean("/" + file.Path)
+
+		m, err := manifest.Load(d.user.Fs, name)
+		if errors.Is(err, fbErrors.ErrInvalidDataType) {
+			return http.StatusBadRequest, err
+		} else if err != nil {
+			return errToStatus(err), err
+		}
+
+		if r.URL.Query().Get("async") == "true" {
+			return enqueueJob(w, d, jobManager, &jobs.Job{
+				Action:    jobs.Verify,
+				Src:       name,
+				Algorithm: m.Algorithm,
+			})
+		}
+
+		report, err := manifest.Verify(r.Context(), d.user.Fs, m, manifest.Options{Checker: rules.ForAction(d, rules.Read)})
+		if err != nil {
+			return errToStatus(err), err
+		}
+		return renderJSON(w, r, report)
+	})
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/manifest"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestManifestHandlers(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"01.flac": "one", "02.flac": "two"} {
+		if err := os.WriteFile(filepath.Join(root, "album", name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := newTestServer(t, root, &users.User{
+		Username: "listener",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true},
+	})
+	post := manifestPostHandler(events.NewHub(), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/manifest/album?algo=sha256", nil)
+	if status, body := ts.serve(post, "/api/manifest", req); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if _, err := os.Stat(filepath.Join(root, "album", "SHA256SUMS")); err != nil {
+		t.Fatal(err)
+	}
+
+	for url, expected := range map[string]int{
+		"/api/manifest/album?algo=sha256":                     http.StatusConflict,
+		"/api/manifest/album?algo=crc64":                      http.StatusBadRequest,
+		"/api/manifest/album/01.flac?algo=md5":                http.StatusBadRequest,
+		"/api/manifest/?algo=md5&destination=/album/MD5SUMS":  http.StatusBadRequest,
+		"/api/manifest/album?algo=md5&destination=/album.md5": http.StatusOK,
+	} {
+		req = httptest.NewRequest(http.MethodPost, url, nil)
+		if status, body := ts.serve(post, "/api/manifest", req); status != expected {
+			t.Errorf("%s: expected %d, got %d: %s", url, expected, status, body)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "album", "02.flac"), []byte("TWO"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/manifest/album.md5", nil)
+	status, body := ts.serve(manifestGetHandler(nil), "/api/manifest", req)
+	var report manifest.Report
+	if err := json.Unmarshal(body, &report); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected verification %d: %s", status, body)
+	}
+	// The SHA256SUMS written first is listed too.
+	if report.OK != 2 || report.Mismatch != 1 || report.Results[0].Path != "album/02.flac" {
+		t.Errorf("unexpected report %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/manifest/album/01.flac", nil)
+	if status, _ := ts.serve(manifestGetHandler(nil), "/api/manifest", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for a file that isn't a manifest, got %d", status)
+	}
+}
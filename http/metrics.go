@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/metrics"
+)
+
+var (
+	requestsTotal = metrics.Default.NewCounter("filebrowser_http_requests_total",
+		"Number of HTTP requests, by route, method and status code.", "route", "method", "code")
+	requestDuration = metrics.Default.NewHistogram("filebrowser_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route and method.", nil, "route", "method")
+	uploadedBytes = metrics.Default.NewCounter("filebrowser_uploaded_bytes_total",
+		"Number of bytes uploaded through tus.")
+	downloadedBytes = metrics.Default.NewCounter("filebrowser_downloaded_bytes_total",
+		"Number of bytes of raw files downloaded.")
+	previewCache = metrics.Default.NewCounter("filebrowser_preview_cache_requests_total",
+		"Number of preview cache lookups, by preview size and result.", "size", "result")
+)
+
+// instrumentRoute records the requests count and latency of the route matched by mux.
+func instrumentRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		requestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+		requestsTotal.Inc(route, r.Method, strconv.Itoa(rec.Status()))
+	})
+}
+
+// statusRecorder records the status code written to a ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Status returns the status code of the response, or 200 if
+// none was written yet.
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// byteCounter counts the bytes written to a ResponseWriter.
+type byteCounter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (b *byteCounter) Write(p []byte) (int, error) {
+	n, err := b.ResponseWriter.Write(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// NewMetricsHandler returns a handler serving the metrics without
+// authentication, to be used on a separate listen address.
+func NewMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		_ = metrics.Default.WriteText(w)
+	})
+}
+
+var metricsAdminHandler = withAdmin(func(w http.ResponseWriter, _ *http.Request, _ *data) (int, error) {
+	return writeMetrics(w)
+})
+
+// metricsHandler serves the metrics to admins and, if one is
+// configured, to requests bearing the metrics token.
+var metricsHandler = func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if token := d.server.MetricsToken; token != "" {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+			return writeMetrics(w)
+		}
+	}
+
+	return metricsAdminHandler(w, r, d)
+}
+
+func writeMetrics(w http.ResponseWriter) (int, error) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := metrics.Default.WriteText(w); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return 0, nil
+}
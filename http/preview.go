@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 
 	"github.com/filebrowser/filebrowser/v2/files"
 	"github.com/filebrowser/filebrowser/v2/img"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/virtual"
 )
 
 /*
@@ -45,6 +48,15 @@ func previewHandler(imgSvc ImgService, fileCache FileCache, enableThumbnails, re
 		if err != nil {
 			return http.StatusBadRequest, err
 		}
+		// Virtual files are served by their providers rather than the
+		// filesystem, as they are by the virtual resource routes.
+		if p := "/" + vars["path"]; p == virtual.Root || strings.HasPrefix(p, virtual.Root+"/") {
+			p = strings.TrimPrefix(p, virtual.Root)
+			return previewVirtualFile(w, r, d, imgSvc, p, previewSize, enableThumbnails, resizePreview)
+		}
+		if !d.CheckAction("/"+vars["path"], rules.Read) {
+			return http.StatusForbidden, nil
+		}
 
 		file, err := files.NewFileInfo(&files.FileOptions{
 			Fs:         d.user.Fs,
@@ -58,6 +70,10 @@ func previewHandler(imgSvc ImgService, fileCache FileCache, enableThumbnails, re
 			return errToStatus(err), err
 		}
 
+		if entry := r.URL.Query().Get("archive"); entry != "" {
+			return handleArchivePreview(w, r, imgSvc, fileCache, file, entry, previewSize, enableThumbnails, resizePreview)
+		}
+
 		setContentDisposition(w, r, file)
 
 		switch file.Type {
@@ -97,7 +113,10 @@ func handleImagePreview(
 	if err != nil {
 		return errToStatus(err), err
 	}
-	if !ok {
+	if ok {
+		previewCache.Inc(previewSize.String(), "hit")
+	} else {
+		previewCache.Inc(previewSize.String(), "miss")
 		resizedImage, err = createPreview(imgSvc, fileCache, file, previewSize)
 		if err != nil {
 			return errToStatus(err), err
@@ -118,6 +137,24 @@ func createPreview(imgSvc ImgService, fileCache FileCache,
 	}
 	defer fd.Close()
 
+	resizedImage, err := resizeImage(imgSvc, fd, previewSize)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		cacheKey := previewCacheKey(file, previewSize)
+		if err := fileCache.Store(context.Background(), cacheKey, resizedImage); err != nil {
+			fmt.Printf("failed to cache resized image: %v", err)
+		}
+	}()
+
+	return resizedImage, nil
+}
+
+// resizeImage turns the image read from in into a preview of the
+// given size.
+func resizeImage(imgSvc ImgService, in io.Reader, previewSize PreviewSize) ([]byte, error) {
 	var (
 		width   int
 		height  int
@@ -138,17 +175,10 @@ func createPreview(imgSvc ImgService, fileCache FileCache,
 	}
 
 	buf := &bytes.Buffer{}
-	if err := imgSvc.Resize(context.Background(), fd, width, height, buf, options...); err != nil {
+	if err := imgSvc.Resize(context.Background(), in, width, height, buf, options...); err != nil {
 		return nil, err
 	}
 
-	go func() {
-		cacheKey := previewCacheKey(file, previewSize)
-		if err := fileCache.Store(context.Background(), cacheKey, buf.Bytes()); err != nil {
-			fmt.Printf("failed to cache resized image: %v", err)
-		}
-	}()
-
 	return buf.Bytes(), nil
 }
 
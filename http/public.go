@@ -28,7 +28,7 @@ var withHashFile = func(fn handleFunc) handleFunc {
 			return status, err
 		}
 
-		user, err := d.store.Users.Get(d.server.Root, link.UserID)
+		user, err := effectiveUser(d.store, d.server.Root, link.UserID)
 		if err != nil {
 			return errToStatus(err), err
 		}
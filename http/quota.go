@@ -0,0 +1,213 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/quota"
+)
+
+// quotaRoom returns how many more bytes and files the user can store
+// in its scope, or -1 for either if it's not limited.
+func quotaRoom(quotas *quota.Tracker, d *data) (bytes, files int64, err error) {
+	q := d.user.Quota
+	root, ok := scopeRoot(d)
+	if quotas == nil || q.Unlimited() || !ok {
+		return -1, -1, nil
+	}
+
+	usage, err := quotas.Usage(root)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	bytes, files = -1, -1
+	if q.Bytes > 0 {
+		bytes = max(q.Bytes-usage.Bytes, 0)
+	}
+	if q.Files > 0 {
+		files = max(q.Files-usage.Files, 0)
+	}
+	return bytes, files, nil
+}
+
+// checkQuota checks that bytes and files more fit in the quota of the
+// user, failing with ErrQuotaExceeded otherwise. It returns how many
+// more bytes can be stored, or -1 if it's not limited.
+func checkQuota(quotas *quota.Tracker, d *data, bytes, files int64) (int64, error) {
+	roomBytes, roomFiles, err := quotaRoom(quotas, d)
+	switch {
+	case err != nil:
+		return 0, err
+	case roomBytes >= 0 && bytes > roomBytes:
+		return 0, fmt.Errorf("%d bytes don't fit in the %d bytes left: %w", bytes, roomBytes, fbErrors.ErrQuotaExceeded)
+	case roomFiles >= 0 && files > roomFiles:
+		return 0, fmt.Errorf("%d files don't fit in the %d files left: %w", files, roomFiles, fbErrors.ErrQuotaExceeded)
+	}
+	return roomBytes, nil
+}
+
+// addUsage accounts for what the user wrote in its scope.
+func addUsage(quotas *quota.Tracker, d *data, bytes, files int64) {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		quotas.Add(root, bytes, files)
+	}
+}
+
+// invalidateUsage makes the usage of the scope of the user be walked
+// again, after writes that weren't measured.
+func invalidateUsage(quotas *quota.Tracker, d *data) {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		quotas.Invalidate(root)
+	}
+}
+
+// reserveUpload reserves bytes for the upload at name, which are then
+// written in parts, so that concurrent uploads can't each fit in what's
+// left.
+func reserveUpload(quotas *quota.Tracker, d *data, name string, bytes int64) {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		quotas.Reserve(root, d.user.FullPath(name), bytes)
+	}
+}
+
+// uploadReserved returns how many bytes are still reserved for the
+// upload at name.
+func uploadReserved(quotas *quota.Tracker, d *data, name string) int64 {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		return quotas.Reserved(root, d.user.FullPath(name))
+	}
+	return 0
+}
+
+// useUpload accounts for bytes written to the upload at name, out of
+// what was reserved for it first.
+func useUpload(quotas *quota.Tracker, d *data, name string, bytes int64) {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		quotas.Use(root, d.user.FullPath(name), bytes)
+	}
+}
+
+// releaseUpload gives back what's still reserved for the upload at
+// name.
+func releaseUpload(quotas *quota.Tracker, d *data, name string) {
+	if root, ok := scopeRoot(d); ok && quotas != nil {
+		quotas.Release(root, d.user.FullPath(name))
+	}
+}
+
+// quotaReader fails with ErrQuotaExceeded once more than n bytes are
+// read from r, for bodies without a length. It reads everything if n
+// is negative.
+func quotaReader(r io.Reader, n int64) io.Reader {
+	if n < 0 {
+		return r
+	}
+	return &limitedReader{r: r, n: n}
+}
+
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n = int(l.n)
+		l.n = 0
+		return n, fmt.Errorf("the body doesn't fit in the quota: %w", fbErrors.ErrQuotaExceeded)
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+// reservePatch checks that what a copy or an extraction writes to dst
+// fits in the quota of the user, and accounts for it right away so
+// that it's not counted out when it runs in the background.
+func reservePatch(quotas *quota.Tracker, d *data, action, src, dst string, override bool) error {
+	// Measuring archives reads them whole.
+	if quotas == nil || d.user.Quota.Unlimited() {
+		return nil
+	}
+
+	var bytes, files int64
+	switch action {
+	case "copy":
+		var err error
+		bytes, files, err = measure(d.user.Fs, src)
+		if err != nil {
+			return err
+		}
+		if override {
+			oldBytes, oldFiles, err := measure(d.user.Fs, dst)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			bytes, files = bytes-oldBytes, files-oldFiles
+		}
+	case "extract":
+		count, size, err := archive.Measure(d.user.Fs, src)
+		if err != nil {
+			return err
+		}
+		bytes, files = size, int64(count)
+	default:
+		return nil
+	}
+
+	if _, err := checkQuota(quotas, d, bytes, files); err != nil {
+		return err
+	}
+	addUsage(quotas, d, bytes, files)
+	return nil
+}
+
+// reserveArchive checks that an archive of sources fits in the quota
+// of the user, and accounts for it. Its size isn't known before it's
+// created, so it's taken as the size of the sources, as if they were
+// stored without compression.
+func reserveArchive(quotas *quota.Tracker, d *data, sources []string) error {
+	if quotas == nil || d.user.Quota.Unlimited() {
+		return nil
+	}
+
+	var bytes int64
+	for _, source := range sources {
+		size, _, err := measure(d.user.Fs, source)
+		if err != nil {
+			return err
+		}
+		bytes += size
+	}
+
+	if _, err := checkQuota(quotas, d, bytes, 1); err != nil {
+		return err
+	}
+	addUsage(quotas, d, bytes, 1)
+	return nil
+}
+
+// measure returns the size and number of the regular files at name,
+// and in it if it's a directory.
+func measure(fs afero.Fs, name string) (bytes, files int64, err error) {
+	err = afero.Walk(fs, name, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			bytes += info.Size()
+			files++
+		}
+		return nil
+	})
+	return bytes, files, err
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/diskcache"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestQuota(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "saves"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "saves/slot1.sav"), []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true, Modify: true, Download: true},
+		Quota:    users.Quota{Bytes: 25, Files: 3},
+	})
+
+	cache, hub, quotas := diskcache.NewNoOp(), events.NewHub(), quota.NewTracker()
+	for _, c := range []struct {
+		method, url, body string
+		headers           map[string]string
+		handler           handleFunc
+		prefix            string
+		want              int
+	}{
+		{http.MethodPost, "/api/resources/saves/slot2.sav", "0123456789", nil, resourcePostHandler(cache, hub, quotas), "/api/resources", http.StatusOK},
+		{http.MethodPost, "/api/resources/saves/slot3.sav", "0123456789", nil, resourcePostHandler(cache, hub, quotas), "/api/resources", http.StatusInsufficientStorage},
+		// Overriding a file only needs room for the difference.
+		{http.MethodPost, "/api/resources/saves/slot1.sav?override=true", "012345678901234", nil, resourcePostHandler(cache, hub, quotas), "/api/resources", http.StatusOK},
+		{http.MethodPut, "/api/resources/saves/slot1.sav", "0123456789012345", nil, resourcePutHandler(hub, quotas), "/api/resources", http.StatusInsufficientStorage},
+		{http.MethodPost, "/api/tus/saves/slot3.sav", "", map[string]string{"Upload-Length": "1"}, tusPostHandler(hub, quotas), "/api/tus", http.StatusInsufficientStorage},
+		{http.MethodPatch, "/api/resources/saves/slot2.sav?action=copy&destination=/slot2.sav", "", nil, resourcePatchHandler(cache, hub, nil, quotas), "/api/resources", http.StatusInsufficientStorage},
+		{http.MethodPost, "/api/tus/saves/empty.sav", "", map[string]string{"Upload-Length": "0"}, tusPostHandler(hub, quotas), "/api/tus", http.StatusCreated},
+		// The third file is full.
+		{http.MethodPost, "/api/resources/saves/", "", nil, resourcePostHandler(cache, hub, quotas), "/api/resources", http.StatusOK},
+		{http.MethodPost, "/api/resources/saves/slot4.sav", "", nil, resourcePostHandler(cache, hub, quotas), "/api/resources", http.StatusInsufficientStorage},
+	} {
+		req := httptest.NewRequest(c.method, c.url, strings.NewReader(c.body))
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		status, body := ts.serve(c.handler, c.prefix, req)
+		if status != c.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", c.method, c.url, c.want, status, body)
+		}
+		if status == http.StatusInsufficientStorage && !strings.Contains(string(body), "left") {
+			t.Errorf("%s %s: expected the error to tell what's left, got %s", c.method, c.url, body)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "slot2.sav")); !os.IsNotExist(err) {
+		t.Errorf("expected the copy over quota not to be written, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/usage/", nil)
+	status, body := ts.serve(diskUsage(quotas), "/api/usage", req)
+	var usage DiskUsageResponse
+	if err := json.Unmarshal(body, &usage); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	want := QuotaUsage{Limit: users.Quota{Bytes: 25, Files: 3}, Used: quota.Usage{Bytes: 25, Files: 3}}
+	if usage.Quota == nil || *usage.Quota != want {
+		t.Errorf("expected quota usage %+v, got %s", want, body)
+	}
+}
+
+func TestQuotaUploads(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "saves"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true, Modify: true},
+		Quota:    users.Quota{Bytes: 10, Files: 2},
+	})
+
+	hub, quotas := events.NewHub(), quota.NewTracker()
+	tusPatch := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	for _, c := range []struct {
+		method, url, body string
+		headers           map[string]string
+		handler           handleFunc
+		prefix            string
+		want              int
+	}{
+		{http.MethodPost, "/api/tus/saves/a.sav", "", map[string]string{"Upload-Length": "6"}, tusPostHandler(hub, quotas), "/api/tus", http.StatusCreated},
+		// The first upload is reserved before any of it is written.
+		{http.MethodPost, "/api/tus/saves/b.sav", "", map[string]string{"Upload-Length": "6"}, tusPostHandler(hub, quotas), "/api/tus", http.StatusInsufficientStorage},
+		{http.MethodPatch, "/api/tus/saves/a.sav", "012345", tusPatch, tusPatchHandler(hub, quotas), "/api/tus", http.StatusNoContent},
+		{http.MethodPost, "/api/manifest/saves/?algo=md5", "", nil, manifestPostHandler(hub, nil, quotas), "/api/manifest", http.StatusOK},
+		{http.MethodPost, "/api/manifest/saves/?algo=md5&destination=/saves/other.md5", "", nil, manifestPostHandler(hub, nil, quotas), "/api/manifest", http.StatusInsufficientStorage},
+	} {
+		req := httptest.NewRequest(c.method, c.url, strings.NewReader(c.body))
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		if status, body := ts.serve(c.handler, c.prefix, req); status != c.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", c.method, c.url, c.want, status, body)
+		}
+	}
+
+	if reserved := quotas.Reserved(root, filepath.Join(root, "saves/a.sav")); reserved != 0 {
+		t.Errorf("expected nothing left reserved once the upload is done, got %d", reserved)
+	}
+}
+
+func TestQuotaReader(t *testing.T) {
+	buf := make([]byte, 4)
+	r := quotaReader(strings.NewReader("0123456789"), 6)
+
+	n, err := r.Read(buf)
+	if n != 4 || err != nil {
+		t.Fatalf("expected 4 bytes, got %d: %v", n, err)
+	}
+	n, err = r.Read(buf)
+	if n != 2 || err == nil {
+		t.Fatalf("expected 2 bytes and an error, got %d: %v", n, err)
+	}
+}
@@ -11,8 +11,10 @@ import (
 
 	"github.com/mholt/archiver/v3"
 
+	"github.com/filebrowser/filebrowser/v2/archive"
 	"github.com/filebrowser/filebrowser/v2/files"
 	"github.com/filebrowser/filebrowser/v2/fileutils"
+	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
@@ -44,34 +46,47 @@ func parseQueryFiles(r *http.Request, f *files.FileInfo, _ *users.User) ([]strin
 	return fileSlice, nil
 }
 
+// parseQueryFormat returns the archive format asked for by the algo
+// query parameter. "zipstore" is a zip archive whose files are
+// stored without compression.
+//
 //nolint:goconst
-func parseQueryAlgorithm(r *http.Request) (string, archiver.Writer, error) {
-	switch r.URL.Query().Get("algo") {
+func parseQueryFormat(r *http.Request) (format archive.Format, store bool, err error) {
+	switch algo := r.URL.Query().Get("algo"); algo {
 	case "zip", "true", "":
-		return ".zip", archiver.NewZip(), nil
-	case "tar":
-		return ".tar", archiver.NewTar(), nil
-	case "targz":
-		return ".tar.gz", archiver.NewTarGz(), nil
-	case "tarbz2":
-		return ".tar.bz2", archiver.NewTarBz2(), nil
-	case "tarxz":
-		return ".tar.xz", archiver.NewTarXz(), nil
-	case "tarlz4":
-		return ".tar.lz4", archiver.NewTarLz4(), nil
-	case "tarsz":
-		return ".tar.sz", archiver.NewTarSz(), nil
+		return archive.Zip, false, nil
+	case "zipstore":
+		return archive.Zip, true, nil
+	case "tar", "targz", "tarbz2", "tarxz", "tarlz4", "tarsz":
+		return archive.Format(algo), false, nil
 	default:
-		return "", nil, errors.New("format not implemented")
+		return "", false, errors.New("format not implemented")
 	}
 }
 
+func parseQueryAlgorithm(r *http.Request) (string, archiver.Writer, error) {
+	format, store, err := parseQueryFormat(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ar, err := archive.NewWriter(format, store, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return format.Extension(), ar, nil
+}
+
 func setContentDisposition(w http.ResponseWriter, r *http.Request, file *files.FileInfo) {
+	setContentDispositionName(w, r, file.Name)
+}
+
+func setContentDispositionName(w http.ResponseWriter, r *http.Request, name string) {
 	if r.URL.Query().Get("inline") == "true" {
 		w.Header().Set("Content-Disposition", "inline")
 	} else {
 		// As per RFC6266 section 4.3
-		w.Header().Set("Content-Disposition", "attachment; filename*=utf-8''"+url.PathEscape(file.Name))
+		w.Header().Set("Content-Disposition", "attachment; filename*=utf-8''"+url.PathEscape(name))
 	}
 }
 
@@ -79,6 +94,9 @@ var rawHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data)
 	if !d.user.Perm.Download {
 		return http.StatusAccepted, nil
 	}
+	if !d.CheckAction(r.URL.Path, rules.Read) {
+		return http.StatusForbidden, nil
+	}
 
 	file, err := files.NewFileInfo(&files.FileOptions{
 		Fs:         d.user.Fs,
@@ -97,6 +115,10 @@ var rawHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data)
 		return 0, nil
 	}
 
+	if !file.IsDir && r.URL.Query().Has("archive") {
+		return rawArchiveEntryHandler(w, r, file, r.URL.Query().Get("archive"))
+	}
+
 	if !file.IsDir {
 		return rawFileHandler(w, r, file)
 	}
@@ -105,7 +127,7 @@ var rawHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data)
 })
 
 func addFile(ar archiver.Writer, d *data, path, commonPath string) error {
-	if !d.Check(path) {
+	if !d.CheckAction(path, rules.Read) {
 		return nil
 	}
 
@@ -208,6 +230,9 @@ func rawFileHandler(w http.ResponseWriter, r *http.Request, file *files.FileInfo
 	setContentDisposition(w, r, file)
 	w.Header().Add("Content-Security-Policy", `script-src 'none';`)
 	w.Header().Set("Cache-Control", "private")
-	http.ServeContent(w, r, file.Name, file.ModTime, fd)
+
+	counter := &byteCounter{ResponseWriter: w}
+	http.ServeContent(counter, r, file.Name, file.ModTime, fd)
+	downloadedBytes.Add(float64(counter.n))
 	return 0, nil
 }
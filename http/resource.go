@@ -5,185 +5,45 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/spf13/afero"
 
+	"github.com/filebrowser/filebrowser/v2/archive"
 	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
 	"github.com/filebrowser/filebrowser/v2/files"
 	"github.com/filebrowser/filebrowser/v2/fileutils"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
 )
 
-var logFilesVirtualPath = "/virtual/logs"
-var dirSize = 3488
-
-var logFiles = []string{
-	"/rcade/share/.emulationstation/es_log.txt",
-	"/rcade/share/.emulationstation/es_log.txt.bak",
-	"/rcade/share/.emulationstation/upgrade.log",
-	"/tmp/last_game_launch.log",
-	"/tmp/rcade-usbmount.log",
-	"/var/log/messages",
-}
-
-// getLogFile returns a FileInfo for a log file
-func getLogFile(path string) (*files.FileInfo, error) {
-	logFileName := filepath.Base(path)
-	for _, logFile := range logFiles {
-		if filepath.Base(logFile) == logFileName {
-			logFileStat, err := os.Stat(logFile)
-			if err != nil {
-				if os.IsNotExist(err) {
-					return &files.FileInfo{}, nil
-				}
-
-				return &files.FileInfo{}, err
-			}
-			content, err := os.ReadFile(logFile)
-			if err != nil {
-				return &files.FileInfo{}, err
-			}
-			return &files.FileInfo{
-				Path:      filepath.Join("/virtual", path),
-				Name:      logFileName,
-				Size:      logFileStat.Size(),
-				Extension: filepath.Ext(logFileName),
-				ModTime:   logFileStat.ModTime(),
-				Mode:      fs.FileMode(0444),
-				IsDir:     false,
-				IsSymlink: false,
-				Type:      "text",
-				Content:   string(content),
-			}, nil
-		}
-	}
-	return &files.FileInfo{}, nil
-}
-
-// getLogFiles returns a Listing of log files
-func getLogFiles() (*files.FileInfo, error) {
-	var items []*files.FileInfo
-	for _, path := range logFiles {
-		logFileName := filepath.Base(path)
-		virtPath := filepath.Join(logFilesVirtualPath, logFileName)
-		logFileStat, err := os.Stat(path)
-		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
-
-			return &files.FileInfo{}, err
-		}
-		items = append(items, &files.FileInfo{
-			Path:      virtPath,
-			Name:      logFileName,
-			Type:      "text",
-			IsDir:     false,
-			IsSymlink: false,
-			Mode:      fs.FileMode(0444),
-			Size:      logFileStat.Size(),
-			ModTime:   logFileStat.ModTime(),
-			Extension: filepath.Ext(logFileName),
-		})
-	}
-	i := files.FileInfo{
-		Listing: &files.Listing{
-			Items:    items,
-			NumDirs:  0,
-			NumFiles: len(items),
-			Sorting:  files.Sorting{By: "name", Asc: true},
-		},
-		Path:      logFilesVirtualPath,
-		Name:      "logs",
-		IsDir:     true,
-		IsSymlink: false,
-		Extension: "",
-	}
-
-	return &i, nil
-}
-
-func getVirtualRoot() (*files.FileInfo, error) {
-	var items []*files.FileInfo
-
-	currentTime := time.Now()
-	modTime := currentTime.Add(-5 * time.Second)
-
-	items = append(items, &files.FileInfo{
-		Path:    "/virtual/logs",
-		Name:    "logs",
-		IsDir:   true,
-		Mode:    fs.FileMode(0555) | fs.ModeDir,
-		Size:    int64(dirSize),
-		ModTime: modTime,
-	})
-	i := files.FileInfo{
-		Listing: &files.Listing{
-			Items:    items,
-			NumDirs:  len(items),
-			NumFiles: 0,
-			Sorting:  files.Sorting{By: "name", Asc: true},
-		},
-		Path:    "/virtual",
-		Name:    "virtual",
-		Size:    int64(dirSize),
-		Mode:    fs.FileMode(0555) | fs.ModeDir,
-		ModTime: modTime,
-		IsDir:   true,
-	}
-
-	return &i, nil
-}
-
-var resourceVirtualGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	path := strings.TrimSuffix(r.URL.Path, "/")
+var resourceGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	// Directories that can't be read are shown empty, so that files
+	// can still be uploaded to them.
+	canRead := d.CheckAction(r.URL.Path, rules.Read)
 
-	switch path {
-	case "":
-		return virtualRootHandler(w, r, d)
-	case "/logs":
-		return logFilesHandler(w, r, d)
-	default:
-		if strings.HasPrefix(path, "/logs/") {
-			return logFileHandler(w, r, d)
+	if r.URL.Query().Has("archive") {
+		if !canRead {
+			return http.StatusForbidden, nil
 		}
-		return http.StatusNotFound, nil
-	}
-})
-
-var virtualRootHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	file, err := getVirtualRoot()
-	if err != nil {
-		return errToStatus(err), err
+		return archiveGetHandler(w, r, d)
 	}
-	return renderJSON(w, r, file)
-})
 
-var logFileHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	file, err := getLogFile(r.URL.Path)
+	rng, err := parseContentRange(r)
 	if err != nil {
-		return errToStatus(err), err
-	}
-	return renderJSON(w, r, file)
-})
-
-var logFilesHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	file, err := getLogFiles()
-	if err != nil {
-		return errToStatus(err), err
+		return http.StatusBadRequest, err
 	}
-	return renderJSON(w, r, file)
-})
 
-var resourceGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
 	file, err := files.NewFileInfo(&files.FileOptions{
 		Fs:         d.user.Fs,
 		Path:       r.URL.Path,
@@ -191,18 +51,27 @@ var resourceGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d
 		Expand:     true,
 		ReadHeader: d.server.TypeDetectionByHeader,
 		Checker:    d,
-		Content:    true,
+		Content:    canRead,
+		Range:      rng,
 	})
 	if err != nil {
 		return errToStatus(err), err
 	}
 
 	if file.IsDir {
+		if !canRead {
+			file.Listing.Items = []*files.FileInfo{}
+			file.Listing.NumDirs, file.Listing.NumFiles = 0, 0
+		}
 		file.Listing.Sorting = d.user.Sorting
 		file.Listing.ApplySort()
 		return renderJSON(w, r, file)
 	}
 
+	if !canRead {
+		return http.StatusForbidden, nil
+	}
+
 	if checksum := r.URL.Query().Get("checksum"); checksum != "" {
 		err := file.Checksum(checksum)
 		if errors.Is(err, fbErrors.ErrInvalidOption) {
@@ -213,16 +82,64 @@ var resourceGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d
 
 		// do not waste bandwidth if we just want the checksum
 		file.Content = ""
+		file.Window = nil
 	}
 
 	return renderJSON(w, r, file)
 })
 
-func resourceDeleteHandler(fileCache FileCache) handleFunc {
-	return withUser(func(_ http.ResponseWriter, r *http.Request, d *data) (int, error) {
+// parseContentRange parses the offset, limit and unit (bytes or lines)
+// query parameters used to page through big files. It returns a nil
+// range when none of them is set.
+func parseContentRange(r *http.Request) (*files.Range, error) {
+	query := r.URL.Query()
+	offset, limit, unit := query.Get("offset"), query.Get("limit"), query.Get("unit")
+	if offset == "" && limit == "" && unit == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	rng := &files.Range{}
+	switch unit {
+	case "", "bytes":
+	case "lines":
+		rng.Lines = true
+	default:
+		return nil, fmt.Errorf("invalid unit %q: %w", unit, fbErrors.ErrInvalidRequestParams)
+	}
+
+	var err error
+	if offset != "" {
+		if rng.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil || rng.Offset < 0 {
+			return nil, fmt.Errorf("invalid offset %q: %w", offset, fbErrors.ErrInvalidRequestParams)
+		}
+	}
+
+	if limit != "" {
+		if rng.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || rng.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit %q: %w", limit, fbErrors.ErrInvalidRequestParams)
+		}
+	}
+
+	return rng, nil
+}
+
+// resourceDeleteHandler deletes files and directories. Unless
+// permanent is set, or the permanent query parameter is true, they're
+// moved into the trash of the user, from where they can be restored.
+// Files on other devices than the trash fail with 422 instead, so that
+// clients ask before deleting them permanently. With async=true the
+// deletion runs as a background job.
+func resourceDeleteHandler(fileCache FileCache, hub *events.Hub, jobManager *jobs.Manager, permanent bool) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		permanent := permanent || r.URL.Query().Get("permanent") == "true"
 		if r.URL.Path == "/" || !d.user.Perm.Delete {
 			return http.StatusForbidden, nil
 		}
+		if ok, err := d.checkTree(r.URL.Path, rules.Delete); err != nil {
+			return errToStatus(err), err
+		} else if !ok {
+			return http.StatusForbidden, nil
+		}
 
 		file, err := files.NewFileInfo(&files.FileOptions{
 			Fs:         d.user.Fs,
@@ -242,19 +159,36 @@ func resourceDeleteHandler(fileCache FileCache) handleFunc {
 			return errToStatus(err), err
 		}
 
+		if !permanent && r.URL.Query().Get("async") == "true" {
+			return enqueueJob(w, d, jobManager, &jobs.Job{
+				Action: jobs.Delete,
+				Src:    r.URL.Path,
+				IsDir:  file.IsDir,
+			})
+		}
+
 		err = d.RunHook(func() error {
-			return d.user.Fs.RemoveAll(r.URL.Path)
+			if permanent {
+				return d.user.Fs.RemoveAll(r.URL.Path)
+			}
+			_, err := trashBin(d).Move(r.URL.Path)
+			return err
 		}, "delete", r.URL.Path, "", d.user)
 
 		if err != nil {
 			return errToStatus(err), err
 		}
 
+		if !permanent {
+			pruneTrash(trashBin(d), d.settings)
+		}
+
+		publishEvent(hub, d, events.Delete, r.URL.Path, "", file.IsDir)
 		return http.StatusNoContent, nil
 	})
 }
 
-func resourcePostHandler(fileCache FileCache) handleFunc {
+func resourcePostHandler(fileCache FileCache, hub *events.Hub, quotas *quota.Tracker) handleFunc {
 	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
 		if !d.user.Perm.Create || !d.Check(r.URL.Path) {
 			return http.StatusForbidden, nil
@@ -262,7 +196,13 @@ func resourcePostHandler(fileCache FileCache) handleFunc {
 
 		// Directories creation on POST.
 		if strings.HasSuffix(r.URL.Path, "/") {
+			if !d.CheckAction(r.URL.Path, rules.Create) {
+				return http.StatusForbidden, nil
+			}
 			err := d.user.Fs.MkdirAll(r.URL.Path, files.PermDir)
+			if err == nil {
+				publishEvent(hub, d, events.Create, r.URL.Path, "", true)
+			}
 			return errToStatus(err), err
 		}
 
@@ -274,13 +214,20 @@ func resourcePostHandler(fileCache FileCache) handleFunc {
 			ReadHeader: d.server.TypeDetectionByHeader,
 			Checker:    d,
 		})
+		evt := events.Create
+		var oldSize, newFiles int64 = 0, 1
+		if err != nil && !d.CheckAction(r.URL.Path, rules.Create) {
+			return http.StatusForbidden, nil
+		}
 		if err == nil {
+			evt = events.Modify
+			oldSize, newFiles = file.Size, 0
 			if r.URL.Query().Get("override") != "true" {
 				return http.StatusConflict, nil
 			}
 
 			// Permission for overwriting the file
-			if !d.user.Perm.Modify {
+			if !d.user.Perm.Modify || !d.CheckAction(r.URL.Path, rules.Modify) {
 				return http.StatusForbidden, nil
 			}
 
@@ -290,12 +237,21 @@ func resourcePostHandler(fileCache FileCache) handleFunc {
 			}
 		}
 
+		room, err := checkQuota(quotas, d, max(r.ContentLength, 0)-oldSize, newFiles)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if room >= 0 {
+			room += oldSize
+		}
+
 		err = d.RunHook(func() error {
-			info, writeErr := writeFile(d.user.Fs, r.URL.Path, r.Body)
+			info, writeErr := writeFile(d.user.Fs, r.URL.Path, quotaReader(r.Body, room))
 			if writeErr != nil {
 				return writeErr
 			}
 
+			addUsage(quotas, d, info.Size()-oldSize, newFiles)
 			etag := fmt.Sprintf(`"%x%x"`, info.ModTime().UnixNano(), info.Size())
 			w.Header().Set("ETag", etag)
 			return nil
@@ -303,46 +259,65 @@ func resourcePostHandler(fileCache FileCache) handleFunc {
 
 		if err != nil {
 			_ = d.user.Fs.RemoveAll(r.URL.Path)
+			invalidateUsage(quotas, d)
+		} else {
+			publishEvent(hub, d, evt, r.URL.Path, "", false)
 		}
 
 		return errToStatus(err), err
 	})
 }
 
-var resourcePutHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	if !d.user.Perm.Modify || !d.Check(r.URL.Path) {
-		return http.StatusForbidden, nil
-	}
+func resourcePutHandler(hub *events.Hub, quotas *quota.Tracker) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		if !d.user.Perm.Modify || !d.CheckAction(r.URL.Path, rules.Modify) {
+			return http.StatusForbidden, nil
+		}
 
-	// Only allow PUT for files.
-	if strings.HasSuffix(r.URL.Path, "/") {
-		return http.StatusMethodNotAllowed, nil
-	}
+		// Only allow PUT for files.
+		if strings.HasSuffix(r.URL.Path, "/") {
+			return http.StatusMethodNotAllowed, nil
+		}
 
-	exists, err := afero.Exists(d.user.Fs, r.URL.Path)
-	if err != nil {
-		return http.StatusInternalServerError, err
-	}
-	if !exists {
-		return http.StatusNotFound, nil
-	}
+		old, err := d.user.Fs.Stat(r.URL.Path)
+		if os.IsNotExist(err) {
+			return http.StatusNotFound, nil
+		} else if err != nil {
+			return http.StatusInternalServerError, err
+		}
 
-	err = d.RunHook(func() error {
-		info, writeErr := writeFile(d.user.Fs, r.URL.Path, r.Body)
-		if writeErr != nil {
-			return writeErr
+		room, err := checkQuota(quotas, d, max(r.ContentLength, 0)-old.Size(), 0)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if room >= 0 {
+			room += old.Size()
 		}
 
-		etag := fmt.Sprintf(`"%x%x"`, info.ModTime().UnixNano(), info.Size())
-		w.Header().Set("ETag", etag)
-		return nil
-	}, "save", r.URL.Path, "", d.user)
+		err = d.RunHook(func() error {
+			info, writeErr := writeFile(d.user.Fs, r.URL.Path, quotaReader(r.Body, room))
+			if writeErr != nil {
+				return writeErr
+			}
 
-	return errToStatus(err), err
-})
+			addUsage(quotas, d, info.Size()-old.Size(), 0)
+			etag := fmt.Sprintf(`"%x%x"`, info.ModTime().UnixNano(), info.Size())
+			w.Header().Set("ETag", etag)
+			return nil
+		}, "save", r.URL.Path, "", d.user)
+
+		if err == nil {
+			publishEvent(hub, d, events.Modify, r.URL.Path, "", false)
+		} else {
+			invalidateUsage(quotas, d)
+		}
 
-func resourcePatchHandler(fileCache FileCache) handleFunc {
-	return withUser(func(_ http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		return errToStatus(err), err
+	})
+}
+
+func resourcePatchHandler(fileCache FileCache, hub *events.Hub, jobManager *jobs.Manager, quotas *quota.Tracker) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
 		src := r.URL.Path
 		dst := r.URL.Query().Get("destination")
 		action := r.URL.Query().Get("action")
@@ -357,8 +332,19 @@ func resourcePatchHandler(fileCache FileCache) handleFunc {
 			return http.StatusForbidden, nil
 		}
 
-		err = checkParent(src, dst)
-		if err != nil {
+		// Archives are usually created next to the files they hold, so
+		// compressions don't go through checkParent.
+		var compress *archive.CreateOptions
+		if action == "compress" {
+			compress, err = parseCompressOptions(r)
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			dst = withArchiveExtension(dst, compress.Format)
+			if !d.Check(dst) {
+				return http.StatusForbidden, nil
+			}
+		} else if err = checkParent(src, dst); err != nil {
 			return http.StatusBadRequest, err
 		}
 
@@ -378,14 +364,72 @@ func resourcePatchHandler(fileCache FileCache) handleFunc {
 			return http.StatusForbidden, nil
 		}
 
+		if status, err := checkPatchRules(action, src, dst, override, d); status != 0 || err != nil {
+			return status, err
+		}
+
+		if compress != nil {
+			return compressFiles(w, r, src, dst, override, compress, d, hub, jobManager, quotas)
+		}
+
+		if err = reservePatch(quotas, d, action, src, dst, override); err != nil {
+			return errToStatus(err), err
+		}
+
+		if r.URL.Query().Get("async") == "true" {
+			return enqueuePatchJob(w, r, action, src, dst, override, d, fileCache, jobManager)
+		}
+
 		err = d.RunHook(func() error {
-			return patchAction(r.Context(), action, src, dst, d, fileCache)
+			return patchAction(r.Context(), action, src, dst, override, d, fileCache, hub)
 		}, action, src, dst, d.user)
+		if err != nil {
+			invalidateUsage(quotas, d)
+		}
 
 		return errToStatus(err), err
 	})
 }
 
+// checkPatchRules checks that the rules allow the action: reading
+// what's copied, extracted or compressed, deleting what's moved away,
+// creating the destination and, if it's overridden, modifying it and
+// everything it holds. Copies and moves must also be able to write
+// everything they bring to where it goes; extractions check each of
+// their entries as they go.
+func checkPatchRules(action, src, dst string, override bool, d *data) (int, error) {
+	srcAction := rules.Read
+	if action == "rename" {
+		srcAction = rules.Delete
+	}
+	if ok, err := d.checkTree(src, srcAction); err != nil {
+		return errToStatus(err), err
+	} else if !ok {
+		return http.StatusForbidden, nil
+	}
+
+	if !d.CheckAction(dst, rules.Create) {
+		return http.StatusForbidden, nil
+	}
+	if override {
+		if _, err := d.user.Fs.Stat(dst); err == nil {
+			if ok, err := d.checkTree(dst, rules.Modify); err != nil {
+				return errToStatus(err), err
+			} else if !ok {
+				return http.StatusForbidden, nil
+			}
+		}
+	}
+	if action == "copy" || action == "rename" {
+		if ok, err := d.checkTargets(src, dst); err != nil {
+			return errToStatus(err), err
+		} else if !ok {
+			return http.StatusForbidden, nil
+		}
+	}
+	return 0, nil
+}
+
 func checkParent(src, dst string) error {
 	rel, err := filepath.Rel(src, dst)
 	if err != nil {
@@ -456,14 +500,20 @@ func delThumbs(ctx context.Context, fileCache FileCache, file *files.FileInfo) e
 	return nil
 }
 
-func patchAction(ctx context.Context, action, src, dst string, d *data, fileCache FileCache) error {
+func patchAction(ctx context.Context, action, src, dst string, override bool, d *data, fileCache FileCache, hub *events.Hub) error {
 	switch action {
 	case "copy":
 		if !d.user.Perm.Create {
 			return fbErrors.ErrPermissionDenied
 		}
 
-		return fileutils.Copy(d.user.Fs, src, dst)
+		if err := fileutils.Copy(d.user.Fs, src, dst); err != nil {
+			return err
+		}
+
+		isDir, _ := afero.IsDir(d.user.Fs, dst)
+		publishEvent(hub, d, events.Create, dst, "", isDir)
+		return nil
 	case "rename":
 		if !d.user.Perm.Rename {
 			return fbErrors.ErrPermissionDenied
@@ -489,51 +539,147 @@ func patchAction(ctx context.Context, action, src, dst string, d *data, fileCach
 			return err
 		}
 
-		return fileutils.MoveFile(d.user.Fs, src, dst)
-	default:
-		return fmt.Errorf("unsupported action %s: %w", action, fbErrors.ErrInvalidRequestParams)
-	}
-}
+		if err := fileutils.MoveFile(d.user.Fs, src, dst); err != nil {
+			return err
+		}
 
-type DiskUsageResponse struct {
-	Total uint64 `json:"total"`
-	Used  uint64 `json:"used"`
-}
+		publishEvent(hub, d, events.Rename, src, dst, file.IsDir)
+		return nil
+	case "extract":
+		if !d.user.Perm.Create {
+			return fbErrors.ErrPermissionDenied
+		}
 
-var diskUsage = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	if strings.HasPrefix(r.URL.Path, "/virtual") {
-		return renderJSON(w, r, &DiskUsageResponse{
-			Total: 0,
-			Used:  0,
+		err := archive.Extract(ctx, d.user.Fs, src, dst, archive.ExtractOptions{
+			Checker:  d,
+			Override: override,
 		})
+		if err != nil {
+			return err
+		}
+
+		publishEvent(hub, d, events.Create, dst, "", true)
+		return nil
+	default:
+		return fmt.Errorf("unsupported action %s: %w", action, fbErrors.ErrInvalidRequestParams)
 	}
+}
 
+// enqueuePatchJob queues a copy, rename or extraction as a background
+// job after the same permission checks patchAction does.
+func enqueuePatchJob(
+	w http.ResponseWriter,
+	r *http.Request,
+	action, src, dst string,
+	override bool,
+	d *data,
+	fileCache FileCache,
+	jobManager *jobs.Manager,
+) (int, error) {
 	file, err := files.NewFileInfo(&files.FileOptions{
 		Fs:         d.user.Fs,
-		Path:       r.URL.Path,
+		Path:       src,
 		Modify:     d.user.Perm.Modify,
 		Expand:     false,
 		ReadHeader: false,
 		Checker:    d,
-		Content:    false,
 	})
 	if err != nil {
 		return errToStatus(err), err
 	}
-	fPath := file.RealPath()
-	if !file.IsDir {
-		return renderJSON(w, r, &DiskUsageResponse{
-			Total: 0,
-			Used:  0,
-		})
-	}
 
-	usage, err := disk.UsageWithContext(r.Context(), fPath)
-	if err != nil {
-		return errToStatus(err), err
+	switch action {
+	case "copy":
+		if !d.user.Perm.Create {
+			return http.StatusForbidden, nil
+		}
+	case "rename":
+		if !d.user.Perm.Rename {
+			return http.StatusForbidden, nil
+		}
+		if err := delThumbs(r.Context(), fileCache, file); err != nil {
+			return errToStatus(err), err
+		}
+	case "extract":
+		if !d.user.Perm.Create {
+			return http.StatusForbidden, nil
+		}
+		if file.IsDir || !archive.IsArchive(file.Name) {
+			return http.StatusBadRequest, fmt.Errorf("%s is not a supported archive: %w", src, fbErrors.ErrInvalidRequestParams)
+		}
+	default:
+		return http.StatusBadRequest, fmt.Errorf("unsupported action %s: %w", action, fbErrors.ErrInvalidRequestParams)
 	}
-	return renderJSON(w, r, &DiskUsageResponse{
-		Total: usage.Total,
-		Used:  usage.Used,
+
+	return enqueueJob(w, d, jobManager, &jobs.Job{
+		Action:   jobs.Action(action),
+		Src:      path.Clean("/" + src),
+		Dst:      path.Clean("/" + dst),
+		IsDir:    file.IsDir,
+		Override: override,
 	})
-})
+}
+
+type DiskUsageResponse struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+	// Quota is set for users with a quota.
+	Quota *QuotaUsage `json:"quota,omitempty"`
+}
+
+// QuotaUsage is how much of their quota users use. Zero limits are
+// unlimited.
+type QuotaUsage struct {
+	Limit users.Quota `json:"limit"`
+	Used  quota.Usage `json:"used"`
+}
+
+func diskUsage(quotas *quota.Tracker) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		if strings.HasPrefix(r.URL.Path, "/virtual") {
+			return renderJSON(w, r, &DiskUsageResponse{
+				Total: 0,
+				Used:  0,
+			})
+		}
+
+		file, err := files.NewFileInfo(&files.FileOptions{
+			Fs:         d.user.Fs,
+			Path:       r.URL.Path,
+			Modify:     d.user.Perm.Modify,
+			Expand:     false,
+			ReadHeader: false,
+			Checker:    d,
+			Content:    false,
+		})
+		if err != nil {
+			return errToStatus(err), err
+		}
+		fPath := file.RealPath()
+		if !file.IsDir {
+			return renderJSON(w, r, &DiskUsageResponse{
+				Total: 0,
+				Used:  0,
+			})
+		}
+
+		usage, err := disk.UsageWithContext(r.Context(), fPath)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		resp := &DiskUsageResponse{
+			Total: usage.Total,
+			Used:  usage.Used,
+		}
+
+		root, ok := scopeRoot(d)
+		if ok && quotas != nil && !d.user.Quota.Unlimited() {
+			used, err := quotas.Usage(root)
+			if err != nil {
+				return errToStatus(err), err
+			}
+			resp.Quota = &QuotaUsage{Limit: d.user.Quota, Used: used}
+		}
+		return renderJSON(w, r, resp)
+	})
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/diskcache"
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/trash"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestActionRules(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"boot/cmdline.txt", "roms/bios/scph1001.bin", "roms/game.iso", "incoming/upload.zip", "saves/locked/slot.sav", "backup/locked/slot.sav"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Create: true, Rename: true, Modify: true, Delete: true, Download: true},
+		Rules: []rules.Rule{
+			{Path: "/boot", Actions: []rules.Action{rules.Create, rules.Modify, rules.Delete}},
+			{Path: "/roms/bios", Actions: []rules.Action{rules.Delete}},
+			{Path: "/incoming", Actions: []rules.Action{rules.Read}},
+			{Path: "/saves/locked", Actions: []rules.Action{rules.Modify}},
+			{Path: "/new/locked", Actions: []rules.Action{rules.Create}},
+		},
+	})
+
+	cache, hub := diskcache.NewNoOp(), events.NewHub()
+	for _, c := range []struct {
+		method, url string
+		handler     handleFunc
+		prefix      string
+		want        int
+	}{
+		{http.MethodGet, "/api/resources/boot/cmdline.txt", resourceGetHandler, "/api/resources", http.StatusOK},
+		{http.MethodPut, "/api/resources/boot/cmdline.txt", resourcePutHandler(hub, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPost, "/api/resources/boot/new.txt", resourcePostHandler(cache, hub, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPost, "/api/resources/boot/dir/", resourcePostHandler(cache, hub, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodDelete, "/api/resources/boot/cmdline.txt", resourceDeleteHandler(cache, hub, nil, true), "/api/resources", http.StatusForbidden},
+		{http.MethodPost, "/api/tus/boot/new.txt", tusPostHandler(hub, nil), "/api/tus", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/roms/game.iso?action=copy&destination=/boot/game.iso", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		// Deleting a directory holding undeletable files fails too.
+		{http.MethodDelete, "/api/resources/roms/", resourceDeleteHandler(cache, hub, nil, true), "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/roms/bios/scph1001.bin?action=rename&destination=/scph1001.bin", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/roms/bios/scph1001.bin?action=copy&destination=/scph1001.bin", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusOK},
+		{http.MethodGet, "/api/raw/incoming/upload.zip", rawHandler, "/api/raw", http.StatusForbidden},
+		{http.MethodGet, "/api/resources/incoming/upload.zip", resourceGetHandler, "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/incoming/upload.zip?action=copy&destination=/upload.zip", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPost, "/api/resources/incoming/new.zip", resourcePostHandler(cache, hub, nil), "/api/resources", http.StatusOK},
+		// Overriding or creating directories checks what they hold.
+		{http.MethodPatch, "/api/resources/backup/?action=copy&destination=/saves/&override=true", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/backup/?action=copy&destination=/new/", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/backup/?action=rename&destination=/new/", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusForbidden},
+		{http.MethodPatch, "/api/resources/backup/?action=copy&destination=/other/", resourcePatchHandler(cache, hub, nil, nil), "/api/resources", http.StatusOK},
+		// What reads files by itself can't read them either.
+		{http.MethodGet, "/api/subtitle/incoming/movie.srt", subtitleHandler, "/api/subtitle", http.StatusForbidden},
+		{http.MethodGet, "/api/dupes/incoming/", dupesHandler, "/api/dupes", http.StatusForbidden},
+		{http.MethodGet, "/api/manifest/incoming/upload.zip", manifestGetHandler(nil), "/api/manifest", http.StatusForbidden},
+		{http.MethodPost, "/api/manifest/incoming/?algo=md5", manifestPostHandler(hub, nil, nil), "/api/manifest", http.StatusForbidden},
+		{http.MethodPost, "/api/manifest/boot/?algo=md5", manifestPostHandler(hub, nil, nil), "/api/manifest", http.StatusForbidden},
+		{http.MethodDelete, "/api/resources/roms/game.iso", resourceDeleteHandler(cache, hub, nil, true), "/api/resources", http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(c.method, c.url, strings.NewReader("data"))
+		if status, body := ts.serve(c.handler, c.prefix, req); status != c.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", c.method, c.url, c.want, status, body)
+		}
+	}
+
+	// Directories that can't be read are listed empty.
+	req := httptest.NewRequest(http.MethodGet, "/api/resources/incoming/", nil)
+	status, body := ts.serve(resourceGetHandler, "/api/resources", req)
+	var dir files.FileInfo
+	if err := json.Unmarshal(body, &dir); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	if len(dir.Items) != 0 {
+		t.Errorf("expected an empty listing, got %s", body)
+	}
+
+	// Items can't be restored where nothing can be created.
+	req = httptest.NewRequest(http.MethodDelete, "/api/resources/backup/locked/slot.sav", nil)
+	if status, body = ts.serve(resourceDeleteHandler(cache, hub, nil, false), "/api/resources", req); status != http.StatusNoContent {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	status, body = ts.serve(trashListHandler, "", httptest.NewRequest(http.MethodGet, "/api/trash", nil))
+	var items []trash.Item
+	if err := json.Unmarshal(body, &items); status != http.StatusOK || err != nil || len(items) != 1 {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	restore := func(dst string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/trash/"+items[0].ID+"/restore?destination="+dst, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": items[0].ID})
+		status, _ := ts.serve(trashRestoreHandler(cache, hub, nil), "", req)
+		return status
+	}
+	if status := restore("/boot/slot.sav"); status != http.StatusForbidden {
+		t.Errorf("expected restoring into /boot to be forbidden, got %d", status)
+	}
+	if status := restore("/backup/locked/slot.sav"); status != http.StatusOK {
+		t.Errorf("expected the item to be restored, got %d", status)
+	}
+
+	// Uploads can only append to what they created themselves.
+	quotas := quota.NewTracker()
+	for _, c := range []struct {
+		method, url string
+		headers     map[string]string
+		handler     handleFunc
+		want        int
+	}{
+		{http.MethodPatch, "/api/tus/saves/locked/slot.sav", map[string]string{"Upload-Offset": "21"}, tusPatchHandler(hub, quotas), http.StatusForbidden},
+		{http.MethodPost, "/api/tus/saves/locked/new.sav", map[string]string{"Upload-Length": "4"}, tusPostHandler(hub, quotas), http.StatusCreated},
+		{http.MethodPatch, "/api/tus/saves/locked/new.sav", map[string]string{"Upload-Offset": "0"}, tusPatchHandler(hub, quotas), http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(c.method, c.url, strings.NewReader("data"))
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		if status, body := ts.serve(c.handler, "/api/tus", req); status != c.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", c.method, c.url, c.want, status, body)
+		}
+	}
+}
+
+func TestTrashHidden(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{".trash/notes.txt", "users/bob/.trash/2/files/x/save.sav", "users/bob/old/.trash/2/a.txt"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "admin",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Admin: true, Download: true},
+	})
+	bob := &users.User{Username: "bob", Password: "pw", Scope: "users/bob"}
+	if err := ts.storage.Users.Save(bob); err != nil {
+		t.Fatal(err)
+	}
+	if bob.ID != 2 {
+		t.Fatalf("expected bob to get id 2, got %d", bob.ID)
+	}
+
+	// Only the bins of the server are hidden, not whatever is called
+	// like them.
+	for url, want := range map[string]int{
+		"/api/raw/.trash/notes.txt":                    http.StatusOK,
+		"/api/raw/users/bob/old/.trash/2/a.txt":        http.StatusOK,
+		"/api/raw/users/bob/.trash/2/files/x/save.sav": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if status, body := ts.serve(rawHandler, "/api/raw", req); status != want {
+			t.Errorf("%s: expected %d, got %d: %s", url, want, status, body)
+		}
+	}
+}
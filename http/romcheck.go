@@ -0,0 +1,61 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/romcheck"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// romcheckHandler lists the DAT files of the DAT directory of the
+// server or, given one of them as ?dat=, verifies the ROM directory
+// at the path of the request against it.
+var romcheckHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if d.server.DatDir == "" {
+		return http.StatusNotFound, nil
+	}
+
+	name := r.URL.Query().Get("dat")
+	if name == "" {
+		list, err := romcheck.List(d.server.DatDir)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		return renderJSON(w, r, list)
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return http.StatusBadRequest, fmt.Errorf("invalid DAT file %q: %w", name, fbErrors.ErrInvalidRequestParams)
+	}
+	if !d.CheckAction(r.URL.Path, rules.Read) {
+		return http.StatusForbidden, nil
+	}
+
+	dir, err := files.NewFileInfo(&files.FileOptions{
+		Fs:      d.user.Fs,
+		Path:    slashClean(r.URL.Path),
+		Modify:  d.user.Perm.Modify,
+		Expand:  false,
+		Checker: d,
+	})
+	if err != nil {
+		return errToStatus(err), err
+	}
+	if !dir.IsDir {
+		return http.StatusBadRequest, fmt.Errorf("%s is not a directory: %w", dir.Path, fbErrors.ErrInvalidRequestParams)
+	}
+
+	dat, err := romcheck.Load(filepath.Join(d.server.DatDir, name))
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	report, err := romcheck.Check(r.Context(), d.user.Fs, dir.Path, dat, rules.ForAction(d, rules.Read))
+	if err != nil {
+		return errToStatus(err), err
+	}
+	return renderJSON(w, r, report)
+})
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/romcheck"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestRomcheck(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "gb"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "gb", "tetris.gb"), []byte("tetris"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, root, &users.User{Username: "player", Password: "pw", Scope: "."})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/romcheck", nil)
+	if status, _ := ts.serve(romcheckHandler, "/api/romcheck", req); status != http.StatusNotFound {
+		t.Errorf("expected 404 without a DAT directory, got %d", status)
+	}
+
+	ts.server.DatDir = t.TempDir()
+	dat := `game ( name tetris rom ( name tetris.gb size 6 crc 1d6b1d1d ) )`
+	if err := os.WriteFile(filepath.Join(ts.server.DatDir, "gb.dat"), []byte(dat), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/romcheck", nil)
+	status, body := ts.serve(romcheckHandler, "/api/romcheck", req)
+	var list []*romcheck.DatFile
+	if err := json.Unmarshal(body, &list); status != http.StatusOK || err != nil || len(list) != 1 || list[0].Games != 1 {
+		t.Fatalf("unexpected DAT list %d %s", status, body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/romcheck/gb?dat=gb.dat", nil)
+	status, body = ts.serve(romcheckHandler, "/api/romcheck", req)
+	var report romcheck.Report
+	if err := json.Unmarshal(body, &report); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected report %d %s", status, body)
+	}
+	if report.Path != "/gb" || len(report.Results) != 1 {
+		t.Errorf("unexpected report %s", body)
+	}
+
+	for _, url := range []string{"/api/romcheck/gb?dat=../gb.dat", "/api/romcheck/gb/tetris.gb?dat=gb.dat"} {
+		req = httptest.NewRequest(http.MethodGet, url, nil)
+		if status, _ = ts.serve(romcheckHandler, "/api/romcheck", req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, status)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// rulesExplainHandler tells which of the global rules, those of the
+// groups of a user and its own match the path query parameter, and
+// what they decide on it. The user query parameter, an id or a
+// username, names the user, which defaults to the one asking. The
+// global rules can hide paths from the users they apply to, so only
+// admins can see them.
+var rulesExplainHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		return http.StatusBadRequest, fmt.Errorf("missing path: %w", fbErrors.ErrInvalidRequestParams)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	user := d.user
+	if name := r.URL.Query().Get("user"); name != "" {
+		var id interface{} = name
+		if n, err := strconv.ParseUint(name, 10, 0); err == nil {
+			id = uint(n)
+		}
+
+		var err error
+		user, err = d.store.Users.Get(d.server.Root, id)
+		if err != nil {
+			return errToStatus(err), err
+		}
+	}
+
+	list, err := d.store.Groups.Of(user)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	sources := groups.RuleSources(d.settings.Rules, user, list)
+	return renderJSON(w, r, rules.Explain(path, user.HideDotfiles, sources...))
+})
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestRulesExplain(t *testing.T) {
+	ts := newTestServer(t, t.TempDir(), &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Rules:    []rules.Rule{{Glob: true, Path: "**/*.srm", Actions: []rules.Action{rules.Delete}}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rules/explain?path=saves/zelda.srm", nil)
+	if status, _ := ts.serve(rulesExplainHandler, "", req); status != http.StatusForbidden {
+		t.Errorf("expected 403 for a user who isn't an admin, got %d", status)
+	}
+
+	ts.user.Perm.Admin = true
+	if err := ts.storage.Users.Update(ts.user, "Perm"); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/rules/explain?path=saves/zelda.srm", nil)
+	status, body := ts.serve(rulesExplainHandler, "", req)
+	var e rules.Explanation
+	if err := json.Unmarshal(body, &e); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	if e.Path != "/saves/zelda.srm" || len(e.Matches) != 1 || e.Matches[0].Source != "user" || e.Actions[rules.Delete].Allow || !e.Visible.Allow {
+		t.Errorf("unexpected explanation %s", body)
+	}
+
+	// Other users are explained by id or username.
+	bob := &users.User{
+		Username: "bob",
+		Password: "pw",
+		Scope:    ".",
+		Rules:    []rules.Rule{{Path: "/saves", Actions: []rules.Action{rules.Modify}}},
+	}
+	if err := ts.storage.Users.Save(bob); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"bob", strconv.FormatUint(uint64(bob.ID), 10)} {
+		req = httptest.NewRequest(http.MethodGet, "/api/rules/explain?path=saves/zelda.srm&user="+user, nil)
+		status, body = ts.serve(rulesExplainHandler, "", req)
+		var e rules.Explanation
+		if err := json.Unmarshal(body, &e); status != http.StatusOK || err != nil {
+			t.Fatalf("unexpected response %d: %s", status, body)
+		}
+		if len(e.Matches) != 1 || e.Actions[rules.Modify].Allow || !e.Actions[rules.Delete].Allow {
+			t.Errorf("user %s: unexpected explanation %s", user, body)
+		}
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/rules/explain?path=saves/zelda.srm&user=nobody", nil)
+	if status, _ = ts.serve(rulesExplainHandler, "", req); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", status)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/rules/explain", nil)
+	if status, _ = ts.serve(rulesExplainHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 without path, got %d", status)
+	}
+
+	// Invalid patterns can't be saved.
+	set, err := ts.storage.Settings.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Rules = []rules.Rule{{Regex: true, Regexp: &rules.Regexp{Raw: "["}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(string(data)))
+	if status, body = ts.serve(settingsPutHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid regex, got %d: %s", status, body)
+	}
+	if err := ts.storage.Users.Update(&users.User{ID: ts.user.ID, Rules: []rules.Rule{{Glob: true, Path: "["}}}, "Rules"); err == nil {
+		t.Error("expected an error for an invalid glob")
+	}
+}
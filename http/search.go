@@ -1,28 +1,135 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/search"
+	"github.com/filebrowser/filebrowser/v2/searchindex"
 )
 
-var searchHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	response := []map[string]interface{}{}
-	query := r.URL.Query().Get("query")
+// defaultSearchLimit is the number of results returned when the limit
+// query parameter isn't set.
+const defaultSearchLimit = 1000
 
-	err := search.Search(d.user.Fs, r.URL.Path, query, d, func(path string, f os.FileInfo) error {
-		response = append(response, map[string]interface{}{
-			"dir":  f.IsDir(),
-			"path": path,
-		})
+// searchSummary ends the results of a search. Truncated is set when
+// the search stopped at the limit or the timeout.
+type searchSummary struct {
+	Count     int  `json:"count"`
+	Truncated bool `json:"truncated"`
+}
 
-		return nil
-	})
+// searchHandler searches the directory of the request, listing its
+// files from idx when it's up to date. Results are streamed as server
+// sent "result" events as they're found, followed by a "done" event
+// with a searchSummary, or an "error" event if the search failed
+// midway. The search stops when the client goes away.
+func searchHandler(idx *searchindex.Index) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		query := r.URL.Query().Get("query")
+
+		limit := defaultSearchLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+				return http.StatusBadRequest, fmt.Errorf("invalid limit %q: %w", value, fbErrors.ErrInvalidRequestParams)
+			}
+		}
+
+		ctx := r.Context()
+		if value := r.URL.Query().Get("timeout"); value != "" {
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return http.StatusBadRequest, fmt.Errorf("invalid timeout %q: %w", value, fbErrors.ErrInvalidRequestParams)
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return http.StatusNotImplemented, nil
+		}
+
+		// The response starts with the first result, so that invalid
+		// queries still get a status.
+		started := false
+		send := func(event string, v interface{}) error {
+			if !started {
+				started = true
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("X-Accel-Buffering", "no")
+				w.WriteHeader(http.StatusOK)
+			}
 
-	if err != nil {
-		return http.StatusInternalServerError, err
-	}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		}
 
-	return renderJSON(w, r, response)
-})
+		summary := searchSummary{}
+		err := search.Search(ctx, d.user.Fs, userIndex(idx, d), r.URL.Path, query, rules.ForAction(d, rules.Read), func(path string, f os.FileInfo, matches []search.Match) error {
+			if summary.Count >= limit {
+				summary.Truncated = true
+				return search.SkipAll
+			}
+
+			item := map[string]interface{}{
+				"dir":     f.IsDir(),
+				"path":    path,
+				"size":    f.Size(),
+				"modTime": f.ModTime(),
+			}
+			if !f.IsDir() {
+				typ := files.TypeByName(f.Name())
+				if typ == "textImmutable" && d.user.Perm.Modify {
+					typ = "text"
+				}
+				item["type"] = typ
+			}
+			if matches != nil {
+				item["matches"] = matches
+			}
+
+			summary.Count++
+			return send("result", item)
+		})
+
+		switch {
+		case r.Context().Err() != nil:
+			// Nobody's listening anymore.
+			return 0, nil
+		case errors.Is(err, context.DeadlineExceeded):
+			summary.Truncated = true
+		case errors.Is(err, fbErrors.ErrInvalidRequestParams) && !started:
+			return http.StatusBadRequest, err
+		case err != nil && !started:
+			return http.StatusInternalServerError, err
+		case err != nil:
+			_ = send("error", err.Error())
+			return 0, err
+		}
+
+		if err := send("done", summary); err != nil {
+			return 0, err
+		}
+		return 0, nil
+	})
+}
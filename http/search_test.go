@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// searchResults parses the events of a search response.
+func searchResults(t *testing.T, body []byte) ([]map[string]interface{}, *searchSummary) {
+	t.Helper()
+
+	var results []map[string]interface{}
+	var summary *searchSummary
+	var event string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			var err error
+			switch event {
+			case "result":
+				var result map[string]interface{}
+				err = json.Unmarshal(data, &result)
+				results = append(results, result)
+			case "done":
+				err = json.Unmarshal(data, &summary)
+			default:
+				t.Fatalf("unexpected event %s: %s", event, data)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if summary == nil {
+		t.Fatalf("missing done event in %s", body)
+	}
+	return results, summary
+}
+
+func TestSearchHandler(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"mario.sfc", "zelda.sfc", "zelda.gba"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := newTestServer(t, root, &users.User{Username: "player", Password: "pw", Scope: "."})
+
+	for url, expected := range map[string]searchSummary{
+		"/api/search/?query=sfc":            {Count: 2},
+		"/api/search/?query=sfc&limit=1":    {Count: 1, Truncated: true},
+		"/api/search/?query=sfc&limit=2":    {Count: 2},
+		"/api/search/?query=ext:nes":        {Count: 0},
+		"/api/search/?query=sfc&timeout=1m": {Count: 2},
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		status, body := ts.serve(searchHandler(nil), "/api/search", req)
+		if status != http.StatusOK {
+			t.Fatalf("%s: unexpected response %d: %s", url, status, body)
+		}
+		results, summary := searchResults(t, body)
+		if *summary != expected || len(results) != expected.Count {
+			t.Errorf("%s: expected %+v, got %+v and %d results", url, expected, summary, len(results))
+		}
+	}
+
+	for _, url := range []string{"/api/search/?query=size:big", "/api/search/?query=a&limit=0", "/api/search/?query=a&timeout=soon"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if status, _ := ts.serve(searchHandler(nil), "/api/search", req); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, status)
+		}
+	}
+}
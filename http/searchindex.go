@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/search"
+	"github.com/filebrowser/filebrowser/v2/searchindex"
+	"github.com/filebrowser/filebrowser/v2/settings"
+)
+
+// openSearchIndex opens the search index of the server, if it has one,
+// and keeps it up to date with the changes published to hub until ctx
+// is done.
+func openSearchIndex(ctx context.Context, server *settings.Server, hub *events.Hub) *searchindex.Index {
+	if server.SearchIndex == "" {
+		return nil
+	}
+
+	idx, err := searchindex.Open(server.SearchIndex, server.Root)
+	if err != nil {
+		log.Printf("Failed to open the search index %s: %v", server.SearchIndex, err)
+		return nil
+	}
+
+	idx.Start(ctx, hub)
+	return idx
+}
+
+// userIndex returns the part of idx under the scope of the user, or
+// nil if there's none.
+func userIndex(idx *searchindex.Index, d *data) search.Index {
+	if idx == nil {
+		return nil
+	}
+
+	root, ok := scopeRoot(d)
+	if !ok {
+		return nil
+	}
+	sub, ok := idx.Sub(root)
+	if !ok {
+		return nil
+	}
+	return sub
+}
+
+func searchIndexGetHandler(idx *searchindex.Index) handleFunc {
+	return withAdmin(func(w http.ResponseWriter, r *http.Request, _ *data) (int, error) {
+		if idx == nil {
+			return http.StatusNotFound, nil
+		}
+
+		status, err := idx.Status()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return renderJSON(w, r, status)
+	})
+}
+
+// searchIndexPostHandler crawls the root again in the background. The
+// index keeps being used in the meantime if it's up to date.
+func searchIndexPostHandler(idx *searchindex.Index) handleFunc {
+	return withAdmin(func(w http.ResponseWriter, _ *http.Request, _ *data) (int, error) {
+		if idx == nil {
+			return http.StatusNotFound, nil
+		}
+
+		idx.Rebuild()
+		status, err := idx.Status()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			return 0, err
+		}
+		return 0, nil
+	})
+}
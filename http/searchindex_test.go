@@ -0,0 +1,73 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/searchindex"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestSearchIndex(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"games/zelda.sfc", "games/mario.sfc", "other/zelda.txt"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx, err := searchindex.Open(filepath.Join(t.TempDir(), "index.db"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files removed behind the back of the index are still found,
+	// since it's not walked.
+	if err := os.Remove(filepath.Join(root, "games/mario.sfc")); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, &users.User{Username: "player", Password: "pw", Scope: "games"})
+	req := httptest.NewRequest(http.MethodGet, "/api/search/?query=sfc", nil)
+	status, body := ts.serve(searchHandler(idx), "/api/search", req)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	if results, _ := searchResults(t, body); len(results) != 2 { //nolint:gomnd
+		t.Errorf("expected the indexed files of the scope, got %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/searchindex", nil)
+	if status, _ = ts.serve(searchIndexGetHandler(idx), "", req); status != http.StatusForbidden {
+		t.Errorf("expected 403 for users, got %d", status)
+	}
+
+	ts.user.Perm.Admin = true
+	if err := ts.storage.Users.Update(ts.user, "Perm"); err != nil {
+		t.Fatal(err)
+	}
+	status, body = ts.serve(searchIndexGetHandler(idx), "", req)
+	var indexStatus searchindex.Status
+	if err := json.Unmarshal(body, &indexStatus); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	if indexStatus.State != searchindex.Ready || indexStatus.Entries != 6 { //nolint:gomnd
+		t.Errorf("unexpected status %s", body)
+	}
+
+	if status, _ = ts.serve(searchIndexGetHandler(nil), "", req); status != http.StatusNotFound {
+		t.Errorf("expected 404 without index, got %d", status)
+	}
+}
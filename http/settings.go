@@ -2,10 +2,13 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
 	"github.com/filebrowser/filebrowser/v2/rules"
 	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/virtual"
 )
 
 type settingsData struct {
@@ -18,6 +21,7 @@ type settingsData struct {
 	Tus              settings.Tus          `json:"tus"`
 	Shell            []string              `json:"shell"`
 	Commands         map[string][]string   `json:"commands"`
+	Virtual          []settings.VirtualDir `json:"virtual"`
 }
 
 var settingsGetHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
@@ -31,6 +35,7 @@ var settingsGetHandler = withAdmin(func(w http.ResponseWriter, r *http.Request,
 		Tus:              d.settings.Tus,
 		Shell:            d.settings.Shell,
 		Commands:         d.settings.Commands,
+		Virtual:          d.settings.Virtual,
 	}
 
 	return renderJSON(w, r, data)
@@ -52,7 +57,17 @@ var settingsPutHandler = withAdmin(func(_ http.ResponseWriter, r *http.Request,
 	d.settings.Tus = req.Tus
 	d.settings.Shell = req.Shell
 	d.settings.Commands = req.Commands
+	if req.Virtual != nil {
+		// Bad providers would only fail later, on every virtual request.
+		if _, err := virtual.NewRegistry(req.Virtual); err != nil {
+			return http.StatusBadRequest, err
+		}
+		d.settings.Virtual = req.Virtual
+	}
 
 	err = d.store.Settings.Save(d.settings)
+	if errors.Is(err, fbErrors.ErrInvalidOption) {
+		return http.StatusBadRequest, err
+	}
 	return errToStatus(err), err
 })
@@ -8,12 +8,16 @@ import (
 	"github.com/asticode/go-astisub"
 
 	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/rules"
 )
 
 var subtitleHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
 	if !d.user.Perm.Download {
 		return http.StatusAccepted, nil
 	}
+	if !d.CheckAction(r.URL.Path, rules.Read) {
+		return http.StatusForbidden, nil
+	}
 
 	file, err := files.NewFileInfo(&files.FileOptions{
 		Fs:         d.user.Fs,
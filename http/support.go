@@ -1,124 +1,85 @@
 package http
 
 import (
-	"bufio"
-	"context"
 	"fmt"
-	"io"
 	"log"
-	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"regexp"
-	"syscall"
-	"time"
 
-	"github.com/google/go-github/github"
+	"github.com/filebrowser/filebrowser/v2/metrics"
+	"github.com/filebrowser/filebrowser/v2/support"
+	"github.com/filebrowser/filebrowser/v2/supportbundle"
+	"github.com/filebrowser/filebrowser/v2/sysinfo"
 )
 
-type SystemInfo struct {
-	ReturnCode int    `json:"returnCode"`
-	Output     string `json:"output"`
-}
-
-type Cloudflared struct {
-	ProxyURL    string    `json:"proxyURL"`
-	Pid         int       `json:"pid"`
-	TimeStarted time.Time `json:"timeStarted"`
-	SessionCode string    `json:"sessionCode"`
-	Started     bool      `json:"started"`
-}
-
-var cloudflaredCmd *exec.Cmd
-var cloudflaredPid int
-var cloudflaredStartTime time.Time
-var cloudflaredProxyURL string
-var cloudflaredConnected bool
-var supportSessionCode string
-
-func downloadCloudflared(filePath string) error {
-	if _, err := os.Stat(filePath); err == nil {
-		return nil
-	} else if !os.IsNotExist(err) {
-		return err
-	}
+var sysinfoScript = []string{"/rcade/scripts/rcade-commands.sh", "sysinfo"}
 
-	ctx := context.Background()
-
-	client := github.NewClient(nil)
-	owner := "cloudflare"
-	repo := "cloudflared"
-
-	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+func supportCollectors(d *data) ([]supportbundle.Collector, error) {
+	registry, err := virtualRegistry(d)
 	if err != nil {
-		log.Fatalf("Error getting latest release: %v", err)
+		return nil, err
 	}
 
-	releaseTag := release.GetTagName()
-
-	desiredAssetName := "cloudflared-linux-arm"
-	var foundAsset github.ReleaseAsset
+	collectors := []supportbundle.Collector{
+		supportbundle.Virtual(registry),
+		supportbundle.Sysinfo(sysinfoScript),
+	}
 
-	for _, asset := range release.Assets {
-		if asset.GetName() == desiredAssetName {
-			foundAsset = asset
-			break
+	if d.user.Perm.Admin {
+		list, err := d.store.Users.Gets(d.server.Root)
+		if err != nil {
+			return nil, err
 		}
+
+		collectors = append(collectors,
+			supportbundle.Settings(d.settings, d.server),
+			supportbundle.Users(list),
+		)
 	}
 
-	downloadURL := foundAsset.GetBrowserDownloadURL()
-	log.Printf("Downloading cloudflared %s\n", releaseTag)
+	return collectors, nil
+}
 
-	outFile, err := os.Create(filePath)
+// supportFileHandler sends a support bundle. It's written to a
+// temporary file first, so that failing to write it can still be
+// reported to the client.
+var supportFileHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	format, err := supportbundle.ParseFormat(r.URL.Query().Get("algo"))
 	if err != nil {
-		log.Fatalf("Error creating file: %v", err)
+		return errToStatus(err), err
 	}
-	defer outFile.Close()
 
-	resp, err := http.Get(downloadURL)
+	collectors, err := supportCollectors(d)
 	if err != nil {
-		log.Fatalf("Error downloading the file: %v", err)
+		return errToStatus(err), err
 	}
-	defer resp.Body.Close()
 
-	_, err = io.Copy(outFile, resp.Body)
+	tmp, err := os.CreateTemp("", "support-*"+format.Extension())
 	if err != nil {
-		log.Fatalf("Error writing to file: %v", err)
+		return http.StatusInternalServerError, err
 	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	log.Printf("cloudflared downloaded to %s\n", filePath)
-	log.Printf("Changing permissions of %s to 0755", filePath)
-
-	err = os.Chmod(filePath, 0755)
+	log.Println("Generating support bundle")
+	manifest, err := supportbundle.Write(r.Context(), tmp, format, collectors)
 	if err != nil {
-		log.Fatalf("Error setting file permissions: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("failed to write support bundle: %w", err)
 	}
 
-	return nil
-}
-
-func generateSupportFile() {
-	log.Println("Generating support file")
-	cmd := exec.Command("/rcade/scripts/rcade-commands.sh", "supportfiles")
-
-	// Run the command and wait for it to complete
-	err := cmd.Run()
-	if err != nil {
-		log.Fatalf("Command finished with error: %v", err)
+	for _, report := range manifest.Collectors {
+		for _, e := range report.Errors {
+			log.Printf("Support bundle: %s: %s", report.Name, e)
+		}
 	}
+	log.Println("Support bundle generated")
 
-	log.Println("Support file generated")
-}
-
-func generateSupportSessionCode() {
-	supportSessionCode = fmt.Sprintf("%04d-%04d-%04d", rand.Intn(10000), rand.Intn(10000), rand.Intn(10000))
-}
-
-var supportFileHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	generateSupportFile()
-
-	return http.StatusNoContent, nil
+	name := "support-" + manifest.Created.Format("20060102-150405") + format.Extension()
+	w.Header().Set("Content-Disposition", "attachment; filename*=utf-8''"+url.PathEscape(name))
+	http.ServeContent(w, r, name, manifest.Created, tmp)
+	return 0, nil
 })
 
 var supportRemountHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
@@ -135,119 +96,51 @@ var supportRemountHandler = withAdmin(func(w http.ResponseWriter, r *http.Reques
 	return http.StatusNoContent, nil
 })
 
-var supportSessionStatusHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	cloudflared := Cloudflared{ProxyURL: cloudflaredProxyURL, Pid: cloudflaredPid, TimeStarted: cloudflaredStartTime, Started: cloudflaredConnected, SessionCode: supportSessionCode}
-	return renderJSON(w, r, cloudflared)
-})
+func supportSessionStatusHandler(tunnels *support.TunnelManager) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, _ *data) (int, error) {
+		return renderJSON(w, r, tunnels.Status())
+	})
+}
 
-var supportStopSessionHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	if cloudflaredPid == 0 {
+func supportStopSessionHandler(tunnels *support.TunnelManager) handleFunc {
+	return withUser(func(_ http.ResponseWriter, _ *http.Request, _ *data) (int, error) {
+		tunnels.Stop()
 		return http.StatusNoContent, nil
-	}
+	})
+}
 
-	if cloudflaredCmd != nil && cloudflaredCmd.Process != nil {
-		log.Printf("Stopping cloudflared (pid: %d)", cloudflaredPid)
-		err := cloudflaredCmd.Process.Signal(syscall.SIGTERM)
-		if err != nil {
-			log.Printf("Error stopping cloudflared: %v", err)
-			return http.StatusInternalServerError, err
-		}
-		err = cloudflaredCmd.Wait()
+func supportStartSessionHandler(tunnels *support.TunnelManager) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, _ *data) (int, error) {
+		status, err := tunnels.Start(r.Context())
 		if err != nil {
-			log.Printf("Error waiting for cloudflared to exit: %v", err)
+			log.Printf("Error starting support session: %v", err)
 			return http.StatusInternalServerError, err
 		}
-		log.Printf("cloudflared process stopped successfully.")
-	}
-
-	cloudflaredCmd = nil
-	cloudflaredPid = 0
-	cloudflaredStartTime = time.Time{}
-	cloudflaredProxyURL = ""
-	cloudflaredConnected = false
-	supportSessionCode = ""
-
-	return http.StatusNoContent, nil
-})
-
-var supportStartSessionHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	var cloudflared Cloudflared
-	if cloudflaredPid != 0 {
-		cloudflared = Cloudflared{ProxyURL: cloudflaredProxyURL, Pid: cloudflaredPid, TimeStarted: cloudflaredStartTime, Started: cloudflaredConnected, SessionCode: supportSessionCode}
-		return renderJSON(w, r, cloudflared)
-	}
 
-	cloudflaredPath := "/tmp/cloudflared"
-	err := downloadCloudflared(cloudflaredPath)
-	if err != nil {
-		log.Printf("Error downloading cloudflared: %v", err)
-		return http.StatusInternalServerError, err
-	}
-
-	port := d.server.Port
-	proxiedURL := "http://localhost:" + port
-
-	cloudflaredCmd = exec.Command(cloudflaredPath, "tunnel", "--url", proxiedURL)
-
-	urlRe := regexp.MustCompile(`INF\s+\|\s+(https:\/\/\S+)\s+\|$`)
-	connStartRe := regexp.MustCompile(`INF Registered tunnel connection`)
-
-	output, err := cloudflaredCmd.StderrPipe()
-	if err != nil {
-		log.Printf("Error getting stderr pipe: %v", err)
-		return http.StatusInternalServerError, err
-	}
-
-	err = cloudflaredCmd.Start()
-	if err != nil {
-		log.Printf("Error starting cloudflared: %v", err)
-		return http.StatusInternalServerError, err
-	}
-	cloudflaredPid = cloudflaredCmd.Process.Pid
+		return renderJSON(w, r, status)
+	})
+}
 
-	log.Printf("Starting cloudflared (pid: %d) with URL: %s", cloudflaredPid, proxiedURL)
+var supportSysinfoHandler = withUser(func(w http.ResponseWriter, r *http.Request, _ *data) (int, error) {
+	query := r.URL.Query()
 
-	scanner := bufio.NewScanner(output)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if matches := urlRe.FindStringSubmatch(line); matches != nil {
-			cloudflaredProxyURL = matches[1]
-		}
-		if matches := connStartRe.FindStringSubmatch(line); matches != nil {
-			break
-		}
+	opts := sysinfo.Options{Processes: query.Get("processes") != "false"}
+	if query.Get("script") == "true" {
+		opts.Script = sysinfoScript
 	}
 
-	cloudflaredStartTime = time.Now()
-	cloudflaredConnected = true
-	generateSupportSessionCode()
-
-	log.Printf("Cloudflared proxy URL: %s", cloudflaredProxyURL)
-	resp := Cloudflared{ProxyURL: cloudflaredProxyURL, Pid: cloudflaredPid, TimeStarted: cloudflaredStartTime, Started: cloudflaredConnected, SessionCode: supportSessionCode}
-	return renderJSON(w, r, resp)
-})
-
-var supportSysinfoHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-	cmd := exec.Command("/rcade/scripts/rcade-commands.sh", "sysinfo")
+	info := sysinfo.Collect(r.Context(), opts)
 
-	// Get combined stdout and stderr
-	output, err := cmd.CombinedOutput()
-	sysinfo := SystemInfo{ReturnCode: 0, Output: string(output)}
-
-	if err != nil {
-		// Check if the error is an ExitError to extract the exit code
-		if exitError, ok := err.(*exec.ExitError); ok {
-			// This works on Unix-like systems
-			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
-				sysinfo.ReturnCode = status.ExitStatus()
-				outputString := fmt.Sprintf("ERROR: %s\n\n%s", err, output)
-				sysinfo.Output = outputString
-			}
-		} else {
-			sysinfo.ReturnCode = 999
-			sysinfo.Output = fmt.Sprintf("Command error: %s", err)
+	switch query.Get("format") {
+	case "", "json":
+		return renderJSON(w, r, info)
+	case "prometheus":
+		w.Header().Set("Content-Type", metrics.ContentType)
+		if err := sysinfo.WritePrometheus(w, info); err != nil {
+			return http.StatusInternalServerError, err
 		}
+		return 0, nil
+	default:
+		return http.StatusBadRequest, nil
 	}
-
-	return renderJSON(w, r, sysinfo)
 })
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestSupportBundle(t *testing.T) {
+	logs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(logs, "app.log"), []byte("started\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, t.TempDir(), &users.User{Username: "player", Password: "pw", Scope: "."})
+	set, err := ts.storage.Settings.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Virtual = []settings.VirtualDir{{Name: "logs", Provider: settings.VirtualProviderDir, Path: logs}}
+	if err := ts.storage.Settings.Save(set); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/support?algo=nope", nil)
+	if status, _ := ts.serve(supportFileHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", status)
+	}
+
+	// The bundle is complete by the time it's sent.
+	req = httptest.NewRequest(http.MethodGet, "/api/support", nil)
+	status, body := ts.serve(supportFileHandler, "", req)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, f := range zr.File {
+		found[f.Name] = true
+	}
+	if !found["manifest.json"] || !found["virtual/logs/app.log"] {
+		t.Errorf("unexpected bundle entries %v", found)
+	}
+}
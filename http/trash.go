@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/trash"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// trashPruneInterval is how often the trash of every user is
+// checked against the age and size limits.
+const trashPruneInterval = time.Hour
+
+func trashBin(d *data) *trash.Bin {
+	return trash.NewForUser(d.user.Fs, d.user.ID)
+}
+
+// pruneTrash applies the limits of the settings to bin.
+func pruneTrash(bin *trash.Bin, set *settings.Settings) {
+	if _, err := bin.Prune(set.Trash.GetMaxAge(), int64(set.Trash.MaxSize)); err != nil { //nolint:gosec
+		log.Printf("Failed to prune trash: %v", err)
+	}
+}
+
+// pruneTrashes prunes the trash of every user now and then every
+// trashPruneInterval until ctx is done.
+func pruneTrashes(ctx context.Context, store *storage.Storage, root string) {
+	ticker := time.NewTicker(trashPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		set, err := store.Settings.Get()
+		if err == nil {
+			var list []*users.User
+			list, err = store.Users.Gets(root)
+			for _, u := range list {
+				pruneTrash(trash.NewForUser(u.Fs, u.ID), set)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to prune trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+var trashListHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if !d.user.Perm.Delete {
+		return http.StatusForbidden, nil
+	}
+
+	items, err := trashBin(d).List()
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	return renderJSON(w, r, items)
+})
+
+// trashDeleteHandler permanently removes a single item from the
+// trash or, without an id, empties it.
+var trashDeleteHandler = withUser(func(_ http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if !d.user.Perm.Delete {
+		return http.StatusForbidden, nil
+	}
+
+	var err error
+	if id := mux.Vars(r)["id"]; id != "" {
+		err = trashBin(d).Purge(id)
+	} else {
+		err = trashBin(d).Empty()
+	}
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	return http.StatusNoContent, nil
+})
+
+// trashRestoreHandler moves an item out of the trash, back to where
+// it was deleted from or to the path given by the destination query
+// parameter.
+func trashRestoreHandler(fileCache FileCache, hub *events.Hub, quotas *quota.Tracker) handleFunc {
+	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+		if !d.user.Perm.Delete || !d.user.Perm.Create {
+			return http.StatusForbidden, nil
+		}
+
+		bin := trashBin(d)
+		item, err := bin.Get(mux.Vars(r)["id"])
+		if err != nil {
+			return errToStatus(err), err
+		}
+
+		dst := r.URL.Query().Get("destination")
+		if dst == "" {
+			dst = item.Path
+		}
+		// Restoring never overwrites, so it only creates dst.
+		if !d.CheckAction(path.Clean("/"+dst), rules.Create) {
+			return http.StatusForbidden, nil
+		}
+
+		// The trash is in the scope of the user and counts in its quota,
+		// so restoring takes no more room than the item already does,
+		// and users over their quota can still get their files back.
+		item, err = bin.Restore(item.ID, dst)
+		invalidateUsage(quotas, d)
+		if err != nil {
+			return errToStatus(err), err
+		}
+
+		// Previews were dropped when the file was deleted; make sure
+		// none are left for its new path either, like after a rename.
+		file, err := files.NewFileInfo(&files.FileOptions{
+			Fs:      d.user.Fs,
+			Path:    item.Path,
+			Modify:  d.user.Perm.Modify,
+			Checker: d,
+		})
+		if err != nil {
+			return errToStatus(err), err
+		}
+		if err := delThumbs(r.Context(), fileCache, file); err != nil {
+			return errToStatus(err), err
+		}
+
+		publishEvent(hub, d, events.Create, item.Path, "", item.IsDir)
+		return renderJSON(w, r, item)
+	})
+}
@@ -11,10 +11,14 @@ import (
 
 	"github.com/spf13/afero"
 
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/events"
 	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/quota"
+	"github.com/filebrowser/filebrowser/v2/rules"
 )
 
-func tusPostHandler() handleFunc {
+func tusPostHandler(hub *events.Hub, quotas *quota.Tracker) handleFunc {
 	return withUser(func(_ http.ResponseWriter, r *http.Request, d *data) (int, error) {
 		file, err := files.NewFileInfo(&files.FileOptions{
 			Fs:         d.user.Fs,
@@ -26,7 +30,7 @@ func tusPostHandler() handleFunc {
 		})
 		switch {
 		case errors.Is(err, afero.ErrFileNotFound):
-			if !d.user.Perm.Create || !d.Check(r.URL.Path) {
+			if !d.user.Perm.Create || !d.CheckAction(r.URL.Path, rules.Create) {
 				return http.StatusForbidden, nil
 			}
 
@@ -41,15 +45,29 @@ func tusPostHandler() handleFunc {
 		}
 
 		fileFlags := os.O_CREATE | os.O_WRONLY
-		if r.URL.Query().Get("override") == "true" {
+		override := r.URL.Query().Get("override") == "true"
+		if override {
 			fileFlags |= os.O_TRUNC
 		}
 
 		// if file exists
+		var oldSize, newFiles int64 = 0, 1
 		if file != nil {
 			if file.IsDir {
 				return http.StatusBadRequest, fmt.Errorf("cannot upload to a directory %s", file.RealPath())
 			}
+			if !d.CheckAction(r.URL.Path, rules.Modify) {
+				return http.StatusForbidden, nil
+			}
+			oldSize, newFiles = file.Size, 0
+		}
+
+		uploadLength, err := getUploadLength(r)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if _, err = checkQuota(quotas, d, uploadLength-oldSize, newFiles); err != nil {
+			return errToStatus(err), err
 		}
 
 		openFile, err := d.user.Fs.OpenFile(r.URL.Path, fileFlags, files.PermFile)
@@ -60,6 +78,17 @@ func tusPostHandler() handleFunc {
 			return errToStatus(err), err
 		}
 
+		if file == nil {
+			addUsage(quotas, d, 0, 1)
+			publishEvent(hub, d, events.Create, r.URL.Path, "", false)
+		} else if override {
+			addUsage(quotas, d, -oldSize, 0)
+			oldSize = 0
+		}
+		// The upload is written in parts; reserve what's left of it
+		// right away so that concurrent uploads can't all fit.
+		reserveUpload(quotas, d, r.URL.Path, uploadLength-oldSize)
+
 		return http.StatusCreated, nil
 	})
 }
@@ -90,9 +119,16 @@ func tusHeadHandler() handleFunc {
 	})
 }
 
-func tusPatchHandler() handleFunc {
+func tusPatchHandler(hub *events.Hub, quotas *quota.Tracker) handleFunc {
 	return withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
-		if !d.user.Perm.Modify || !d.Check(r.URL.Path) {
+		// Uploads started by the POST handler are created empty, so
+		// writing them is creating them. Appending to anything else is
+		// modifying it.
+		action := rules.Modify
+		if uploadReserved(quotas, d, r.URL.Path) > 0 {
+			action = rules.Create
+		}
+		if !d.user.Perm.Modify || !d.CheckAction(r.URL.Path, action) {
 			return http.StatusForbidden, nil
 		}
 		if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
@@ -142,13 +178,33 @@ func tusPatchHandler() handleFunc {
 			return http.StatusInternalServerError, fmt.Errorf("could not seek file: %w", err)
 		}
 
+		// What was reserved for the upload is already counted as used.
+		reserved := uploadReserved(quotas, d, r.URL.Path)
+		room, err := checkQuota(quotas, d, max(r.ContentLength-reserved, 0), 0)
+		if err != nil {
+			releaseUpload(quotas, d, r.URL.Path)
+			return errToStatus(err), err
+		}
+		if room >= 0 {
+			room += reserved
+		}
+
 		defer r.Body.Close()
-		bytesWritten, err := io.Copy(openFile, r.Body)
+		bytesWritten, err := io.Copy(openFile, quotaReader(r.Body, room))
+		uploadedBytes.Add(float64(bytesWritten))
+		useUpload(quotas, d, r.URL.Path, bytesWritten)
 		if err != nil {
+			releaseUpload(quotas, d, r.URL.Path)
+		}
+		if errors.Is(err, fbErrors.ErrQuotaExceeded) {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(uploadOffset+bytesWritten, 10))
+			return errToStatus(err), err
+		} else if err != nil {
 			return http.StatusInternalServerError, fmt.Errorf("could not write to file: %w", err)
 		}
 
 		w.Header().Set("Upload-Offset", strconv.FormatInt(uploadOffset+bytesWritten, 10))
+		publishEvent(hub, d, events.Modify, r.URL.Path, "", false)
 
 		return http.StatusNoContent, nil
 	})
@@ -161,3 +217,17 @@ func getUploadOffset(r *http.Request) (int64, error) {
 	}
 	return uploadOffset, nil
 }
+
+// getUploadLength returns the size of the upload, or 0 if the client
+// deferred it.
+func getUploadLength(r *http.Request) (int64, error) {
+	header := r.Header.Get("Upload-Length")
+	if header == "" {
+		return 0, nil
+	}
+	uploadLength, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || uploadLength < 0 {
+		return 0, fmt.Errorf("invalid upload length %q", header)
+	}
+	return uploadLength, nil
+}
@@ -13,11 +13,12 @@ import (
 	"golang.org/x/text/language"
 
 	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/groups"
 	"github.com/filebrowser/filebrowser/v2/users"
 )
 
 var (
-	NonModifiableFieldsForNonAdmin = []string{"Username", "Scope", "LockPassword", "Perm", "Commands", "Rules"}
+	NonModifiableFieldsForNonAdmin = []string{"Username", "Scope", "LockPassword", "Perm", "Commands", "Rules", "Groups", "Quota"}
 )
 
 type modifyUserRequest struct {
@@ -130,6 +131,15 @@ var userPostHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *
 		return http.StatusInternalServerError, err
 	}
 
+	// Users created without a scope get the one of their groups.
+	if req.Data.Scope == "" {
+		list, err := d.store.Groups.Of(req.Data) //nolint:govet
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		req.Data.Scope = groups.Scope(list)
+	}
+
 	userHome, err := d.settings.MakeUserDir(req.Data.Username, req.Data.Scope, d.server.Root)
 	if err != nil {
 		log.Printf("create user: failed to mkdir user home dir: [%s]", userHome)
@@ -139,7 +149,9 @@ var userPostHandler = withAdmin(func(w http.ResponseWriter, r *http.Request, d *
 	log.Printf("user: %s, home dir: [%s].", req.Data.Username, userHome)
 
 	err = d.store.Users.Save(req.Data)
-	if err != nil {
+	if errors.Is(err, fbErrors.ErrInvalidOption) {
+		return http.StatusBadRequest, err
+	} else if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
@@ -200,7 +212,9 @@ var userPutHandler = withSelfOrAdmin(func(w http.ResponseWriter, r *http.Request
 	}
 
 	err = d.store.Users.Update(req.Data, req.Which...)
-	if err != nil {
+	if errors.Is(err, fbErrors.ErrInvalidOption) {
+		return http.StatusBadRequest, err
+	} else if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
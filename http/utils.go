@@ -42,6 +42,10 @@ func errToStatus(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, libErrors.ErrRootUserDeletion):
 		return http.StatusForbidden
+	case errors.Is(err, libErrors.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, libErrors.ErrOtherDevice):
+		return http.StatusUnprocessableEntity
 	default:
 		return http.StatusInternalServerError
 	}
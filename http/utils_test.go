@@ -0,0 +1,76 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/storage"
+	"github.com/filebrowser/filebrowser/v2/storage/bolt"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// testServer serves handlers to a single logged in user.
+type testServer struct {
+	storage *storage.Storage
+	server  *settings.Server
+	user    *users.User
+	token   string
+}
+
+func newTestServer(t *testing.T, root string, user *users.User) *testServer {
+	t.Helper()
+
+	db, err := storm.Open(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := bolt.NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to get storage: %v", err)
+	}
+	if err := store.Users.Save(user); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	if err := store.Settings.Save(&settings.Settings{Key: []byte("key")}); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &authToken{
+		User: userInfo{ID: user.ID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		storage: store,
+		server:  &settings.Server{Root: root},
+		user:    user,
+		token:   token,
+	}
+}
+
+// serve runs fn, with prefix stripped from the path, for the user
+// and returns the status and body of the response.
+func (s *testServer) serve(fn handleFunc, prefix string, r *http.Request) (int, []byte) {
+	r.Header.Set("X-Auth", s.token)
+	recorder := httptest.NewRecorder()
+	handle(fn, prefix, s.storage, s.server).ServeHTTP(recorder, r)
+	result := recorder.Result()
+	defer result.Body.Close()
+	body, _ := io.ReadAll(result.Body)
+	return result.StatusCode, body
+}
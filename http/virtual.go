@@ -0,0 +1,278 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/img"
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/virtual"
+)
+
+// registries holds the registry of the virtual directories, built once
+// and again only when their settings change.
+var registries virtualRegistries
+
+type virtualRegistries struct {
+	mu       sync.Mutex
+	dirs     []settings.VirtualDir
+	registry *virtual.Registry
+}
+
+// virtualRegistry returns the registry of the virtual directories in
+// the settings of d.
+func virtualRegistry(d *data) (*virtual.Registry, error) {
+	return registries.get(d.settings.Virtual)
+}
+
+func (c *virtualRegistries) get(dirs []settings.VirtualDir) (*virtual.Registry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.registry != nil && reflect.DeepEqual(c.dirs, dirs) {
+		return c.registry, nil
+	}
+
+	registry, err := virtual.NewRegistry(dirs)
+	if err != nil {
+		return nil, err
+	}
+	c.dirs, c.registry = dirs, registry
+	return registry, nil
+}
+
+var resourceVirtualGetHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	registry, err := virtualRegistry(d)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	file, err := registry.Stat(r.URL.Path)
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	if file.IsDir {
+		file.Listing.Sorting = d.user.Sorting
+		file.Listing.ApplySort()
+		return renderJSON(w, r, file)
+	}
+
+	if r.URL.Query().Get("follow") == "true" {
+		return followVirtualFile(w, r, registry)
+	}
+
+	if file.Type == "text" {
+		rng, err := parseContentRange(r)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		// Logs can grow without bounds: unless asked
+		// otherwise, only send the end of big ones.
+		if rng == nil {
+			rng = &files.Range{}
+			if file.Size > files.MaxWindowSize {
+				rng.Offset = file.Size - files.MaxWindowSize
+			}
+		}
+
+		fd, err := registry.Open(r.URL.Path)
+		if err != nil {
+			return errToStatus(err), err
+		}
+		defer fd.Close()
+
+		file.Content, file.Window, err = files.ReadWindow(fd, *rng)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		file.Size = file.Window.TotalSize
+	}
+
+	return renderJSON(w, r, file)
+})
+
+var rawVirtualHandler = withUser(func(w http.ResponseWriter, r *http.Request, d *data) (int, error) {
+	if !d.user.Perm.Download {
+		return http.StatusAccepted, nil
+	}
+
+	registry, err := virtualRegistry(d)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	file, err := registry.Stat(r.URL.Path)
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	if file.IsDir {
+		return http.StatusBadRequest, nil
+	}
+
+	fd, err := registry.Open(r.URL.Path)
+	if err != nil {
+		return errToStatus(err), err
+	}
+	defer fd.Close()
+
+	return serveVirtualFile(w, r, file, fd)
+})
+
+func serveVirtualFile(w http.ResponseWriter, r *http.Request, file *files.FileInfo, fd io.Reader) (int, error) {
+	setContentDisposition(w, r, file)
+	w.Header().Add("Content-Security-Policy", `script-src 'none';`)
+	w.Header().Set("Cache-Control", "private")
+
+	if rs, ok := fd.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, file.Name, file.ModTime, rs)
+		return 0, nil
+	}
+
+	if _, err := io.Copy(w, fd); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return 0, nil
+}
+
+// previewVirtualFile previews the image at the path p, relative to
+// virtual.Root. Previews of virtual files aren't cached since their
+// providers can change them at any time.
+func previewVirtualFile(
+	w http.ResponseWriter,
+	r *http.Request,
+	d *data,
+	imgSvc ImgService,
+	p string,
+	previewSize PreviewSize,
+	enableThumbnails, resizePreview bool,
+) (int, error) {
+	registry, err := virtualRegistry(d)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	file, err := registry.Stat(p)
+	if err != nil {
+		return errToStatus(err), err
+	}
+	if file.Type != "image" {
+		return http.StatusNotImplemented, fmt.Errorf("can't create preview for %s type", file.Type)
+	}
+
+	fd, err := registry.Open(p)
+	if err != nil {
+		return errToStatus(err), err
+	}
+	defer fd.Close()
+
+	if (previewSize == PreviewSizeBig && !resizePreview) ||
+		(previewSize == PreviewSizeThumb && !enableThumbnails) {
+		return serveVirtualFile(w, r, file, fd)
+	}
+
+	format, err := imgSvc.FormatFromExtension(file.Extension)
+	if errors.Is(err, img.ErrUnsupportedFormat) || format == img.FormatGif {
+		return serveVirtualFile(w, r, file, fd)
+	}
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	resizedImage, err := resizeImage(imgSvc, fd, previewSize)
+	if err != nil {
+		return errToStatus(err), err
+	}
+
+	setContentDisposition(w, r, file)
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, file.Name, file.ModTime, bytes.NewReader(resizedImage))
+	return 0, nil
+}
+
+// followVirtualFile streams the last lines of a virtual file backed by
+// a file on disk over a WebSocket, followed by every appended line.
+func followVirtualFile(w http.ResponseWriter, r *http.Request, registry *virtual.Registry) (int, error) {
+	realPath, err := registry.RealPath(r.URL.Path)
+	if errors.Is(err, fbErrors.ErrInvalidOption) {
+		return http.StatusBadRequest, err
+	} else if err != nil {
+		return errToStatus(err), err
+	}
+
+	opts, err := parseTailOptions(r)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// The client never sends anything: reading is only
+	// used to notice when it goes away.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = virtual.Follow(ctx, realPath, opts, func(line string) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(WSWriteDeadline)); err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.TextMessage, []byte(line))
+	})
+	if err != nil && ctx.Err() == nil {
+		wsErr(conn, r, http.StatusInternalServerError, err)
+	}
+
+	return 0, nil
+}
+
+func parseTailOptions(r *http.Request) (virtual.TailOptions, error) {
+	opts := virtual.TailOptions{Lines: virtual.DefaultTailLines}
+
+	if lines := r.URL.Query().Get("lines"); lines != "" {
+		n, err := strconv.Atoi(lines)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid lines %q: %w", lines, fbErrors.ErrInvalidRequestParams)
+		}
+		opts.Lines = min(n, virtual.MaxTailLines)
+	}
+
+	if grep := r.URL.Query().Get("grep"); grep != "" {
+		filter, err := regexp.Compile(grep)
+		if err != nil {
+			return opts, fmt.Errorf("invalid grep expression: %w", err)
+		}
+		opts.Filter = filter
+	}
+
+	return opts, nil
+}
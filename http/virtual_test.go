@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestVirtualPreview(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"shot.png": "png", "app.log": "log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The scope holds a real file at the same path, which must not be
+	// the one previewed.
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "virtual/shots"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "virtual/shots/shot.png"), []byte("real"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, root, &users.User{
+		Username: "player",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Download: true},
+	})
+	set, err := ts.storage.Settings.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Virtual = []settings.VirtualDir{{Name: "shots", Provider: settings.VirtualProviderDir, Path: dir}}
+	if err := ts.storage.Settings.Save(set); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := previewHandler(nil, nil, false, false)
+	for p, want := range map[string]int{
+		"virtual/shots/shot.png": http.StatusOK,
+		"virtual/shots/app.log":  http.StatusNotImplemented,
+		"virtual/shots/nope.png": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/preview/thumb/"+p, nil)
+		req = mux.SetURLVars(req, map[string]string{"size": "thumb", "path": p})
+		status, body := ts.serve(handler, "/api/preview", req)
+		if status != want {
+			t.Errorf("%s: expected %d, got %d: %s", p, want, status, body)
+		}
+		if want == http.StatusOK && string(body) != "png" {
+			t.Errorf("%s: expected the virtual file, got %q", p, body)
+		}
+	}
+}
+
+func TestSettingsVirtualValidation(t *testing.T) {
+	ts := newTestServer(t, t.TempDir(), &users.User{
+		Username: "admin",
+		Password: "pw",
+		Scope:    ".",
+		Perm:     users.Permissions{Admin: true},
+	})
+
+	body := `{"virtual": [{"name": "logs", "provider": "nope"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(body))
+	if status, body := ts.serve(settingsPutHandler, "", req); status != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", status, body)
+	}
+
+	set, err := ts.storage.Settings.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range set.Virtual {
+		if dir.Provider == "nope" {
+			t.Errorf("expected the bad provider not to be saved, got %+v", set.Virtual)
+		}
+	}
+}
+
+func TestVirtualRegistryCache(t *testing.T) {
+	var c virtualRegistries
+	dirs := []settings.VirtualDir{{Name: "logs", Provider: settings.VirtualProviderDir, Path: t.TempDir()}}
+
+	first, err := c.get(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := c.get([]settings.VirtualDir{dirs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != first {
+		t.Error("expected the registry to be reused while the settings don't change")
+	}
+
+	changed, err := c.get(append(dirs, settings.VirtualDir{Name: "shots", Provider: settings.VirtualProviderDir, Path: t.TempDir()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == first {
+		t.Error("expected the registry to be rebuilt when the settings change")
+	}
+	if _, err := c.get([]settings.VirtualDir{{Name: "bad", Provider: "nope"}}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
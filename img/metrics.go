@@ -0,0 +1,14 @@
+package img
+
+import "github.com/filebrowser/filebrowser/v2/metrics"
+
+var (
+	resizeWorkers = metrics.Default.NewGauge("filebrowser_image_resize_workers",
+		"Number of image resizes allowed to run at once.")
+	resizeActive = metrics.Default.NewGauge("filebrowser_image_resize_active",
+		"Number of image resizes running.")
+	resizeWaiting = metrics.Default.NewGauge("filebrowser_image_resize_waiting",
+		"Number of image resizes waiting for a free worker.")
+	resizeWait = metrics.Default.NewHistogram("filebrowser_image_resize_wait_seconds",
+		"Time image resizes waited for a free worker.", nil)
+)
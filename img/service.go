@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/dsoprea/go-exif/v3"
@@ -25,6 +26,7 @@ type Service struct {
 }
 
 func New(workers int) *Service {
+	resizeWorkers.Add(float64(workers))
 	return &Service{
 		sem: semaphore.New(workers),
 	}
@@ -136,9 +138,17 @@ func WithQuality(quality Quality) Option {
 }
 
 func (s *Service) Resize(ctx context.Context, in io.Reader, width, height int, out io.Writer, options ...Option) error {
-	if err := s.sem.Acquire(ctx, 1); err != nil {
+	resizeWaiting.Add(1)
+	start := time.Now()
+	err := s.sem.Acquire(ctx, 1)
+	resizeWaiting.Add(-1)
+	if err != nil {
 		return err
 	}
+	resizeWait.Observe(time.Since(start).Seconds())
+
+	resizeActive.Add(1)
+	defer resizeActive.Add(-1)
 	defer s.sem.Release(1)
 
 	format, wrappedReader, err := s.detectFormat(in)
@@ -0,0 +1,331 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/manifest"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/trash"
+)
+
+// saveInterval is how often the progress of a running job is saved.
+var saveInterval = time.Second
+
+const copyBufferSize = 256 * 1024
+
+// tracker updates the progress of a job as its operation advances.
+type tracker struct {
+	job      *Job
+	save     func(*Job)
+	start    time.Time
+	skipped  int64
+	lastSave time.Time
+}
+
+func newTracker(j *Job, save func(*Job)) *tracker {
+	j.FilesDone, j.BytesDone, j.ETA = 0, 0, 0
+	return &tracker{job: j, save: save, start: time.Now(), lastSave: time.Now()}
+}
+
+func (t *tracker) setTotals(files int, bytes int64) {
+	t.job.FilesTotal, t.job.BytesTotal = files, bytes
+	t.update(true)
+}
+
+// skip accounts for bytes an earlier attempt already copied.
+func (t *tracker) skip(n int64) {
+	t.skipped += n
+	t.job.BytesDone += n
+}
+
+// Add implements archive.Progress.
+func (t *tracker) Add(n int64) {
+	t.job.BytesDone += n
+	t.update(false)
+}
+
+// FileDone implements archive.Progress.
+func (t *tracker) FileDone() {
+	t.job.FilesDone++
+	t.update(false)
+}
+
+func (t *tracker) update(force bool) {
+	elapsed := time.Since(t.start).Seconds()
+	copied := t.job.BytesDone - t.skipped
+	if elapsed > 0 && copied > 0 {
+		rate := float64(copied) / elapsed
+		t.job.ETA = float64(t.job.BytesTotal-t.job.BytesDone) / rate
+	}
+
+	if force || time.Since(t.lastSave) >= saveInterval {
+		t.lastSave = time.Now()
+		t.save(t.job)
+	}
+}
+
+// progressReader reports the bytes read through it and stops once
+// its context is done.
+type progressReader struct {
+	ctx context.Context
+	r   io.Reader
+	t   *tracker
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	p.t.Add(int64(n))
+	return n, err
+}
+
+// execute performs the operation of the job on fs.
+func execute(ctx context.Context, fs afero.Fs, j *Job, checker rules.ActionChecker, t *tracker) error {
+	resume := j.Attempts > 1
+
+	switch j.Action {
+	case Copy:
+		return copyTree(ctx, fs, j.Src, j.Dst, resume, t)
+	case Move:
+		return move(ctx, fs, j.Src, j.Dst, resume, t)
+	case Delete:
+		return remove(fs, j, resume, t)
+	case Extract:
+		return extract(ctx, fs, j, checker, resume, t)
+	case Compress:
+		return compress(ctx, fs, j, rules.ForAction(checker, rules.Read), t)
+	case Manifest:
+		return generateManifest(ctx, fs, j, rules.ForAction(checker, rules.Read), t)
+	case Verify:
+		return verifyManifest(ctx, fs, j, rules.ForAction(checker, rules.Read), t)
+	default:
+		return fmt.Errorf("unsupported action %s: %w", j.Action, fbErrors.ErrInvalidRequestParams)
+	}
+}
+
+// measure returns the number of files in src and their size.
+func measure(fs afero.Fs, src string) (count int, size int64, err error) {
+	err = afero.Walk(fs, src, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			count++
+			size += info.Size()
+		}
+		return nil
+	})
+	return count, size, err
+}
+
+// copyTree copies the file or directory src to dst. When resuming,
+// files already copied are skipped and partial ones are completed.
+func copyTree(ctx context.Context, fs afero.Fs, src, dst string, resume bool, t *tracker) error {
+	count, size, err := measure(fs, src)
+	if err != nil {
+		return err
+	}
+	t.setTotals(count, size)
+
+	buf := make([]byte, copyBufferSize)
+	return afero.Walk(fs, src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		target := dst + strings.TrimPrefix(p, src)
+		if info.IsDir() {
+			return fs.MkdirAll(target, info.Mode())
+		}
+		return copyFile(ctx, fs, p, target, info, resume, buf, t)
+	})
+}
+
+func copyFile(ctx context.Context, fs afero.Fs, src, dst string, info os.FileInfo, resume bool, buf []byte, t *tracker) error {
+	var offset int64
+	if resume {
+		if existing, err := fs.Stat(dst); err == nil && !existing.IsDir() && existing.Size() <= info.Size() {
+			offset = existing.Size()
+		}
+	}
+	if resume && offset == info.Size() {
+		t.skip(offset)
+		t.FileDone()
+		return nil
+	}
+
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		if _, err := in.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+		t.skip(offset)
+	}
+
+	out, err := fs.OpenFile(dst, flags, files.PermFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyBuffer(out, &progressReader{ctx: ctx, r: in, t: t}, buf)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fs.Chmod(dst, info.Mode()); err != nil {
+		return err
+	}
+
+	t.FileDone()
+	return nil
+}
+
+// move renames src to dst, falling back to copying and removing
+// src when they're on different volumes.
+func move(ctx context.Context, fs afero.Fs, src, dst string, resume bool, t *tracker) error {
+	if fs.Rename(src, dst) == nil {
+		count, size, _ := measure(fs, dst)
+		t.setTotals(count, size)
+		t.job.FilesDone, t.job.BytesDone = count, size
+		return nil
+	}
+
+	if resume && !exists(fs, src) && exists(fs, dst) {
+		// An earlier attempt got as far as removing the source.
+		return nil
+	}
+
+	if err := copyTree(ctx, fs, src, dst, resume, t); err != nil {
+		return err
+	}
+	return fs.RemoveAll(src)
+}
+
+// remove moves src into the trash of the job's user.
+func remove(fs afero.Fs, j *Job, resume bool, t *tracker) error {
+	if resume && !exists(fs, j.Src) {
+		return nil
+	}
+
+	count, size, err := measure(fs, j.Src)
+	if err != nil {
+		return err
+	}
+	t.setTotals(count, size)
+
+	if _, err := trash.NewForUser(fs, j.UserID).Move(j.Src); err != nil {
+		return err
+	}
+	t.job.FilesDone, t.job.BytesDone = count, size
+	return nil
+}
+
+// extract extracts the archive src into dst. Resumed extractions
+// overwrite the files written by the earlier attempt.
+func extract(ctx context.Context, fs afero.Fs, j *Job, checker rules.ActionChecker, resume bool, t *tracker) error {
+	count, size, err := archive.Measure(fs, j.Src)
+	if err != nil {
+		return err
+	}
+	t.setTotals(count, size)
+
+	return archive.Extract(ctx, fs, j.Src, j.Dst, archive.ExtractOptions{
+		Checker:  checker,
+		Override: j.Override || resume,
+		Progress: t,
+	})
+}
+
+// compress archives the sources of the job into dst. Resumed
+// compressions start over since archives can't be appended to.
+func compress(ctx context.Context, fs afero.Fs, j *Job, checker rules.Checker, t *tracker) error {
+	if j.Archive == nil {
+		return fmt.Errorf("compression without archive options: %w", fbErrors.ErrInvalidRequestParams)
+	}
+
+	var count int
+	var size int64
+	for _, src := range j.Sources {
+		c, s, err := measure(fs, src)
+		if err != nil {
+			return err
+		}
+		count, size = count+c, size+s
+	}
+	t.setTotals(count, size)
+
+	opts := *j.Archive
+	opts.Checker = checker
+	opts.Progress = t
+	return archive.Create(ctx, fs, j.Sources, j.Dst, opts)
+}
+
+// generateManifest writes the checksums of the files in src to dst.
+// Resumed generations start over.
+func generateManifest(ctx context.Context, fs afero.Fs, j *Job, checker rules.Checker, t *tracker) error {
+	count, size, err := measure(fs, j.Src)
+	if err != nil {
+		return err
+	}
+	t.setTotals(count, size)
+
+	_, err = manifest.Generate(ctx, fs, j.Algorithm, j.Src, j.Dst, manifest.Options{
+		Checker:  checker,
+		Progress: t,
+	})
+	return err
+}
+
+// verifyManifest checks the files listed in the manifest src and
+// keeps the report in the job.
+func verifyManifest(ctx context.Context, fs afero.Fs, j *Job, checker rules.Checker, t *tracker) error {
+	m, err := manifest.Load(fs, j.Src)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, e := range m.Entries {
+		if info, err := fs.Stat(path.Join(m.Dir(), e.Path)); err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+	}
+	t.setTotals(len(m.Entries), size)
+
+	j.Verification, err = manifest.Verify(ctx, fs, m, manifest.Options{
+		Checker:  checker,
+		Progress: t,
+	})
+	return err
+}
+
+func exists(fs afero.Fs, p string) bool {
+	_, err := fs.Stat(p)
+	return err == nil
+}
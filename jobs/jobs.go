@@ -0,0 +1,85 @@
+// Package jobs runs long file operations, such as copying a whole
+// directory to a slow USB stick, in the background. Jobs are kept in
+// the database so their progress survives the request that started
+// them and interrupted jobs are resumed after a restart.
+package jobs
+
+import (
+	"time"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	"github.com/filebrowser/filebrowser/v2/manifest"
+)
+
+// Action is the file operation a job performs. Actions are named
+// after the hook events they trigger.
+type Action string
+
+const (
+	Copy     Action = "copy"
+	Move     Action = "rename"
+	Delete   Action = "delete"
+	Extract  Action = "extract"
+	Compress Action = "compress"
+	Manifest Action = "manifest"
+	Verify   Action = "verify"
+)
+
+// State is the state of a job.
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Done      State = "done"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+// Finished reports whether a job in this state won't run again
+// unless it's resumed.
+func (s State) Finished() bool {
+	return s == Done || s == Failed || s == Cancelled
+}
+
+// Job is a queued, running or finished file operation. Paths are
+// relative to the scope of the user the job belongs to.
+type Job struct {
+	ID       uint   `storm:"id,increment" json:"id"`
+	UserID   uint   `storm:"index" json:"userId"`
+	Action   Action `json:"action"`
+	Src      string `json:"src"`
+	Dst      string `json:"dst,omitempty"`
+	IsDir    bool   `json:"isDir"`
+	Override bool   `json:"override"`
+	State    State  `storm:"index" json:"state"`
+	Error    string `json:"error,omitempty"`
+	// Attempts counts the times the job was started. Later attempts
+	// skip the files earlier ones already copied.
+	Attempts   int       `json:"attempts"`
+	FilesTotal int       `json:"filesTotal"`
+	FilesDone  int       `json:"filesDone"`
+	BytesTotal int64     `json:"bytesTotal"`
+	BytesDone  int64     `json:"bytesDone"`
+	ETA        float64   `json:"eta"` // seconds, 0 when unknown
+	Created    time.Time `json:"created"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	// Sources are the files a compression archives into Dst. Src is
+	// their parent directory.
+	Sources []string `json:"sources,omitempty"`
+	// Archive configures the archive a compression creates.
+	Archive *archive.CreateOptions `json:"archive,omitempty"`
+	// Algorithm is the checksum of the manifest a manifest job writes
+	// to Dst for the directory Src.
+	Algorithm manifest.Algorithm `json:"algorithm,omitempty"`
+	// Verification is the report of a finished verification of the
+	// manifest Src.
+	Verification *manifest.Report `json:"verification,omitempty"`
+}
+
+// Filter selects jobs. Zero fields match every job.
+type Filter struct {
+	UserID uint
+	States []State
+}
@@ -0,0 +1,317 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+// DefaultWorkers is the number of jobs run at the same time when
+// Config.Workers isn't set.
+const DefaultWorkers = 2
+
+// DefaultMaxFinished is the number of finished jobs kept for each user
+// when Config.MaxFinished isn't set.
+const DefaultMaxFinished = 50
+
+// Config configures a Manager.
+type Config struct {
+	Workers int
+	// MaxFinished is the number of finished jobs kept for each user.
+	// Older ones are deleted when another one finishes.
+	MaxFinished int
+	// User returns the user with the given id, with its filesystem.
+	User func(id uint) (*users.User, error)
+	// Checker, if set, returns the rules deciding which paths the
+	// jobs of user can access, and what they can do to them. Only
+	// extractions, compressions and manifest generations and
+	// verifications consult it.
+	Checker func(user *users.User) (rules.ActionChecker, error)
+	// Authorize, if set, checks that user can still do j before each
+	// of its attempts, since its rules and permissions can change
+	// while the job is queued or before it's resumed.
+	Authorize func(j *Job, user *users.User) error
+	// Hook runs fn, the operation of the job, between the before and
+	// after hooks of its action. Without it fn is run on its own.
+	Hook func(j *Job, user *users.User, fn func() error) error
+	// Finished, if set, is called after a job stopped running.
+	Finished func(j *Job, user *users.User)
+}
+
+// Manager queues jobs and runs them in the background.
+type Manager struct {
+	store *Storage
+	cfg   Config
+
+	mu      sync.Mutex
+	pending []uint
+	running map[uint]context.CancelFunc
+	wake    chan struct{}
+}
+
+// NewManager creates a manager keeping its jobs in store.
+func NewManager(store *Storage, cfg Config) *Manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.MaxFinished <= 0 {
+		cfg.MaxFinished = DefaultMaxFinished
+	}
+
+	return &Manager{
+		store:   store,
+		cfg:     cfg,
+		running: map[uint]context.CancelFunc{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Start queues the jobs a previous run of the server didn't finish
+// and starts the workers, which stop when ctx is done. Jobs stopped
+// that way are resumed by the next Start.
+func (m *Manager) Start(ctx context.Context) error {
+	interrupted, err := m.store.Find(Filter{States: []State{Queued, Running}})
+	if err != nil {
+		return err
+	}
+
+	// Oldest first.
+	for i := len(interrupted) - 1; i >= 0; i-- {
+		j := interrupted[i]
+		if j.State == Running {
+			j.State = Queued
+			if err := m.store.Save(j); err != nil {
+				return err
+			}
+		}
+		m.push(j.ID)
+	}
+
+	for i := 0; i < m.cfg.Workers; i++ {
+		go m.work(ctx)
+	}
+	return nil
+}
+
+// Enqueue saves a new job and queues it.
+func (m *Manager) Enqueue(j *Job) error {
+	j.State = Queued
+	if err := m.store.Save(j); err != nil {
+		return err
+	}
+
+	m.push(j.ID)
+	return nil
+}
+
+// Cancel stops a queued or running job. Finished jobs are left
+// untouched.
+func (m *Manager) Cancel(id uint) error {
+	m.mu.Lock()
+	if cancel, ok := m.running[id]; ok {
+		m.mu.Unlock()
+		cancel()
+		return nil
+	}
+
+	queued := false
+	for i, pending := range m.pending {
+		if pending == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			queued = true
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	j, err := m.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !queued || j.State.Finished() {
+		return nil
+	}
+
+	j.State = Cancelled
+	j.Finished = time.Now()
+	return m.store.Save(j)
+}
+
+// Resume queues a failed or cancelled job again. It continues where
+// it stopped instead of starting over.
+func (m *Manager) Resume(id uint) error {
+	j, err := m.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if j.State != Failed && j.State != Cancelled {
+		return fbErrors.ErrInvalidRequestParams
+	}
+
+	j.State = Queued
+	j.Error = ""
+	j.Finished = time.Time{}
+	if err := m.store.Save(j); err != nil {
+		return err
+	}
+
+	m.push(j.ID)
+	return nil
+}
+
+// Remove deletes a finished job.
+func (m *Manager) Remove(id uint) error {
+	j, err := m.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !j.State.Finished() {
+		return fbErrors.ErrInvalidRequestParams
+	}
+
+	return m.store.Delete(id)
+}
+
+func (m *Manager) push(id uint) {
+	m.mu.Lock()
+	m.pending = append(m.pending, id)
+	m.mu.Unlock()
+	m.signal()
+}
+
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for a queued job and marks it as running.
+func (m *Manager) next(ctx context.Context) (uint, context.Context, bool) {
+	for {
+		m.mu.Lock()
+		if len(m.pending) > 0 {
+			id := m.pending[0]
+			m.pending = m.pending[1:]
+			jobCtx, cancel := context.WithCancel(ctx)
+			m.running[id] = cancel
+			more := len(m.pending) > 0
+			m.mu.Unlock()
+
+			if more {
+				m.signal()
+			}
+			return id, jobCtx, true
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, false
+		case <-m.wake:
+		}
+	}
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		id, jobCtx, ok := m.next(ctx)
+		if !ok {
+			return
+		}
+
+		m.run(ctx, jobCtx, id)
+
+		m.mu.Lock()
+		m.running[id]()
+		delete(m.running, id)
+		m.mu.Unlock()
+	}
+}
+
+func (m *Manager) save(j *Job) {
+	if err := m.store.Save(j); err != nil {
+		log.Printf("Failed to save job %d: %v", j.ID, err)
+	}
+}
+
+// prune deletes the oldest finished jobs of a user past
+// Config.MaxFinished.
+func (m *Manager) prune(userID uint) {
+	finished, err := m.store.Find(Filter{UserID: userID, States: []State{Done, Failed, Cancelled}})
+	if err != nil {
+		log.Printf("Failed to find the finished jobs of user %d: %v", userID, err)
+		return
+	}
+
+	// Newest first.
+	for i := m.cfg.MaxFinished; i < len(finished); i++ {
+		if err := m.store.Delete(finished[i].ID); err != nil {
+			log.Printf("Failed to delete job %d: %v", finished[i].ID, err)
+		}
+	}
+}
+
+func (m *Manager) run(ctx, jobCtx context.Context, id uint) {
+	j, err := m.store.Get(id)
+	if err != nil {
+		log.Printf("Failed to load job %d: %v", id, err)
+		return
+	}
+	if j.State != Queued {
+		return
+	}
+
+	j.State = Running
+	j.Attempts++
+	j.Started = time.Now()
+	m.save(j)
+
+	var checker rules.ActionChecker
+	user, err := m.cfg.User(j.UserID)
+	if err == nil && m.cfg.Checker != nil {
+		checker, err = m.cfg.Checker(user)
+	}
+	if err == nil && m.cfg.Authorize != nil {
+		err = m.cfg.Authorize(j, user)
+	}
+	if err == nil {
+		t := newTracker(j, m.save)
+		op := func() error { return execute(jobCtx, user.Fs, j, checker, t) }
+		if m.cfg.Hook != nil {
+			err = m.cfg.Hook(j, user, op)
+		} else {
+			err = op()
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// The server is stopping, resume on the next start.
+		j.State = Queued
+		m.save(j)
+		return
+	case err == nil:
+		j.State = Done
+	case errors.Is(err, context.Canceled):
+		j.State = Cancelled
+	default:
+		j.State = Failed
+		j.Error = err.Error()
+	}
+
+	j.ETA = 0
+	j.Finished = time.Now()
+	m.save(j)
+	m.prune(j.UserID)
+
+	if m.cfg.Finished != nil && user != nil {
+		m.cfg.Finished(j, user)
+	}
+}
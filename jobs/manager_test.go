@@ -0,0 +1,558 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/manifest"
+	"github.com/filebrowser/filebrowser/v2/rules"
+	"github.com/filebrowser/filebrowser/v2/trash"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+type memBackend struct {
+	mu   sync.Mutex
+	last uint
+	jobs map[uint]Job
+}
+
+func newMemStorage() *Storage {
+	return NewStorage(&memBackend{jobs: map[uint]Job{}})
+}
+
+func (b *memBackend) Save(j *Job) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if j.ID == 0 {
+		b.last++
+		j.ID = b.last
+	}
+	b.jobs[j.ID] = *j
+	return nil
+}
+
+func (b *memBackend) Get(id uint) (*Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	j, ok := b.jobs[id]
+	if !ok {
+		return nil, fbErrors.ErrNotExist
+	}
+	return &j, nil
+}
+
+func (b *memBackend) Find(f Filter) ([]*Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := []*Job{}
+	for _, j := range b.jobs {
+		j := j
+		if f.UserID != 0 && j.UserID != f.UserID {
+			continue
+		}
+		if len(f.States) > 0 {
+			match := false
+			for _, s := range f.States {
+				match = match || s == j.State
+			}
+			if !match {
+				continue
+			}
+		}
+		list = append(list, &j)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].ID > list[k].ID })
+	return list, nil
+}
+
+func (b *memBackend) Delete(id uint) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.jobs, id)
+	return nil
+}
+
+func newUser(t *testing.T) *users.User {
+	t.Helper()
+	return &users.User{ID: 1, Fs: afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())}
+}
+
+func writeFile(t *testing.T, fs afero.Fs, p, content string) {
+	t.Helper()
+	if err := fs.MkdirAll(path.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitState(t *testing.T, store *Storage, id uint, state State) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, err := store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.State == state {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is %s, expected %s (%s)", id, j.State, state, j.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func start(t *testing.T, m *Manager) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyJob(t *testing.T) {
+	user := newUser(t)
+	if err := user.Fs.MkdirAll("/roms/snes", 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, user.Fs, "/roms/snes/zelda.sfc", "zelda")
+	writeFile(t, user.Fs, "/roms/mario.sfc", "mario!")
+
+	var hooked, finished []string
+	var mu sync.Mutex
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Hook: func(j *Job, _ *users.User, fn func() error) error {
+			mu.Lock()
+			hooked = append(hooked, "before_"+string(j.Action))
+			mu.Unlock()
+			if err := fn(); err != nil {
+				return err
+			}
+			mu.Lock()
+			hooked = append(hooked, "after_"+string(j.Action))
+			mu.Unlock()
+			return nil
+		},
+		Finished: func(j *Job, _ *users.User) {
+			mu.Lock()
+			finished = append(finished, string(j.State))
+			mu.Unlock()
+		},
+	})
+	start(t, m)
+
+	j := &Job{UserID: 1, Action: Copy, Src: "/roms", Dst: "/usb/roms"}
+	if err := m.Enqueue(j); err != nil {
+		t.Fatal(err)
+	}
+
+	done := waitState(t, store, j.ID, Done)
+	if done.FilesDone != 2 || done.FilesTotal != 2 || done.BytesDone != 11 || done.BytesTotal != 11 {
+		t.Errorf("unexpected progress %+v", done)
+	}
+	if data, _ := afero.ReadFile(user.Fs, "/usb/roms/snes/zelda.sfc"); string(data) != "zelda" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hooked) != 2 || hooked[0] != "before_copy" || hooked[1] != "after_copy" {
+		t.Errorf("unexpected hooks %v", hooked)
+	}
+	if len(finished) != 1 || finished[0] != string(Done) {
+		t.Errorf("unexpected finished callbacks %v", finished)
+	}
+}
+
+func TestMoveAndDeleteJobs(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/a.sav", "save")
+	writeFile(t, user.Fs, "/b.sav", "save")
+
+	store := newMemStorage()
+	m := NewManager(store, Config{User: func(uint) (*users.User, error) { return user, nil }})
+	start(t, m)
+
+	move := &Job{UserID: 1, Action: Move, Src: "/a.sav", Dst: "/moved.sav"}
+	del := &Job{UserID: 1, Action: Delete, Src: "/b.sav"}
+	for _, j := range []*Job{move, del} {
+		if err := m.Enqueue(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitState(t, store, move.ID, Done)
+	waitState(t, store, del.ID, Done)
+
+	if _, err := user.Fs.Stat("/a.sav"); err == nil {
+		t.Error("source still exists after move")
+	}
+	if _, err := user.Fs.Stat("/moved.sav"); err != nil {
+		t.Error(err)
+	}
+
+	items, err := trash.NewForUser(user.Fs, 1).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Path != "/b.sav" {
+		t.Errorf("deleted file not in the trash: %+v", items)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/big.iso", "data")
+
+	var once sync.Once
+	started := make(chan struct{})
+	release := make(chan struct{})
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		Workers: 1,
+		User:    func(uint) (*users.User, error) { return user, nil },
+		Hook: func(_ *Job, _ *users.User, fn func() error) error {
+			once.Do(func() { close(started) })
+			<-release
+			return fn()
+		},
+	})
+
+	running := &Job{UserID: 1, Action: Copy, Src: "/big.iso", Dst: "/copy.iso"}
+	queued := &Job{UserID: 1, Action: Copy, Src: "/big.iso", Dst: "/other.iso"}
+	for _, j := range []*Job{running, queued} {
+		if err := m.Enqueue(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start(t, m)
+
+	<-started
+	if err := m.Cancel(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	waitState(t, store, running.ID, Cancelled)
+	waitState(t, store, queued.ID, Cancelled)
+
+	if err := m.Resume(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, store, queued.ID, Done)
+
+	if err := m.Remove(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(queued.ID); err == nil {
+		t.Error("job still exists after removal")
+	}
+}
+
+func TestResumeAfterRestart(t *testing.T) {
+	user := newUser(t)
+	if err := user.Fs.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, user.Fs, "/src/done.bin", "0123456789")
+	writeFile(t, user.Fs, "/src/partial.bin", "0123456789")
+	if err := user.Fs.MkdirAll("/dst", 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Left behind by the interrupted attempt. The marked bytes
+	// prove they aren't copied again.
+	writeFile(t, user.Fs, "/dst/done.bin", "0123456789")
+	writeFile(t, user.Fs, "/dst/partial.bin", "XXXX")
+
+	store := newMemStorage()
+	j := &Job{UserID: 1, Action: Copy, Src: "/src", Dst: "/dst", State: Running, Attempts: 1}
+	if err := store.Save(j); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(store, Config{User: func(uint) (*users.User, error) { return user, nil }})
+	start(t, m)
+
+	done := waitState(t, store, j.ID, Done)
+	if done.Attempts != 2 || done.BytesDone != 20 || done.FilesDone != 2 {
+		t.Errorf("unexpected progress %+v", done)
+	}
+	if data, _ := afero.ReadFile(user.Fs, "/dst/partial.bin"); string(data) != "XXXX456789" {
+		t.Errorf("partial file wasn't resumed: %q", data)
+	}
+}
+
+func TestShutdownRequeues(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/a", "a")
+
+	started := make(chan struct{})
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Hook: func(_ *Job, _ *users.User, fn func() error) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return fn()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &Job{UserID: 1, Action: Copy, Src: "/a", Dst: "/b"}
+	if err := m.Enqueue(j); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+
+	waitState(t, store, j.ID, Queued)
+}
+
+type denyPrefix string
+
+func (p denyPrefix) Check(path string) bool { return !strings.HasPrefix(path, string(p)) }
+
+func (p denyPrefix) CheckAction(path string, _ rules.Action) bool { return p.Check(path) }
+
+func TestExtractJob(t *testing.T) {
+	user := newUser(t)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"snes/zelda.sfc", "private/notes.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, user.Fs, "/pack.zip", buf.String())
+
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Checker: func(*users.User) (rules.ActionChecker, error) {
+			return denyPrefix("/roms/private"), nil
+		},
+	})
+	start(t, m)
+
+	j := &Job{UserID: 1, Action: Extract, Src: "/pack.zip", Dst: "/roms"}
+	if err := m.Enqueue(j); err != nil {
+		t.Fatal(err)
+	}
+
+	done := waitState(t, store, j.ID, Done)
+	if done.FilesTotal != 2 || done.FilesDone != 1 {
+		t.Errorf("unexpected progress %+v", done)
+	}
+	if exists, _ := afero.Exists(user.Fs, "/roms/snes/zelda.sfc"); !exists {
+		t.Error("archive wasn't extracted")
+	}
+	if exists, _ := afero.Exists(user.Fs, "/roms/private/notes.txt"); exists {
+		t.Error("extracted a path the rules deny")
+	}
+}
+
+func TestCompressJob(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/roms/snes/zelda.sfc", "zelda")
+	writeFile(t, user.Fs, "/roms/snes/zelda.srm", "save")
+	writeFile(t, user.Fs, "/roms/private/notes.txt", "notes")
+
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Checker: func(*users.User) (rules.ActionChecker, error) {
+			return denyPrefix("/roms/private"), nil
+		},
+	})
+	start(t, m)
+
+	j := &Job{
+		UserID:  1,
+		Action:  Compress,
+		Src:     "/roms",
+		Dst:     "/roms/backup.tar.gz",
+		IsDir:   true,
+		Sources: []string{"/roms"},
+		Archive: &archive.CreateOptions{Format: archive.TarGz, Exclude: []string{"*.srm"}},
+	}
+	if err := m.Enqueue(j); err != nil {
+		t.Fatal(err)
+	}
+
+	done := waitState(t, store, j.ID, Done)
+	if done.FilesDone != 1 || done.BytesDone != 5 {
+		t.Errorf("unexpected progress %+v", done)
+	}
+
+	var names []string
+	err := archive.Walk(user.Fs, "/roms/backup.tar.gz", func(e *archive.Entry, _ io.Reader) error {
+		if !e.IsDir {
+			names = append(names, e.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "snes/zelda.sfc" {
+		t.Errorf("unexpected entries %v", names)
+	}
+}
+
+func TestManifestJobs(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/music/album/01.flac", "one")
+	writeFile(t, user.Fs, "/music/album/02.flac", "two")
+	writeFile(t, user.Fs, "/music/album/private/notes.txt", "notes")
+
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Checker: func(*users.User) (rules.ActionChecker, error) {
+			return denyPrefix("/music/album/private"), nil
+		},
+	})
+	start(t, m)
+
+	gen := &Job{UserID: 1, Action: Manifest, Src: "/music/album", Dst: "/music/album/album.sfv", IsDir: true, Algorithm: manifest.SFV}
+	if err := m.Enqueue(gen); err != nil {
+		t.Fatal(err)
+	}
+	if done := waitState(t, store, gen.ID, Done); done.FilesDone != 2 {
+		t.Errorf("unexpected progress %+v", done)
+	}
+
+	writeFile(t, user.Fs, "/music/album/02.flac", "TWO")
+	verify := &Job{UserID: 1, Action: Verify, Src: "/music/album/album.sfv"}
+	if err := m.Enqueue(verify); err != nil {
+		t.Fatal(err)
+	}
+	done := waitState(t, store, verify.ID, Done)
+	report := done.Verification
+	if report == nil || report.OK != 1 || report.Mismatch != 1 || len(report.Results) != 1 || report.Results[0].Path != "02.flac" {
+		t.Errorf("unexpected verification %+v", report)
+	}
+}
+
+func TestAuthorizeJob(t *testing.T) {
+	user := newUser(t)
+	writeFile(t, user.Fs, "/a.sav", "save")
+
+	store := newMemStorage()
+	var mu sync.Mutex
+	allowed := false
+	m := NewManager(store, Config{
+		User: func(uint) (*users.User, error) { return user, nil },
+		Authorize: func(*Job, *users.User) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if !allowed {
+				return fbErrors.ErrPermissionDenied
+			}
+			return nil
+		},
+	})
+	start(t, m)
+
+	j := &Job{UserID: 1, Action: Delete, Src: "/a.sav"}
+	if err := m.Enqueue(j); err != nil {
+		t.Fatal(err)
+	}
+	failed := waitState(t, store, j.ID, Failed)
+	if failed.Error != fbErrors.ErrPermissionDenied.Error() {
+		t.Errorf("unexpected error %q", failed.Error)
+	}
+	if _, err := user.Fs.Stat("/a.sav"); err != nil {
+		t.Errorf("expected the denied job not to run: %v", err)
+	}
+
+	// Resumed jobs are authorized again.
+	mu.Lock()
+	allowed = true
+	mu.Unlock()
+	if err := m.Resume(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, store, j.ID, Done)
+}
+
+func TestPruneFinishedJobs(t *testing.T) {
+	user := newUser(t)
+	store := newMemStorage()
+	m := NewManager(store, Config{
+		Workers:     1,
+		MaxFinished: 2,
+		User:        func(uint) (*users.User, error) { return user, nil },
+	})
+
+	// Jobs of other users are kept.
+	other := &Job{UserID: 2, Action: Delete, Src: "/gone.sav", State: Failed}
+	if err := store.Save(other); err != nil {
+		t.Fatal(err)
+	}
+	start(t, m)
+
+	var list []*Job
+	for i := 0; i < 3; i++ {
+		p := fmt.Sprintf("/%d.sav", i)
+		writeFile(t, user.Fs, p, "save")
+		j := &Job{UserID: 1, Action: Delete, Src: p}
+		if err := m.Enqueue(j); err != nil {
+			t.Fatal(err)
+		}
+		waitState(t, store, j.ID, Done)
+		list = append(list, j)
+	}
+
+	// Jobs are pruned right after they are saved as finished.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := store.Get(list[0].ID)
+		if errors.Is(err, fbErrors.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the oldest job to be deleted, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, j := range append(list[1:], other) {
+		if _, err := store.Get(j.ID); err != nil {
+			t.Errorf("expected job %d to be kept: %v", j.ID, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import "time"
+
+// StorageBackend is the interface to implement for a jobs storage.
+type StorageBackend interface {
+	Save(j *Job) error
+	Get(id uint) (*Job, error)
+	Find(f Filter) ([]*Job, error)
+	Delete(id uint) error
+}
+
+// Storage is a jobs storage.
+type Storage struct {
+	back StorageBackend
+}
+
+// NewStorage creates a jobs storage from a backend.
+func NewStorage(back StorageBackend) *Storage {
+	return &Storage{back: back}
+}
+
+// Save wraps a StorageBackend.Save, setting the creation time if empty.
+func (s *Storage) Save(j *Job) error {
+	if j.Created.IsZero() {
+		j.Created = time.Now()
+	}
+	return s.back.Save(j)
+}
+
+// Get wraps a StorageBackend.Get.
+func (s *Storage) Get(id uint) (*Job, error) {
+	return s.back.Get(id)
+}
+
+// Find wraps a StorageBackend.Find. Jobs are returned newest first.
+func (s *Storage) Find(f Filter) ([]*Job, error) {
+	return s.back.Find(f)
+}
+
+// Delete wraps a StorageBackend.Delete.
+func (s *Storage) Delete(id uint) error {
+	return s.back.Delete(id)
+}
@@ -0,0 +1,325 @@
+package manifest
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/marusama/semaphore/v2"
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// DefaultWorkers is the number of files hashed at the same time when
+// Options.Workers isn't set.
+const DefaultWorkers = 4
+
+// Progress is told about the files hashed.
+type Progress interface {
+	// Add reports n more bytes hashed.
+	Add(n int64)
+	// FileDone reports a file was entirely hashed.
+	FileDone()
+}
+
+// Options configures Generate and Verify.
+type Options struct {
+	// Workers bounds the number of files hashed at the same time.
+	Workers int
+	// Checker, if set, decides which files are listed or verified.
+	Checker rules.Checker
+	// Progress, if set, is told about the files hashed. Its methods
+	// are never called concurrently.
+	Progress Progress
+}
+
+// Status is the result of the verification of a file.
+type Status string
+
+const (
+	OK       Status = "ok"
+	Mismatch Status = "mismatch"
+	Missing  Status = "missing"
+)
+
+// Result is the verification of a file listed in a manifest.
+type Result struct {
+	Path     string `json:"path"`
+	Status   Status `json:"status"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// Report is the verification of a manifest. Only the files that
+// failed it are listed in Results.
+type Report struct {
+	Manifest  string    `json:"manifest"`
+	Algorithm Algorithm `json:"algorithm"`
+	OK        int       `json:"ok"`
+	Mismatch  int       `json:"mismatch"`
+	Missing   int       `json:"missing"`
+	Results   []*Result `json:"results"`
+}
+
+// Failed reports whether some files are missing or corrupted.
+func (r *Report) Failed() bool {
+	return r.Mismatch > 0 || r.Missing > 0
+}
+
+// Generate writes to dst a manifest of the files under dir. dst must
+// be in dir or in one of its parents, since the paths of manifests
+// are relative to them. It's only replaced once all files were hashed.
+func Generate(ctx context.Context, fs afero.Fs, alg Algorithm, dir, dst string, opts Options) (*Manifest, error) {
+	dir = path.Clean("/" + dir)
+	dst = path.Clean("/" + dst)
+	tmp := path.Join(path.Dir(dst), "."+path.Base(dst)+".part")
+
+	base, ok := relative(path.Dir(dst), dir)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in %s or above it: %w", dst, dir, fbErrors.ErrInvalidRequestParams)
+	}
+
+	m := &Manifest{Name: dst, Algorithm: alg}
+	err := afero.Walk(fs, dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if opts.Checker != nil && !opts.Checker.Check(p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || p == dst || p == tmp {
+			return nil
+		}
+
+		rel, _ := relative(dir, p)
+		m.Entries = append(m.Entries, &Entry{Path: path.Join(base, rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = hashAll(ctx, fs, alg, len(m.Entries), opts, func(i int) (string, *string) {
+		e := m.Entries[i]
+		return path.Join(m.Dir(), e.Path), &e.Checksum
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fs.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) //nolint:gomnd
+	if err != nil {
+		return nil, err
+	}
+	err = m.Write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = fs.Remove(tmp)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Verify hashes the files listed in m and compares them with their
+// checksums in it. Files outside of the directory of the manifest or
+// denied by the checker are reported missing.
+func Verify(ctx context.Context, fs afero.Fs, m *Manifest, opts Options) (*Report, error) {
+	report := &Report{Manifest: m.Name, Algorithm: m.Algorithm, Results: []*Result{}}
+	dir := m.Dir()
+
+	actual := make([]string, len(m.Entries))
+	missing := make([]bool, len(m.Entries))
+	err := hashAll(ctx, fs, m.Algorithm, len(m.Entries), opts, func(i int) (string, *string) {
+		p, ok := resolve(dir, m.Entries[i].Path)
+		if !ok || (opts.Checker != nil && !opts.Checker.Check(p)) {
+			missing[i] = true
+			return "", nil
+		}
+		return p, &actual[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range m.Entries {
+		result := &Result{Path: e.Path, Expected: e.Checksum, Actual: actual[i]}
+		switch {
+		case missing[i] || actual[i] == "":
+			result.Status = Missing
+			report.Missing++
+		case actual[i] != e.Checksum:
+			result.Status = Mismatch
+			report.Mismatch++
+		default:
+			report.OK++
+			continue
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// resolve joins dir and the path name of a manifest, which must not
+// leave dir.
+func resolve(dir, name string) (string, bool) {
+	if strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	p := path.Join(dir, name)
+	if _, ok := relative(dir, p); !ok {
+		return "", false
+	}
+	return p, true
+}
+
+// relative returns the path of p relative to dir, "" for dir itself.
+// ok is false if p isn't in dir.
+func relative(dir, p string) (rel string, ok bool) {
+	switch {
+	case p == dir:
+		return "", true
+	case dir == "/":
+		return strings.TrimPrefix(p, "/"), true
+	case strings.HasPrefix(p, dir+"/"):
+		return p[len(dir)+1:], true
+	default:
+		return "", false
+	}
+}
+
+// hashAll hashes n files in parallel. file returns the path of the
+// i-th file and where to store its checksum, or a nil pointer for
+// files to skip. Missing files are left without checksum.
+func hashAll(ctx context.Context, fs afero.Fs, alg Algorithm, n int, opts Options, file func(i int) (string, *string)) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	sem := semaphore.New(workers)
+	progress := &lockedProgress{p: opts.Progress}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		name, sum := file(i)
+		if sum == nil {
+			continue
+		}
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			checksum, err := hashFile(ctx, fs, alg, name, progress)
+			switch {
+			case err == nil:
+				*sum = checksum
+				progress.FileDone()
+			case errors.Is(err, os.ErrNotExist) || errors.Is(err, fbErrors.ErrIsDirectory):
+			default:
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", name, err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func hashFile(ctx context.Context, fs afero.Fs, alg Algorithm, name string, progress Progress) (string, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fbErrors.ErrIsDirectory
+	}
+
+	h := alg.newHash()
+	if _, err := io.Copy(h, &progressReader{ctx: ctx, r: f, p: progress}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type progressReader struct {
+	ctx context.Context
+	r   io.Reader
+	p   Progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(b)
+	r.p.Add(int64(n))
+	return n, err
+}
+
+// lockedProgress serializes the calls of the workers to a Progress.
+type lockedProgress struct {
+	mu sync.Mutex
+	p  Progress
+}
+
+func (l *lockedProgress) Add(n int64) {
+	if l.p == nil || n == 0 {
+		return
+	}
+	l.mu.Lock()
+	l.p.Add(n)
+	l.mu.Unlock()
+}
+
+func (l *lockedProgress) FileDone() {
+	if l.p == nil {
+		return
+	}
+	l.mu.Lock()
+	l.p.FileDone()
+	l.mu.Unlock()
+}
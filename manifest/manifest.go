@@ -0,0 +1,225 @@
+// Package manifest generates and verifies checksum manifests, the
+// SFV, MD5SUMS and SHA256SUMS files listing the checksums of the
+// files of a directory tree.
+package manifest
+
+import (
+	"bufio"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+// Algorithm is the checksum, and the file format, of a manifest.
+type Algorithm string
+
+const (
+	// SFV manifests list CRC32 checksums in the Simple File
+	// Verification format: "name CHECKSUM" lines.
+	SFV Algorithm = "sfv"
+	// MD5 manifests are in the format of md5sum.
+	MD5 Algorithm = "md5"
+	// SHA256 manifests are in the format of sha256sum.
+	SHA256 Algorithm = "sha256"
+)
+
+// ParseAlgorithm returns the algorithm named s.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch alg := Algorithm(strings.ToLower(s)); alg {
+	case SFV, MD5, SHA256:
+		return alg, nil
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm %q: %w", s, fbErrors.ErrInvalidOption)
+	}
+}
+
+// AlgorithmOf guesses the algorithm of a manifest from its name, such
+// as "album.sfv", "MD5SUMS" or "release.sha256".
+func AlgorithmOf(name string) (Algorithm, bool) {
+	name = strings.ToLower(path.Base(name))
+	switch {
+	case strings.HasSuffix(name, ".sfv"):
+		return SFV, true
+	case strings.Contains(name, "sha256"):
+		return SHA256, true
+	case strings.Contains(name, "md5"):
+		return MD5, true
+	default:
+		return "", false
+	}
+}
+
+// DefaultName returns the name of the manifest of the directory dir:
+// "<dir>.sfv", "MD5SUMS" or "SHA256SUMS".
+func (a Algorithm) DefaultName(dir string) string {
+	switch a {
+	case SFV:
+		base := path.Base(path.Clean("/" + dir))
+		if base == "/" {
+			base = "checksums"
+		}
+		return base + ".sfv"
+	case MD5:
+		return "MD5SUMS"
+	default:
+		return "SHA256SUMS"
+	}
+}
+
+func (a Algorithm) newHash() hash.Hash {
+	switch a {
+	case SFV:
+		return crc32.NewIEEE()
+	case MD5:
+		return md5.New() //nolint:gosec
+	default:
+		return sha256.New()
+	}
+}
+
+// Entry is a file listed in a manifest.
+type Entry struct {
+	// Path is relative to the directory of the manifest, with
+	// forward slashes.
+	Path string `json:"path"`
+	// Checksum is lower case hex.
+	Checksum string `json:"checksum"`
+}
+
+// Manifest is a parsed manifest.
+type Manifest struct {
+	// Name is the path of the manifest file.
+	Name      string    `json:"name"`
+	Algorithm Algorithm `json:"algorithm"`
+	Entries   []*Entry  `json:"entries"`
+}
+
+// Dir returns the directory the paths of the manifest are relative
+// to.
+func (m *Manifest) Dir() string {
+	return path.Dir(m.Name)
+}
+
+// Load reads the manifest at name, guessing its algorithm from its
+// name or, failing that, from the length of its checksums.
+func Load(fs afero.Fs, name string) (*Manifest, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	alg, _ := AlgorithmOf(name)
+	m, err := Parse(f, alg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+	}
+	m.Name = name
+	return m, nil
+}
+
+// Parse reads a manifest. alg may be empty, in which case it's told
+// from the checksums.
+func Parse(r io.Reader, alg Algorithm) (*Manifest, error) {
+	m := &Manifest{Algorithm: alg}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, ";") || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		e, lineAlg, ok := parseLine(text, m.Algorithm)
+		if !ok {
+			return nil, fmt.Errorf("invalid line %d: %w", line, fbErrors.ErrInvalidDataType)
+		}
+		if m.Algorithm == "" {
+			m.Algorithm = lineAlg
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if m.Algorithm == "" {
+		return nil, fmt.Errorf("empty manifest: %w", fbErrors.ErrInvalidDataType)
+	}
+	return m, nil
+}
+
+// parseLine parses "checksum  path" and "checksum *path" lines of
+// md5sum and sha256sum and "path CHECKSUM" lines of SFV files.
+func parseLine(text string, alg Algorithm) (*Entry, Algorithm, bool) {
+	if alg == "" || alg == MD5 || alg == SHA256 {
+		if sum, name, ok := strings.Cut(text, " "); ok && isHex(sum) {
+			name = strings.TrimPrefix(name, " ")
+			name = strings.TrimPrefix(name, "*")
+			switch {
+			case len(sum) == 32 && alg != SHA256:
+				return newEntry(name, sum), MD5, name != ""
+			case len(sum) == 64 && alg != MD5:
+				return newEntry(name, sum), SHA256, name != ""
+			}
+		}
+		if alg != "" {
+			return nil, "", false
+		}
+	}
+
+	i := strings.LastIndexAny(text, " \t")
+	if i < 0 {
+		return nil, "", false
+	}
+	name, sum := strings.TrimRight(text[:i], " \t"), text[i+1:]
+	if len(sum) != 8 || !isHex(sum) || name == "" {
+		return nil, "", false
+	}
+	return newEntry(name, sum), SFV, true
+}
+
+func newEntry(name, sum string) *Entry {
+	// SFV files made on Windows use backslashes.
+	name = strings.ReplaceAll(name, "\\", "/")
+	return &Entry{Path: name, Checksum: strings.ToLower(sum)}
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return s != "" && err == nil
+}
+
+// Write writes the manifest in the format of its algorithm.
+func (m *Manifest) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if m.Algorithm == SFV {
+		if _, err := bw.WriteString("; Generated by File Browser\n"); err != nil {
+			return err
+		}
+	}
+	for _, e := range m.Entries {
+		var err error
+		if m.Algorithm == SFV {
+			_, err = fmt.Fprintf(bw, "%s %s\n", e.Path, strings.ToUpper(e.Checksum))
+		} else {
+			_, err = fmt.Fprintf(bw, "%s  %s\n", e.Checksum, e.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+type denyPrefix string
+
+func (p denyPrefix) Check(path string) bool { return !strings.HasPrefix(path, string(p)) }
+
+func writeFile(t *testing.T, fs afero.Fs, name, content string) {
+	t.Helper()
+	if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		alg           Algorithm
+		paths         []string
+	}{
+		{
+			name:    "album.sfv",
+			content: "; comment\r\n01 - Intro.flac 1A2B3C4D\r\nCD2\\02.flac\t0000ABCD\r\n",
+			alg:     SFV,
+			paths:   []string{"01 - Intro.flac", "CD2/02.flac"},
+		},
+		{
+			name:    "MD5SUMS",
+			content: "d41d8cd98f00b204e9800998ecf8427e  empty\nd41d8cd98f00b204e9800998ecf8427e *dir/bin ary\n",
+			alg:     MD5,
+			paths:   []string{"empty", "dir/bin ary"},
+		},
+		{
+			name:    "release.txt",
+			content: "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855  a\n",
+			alg:     SHA256,
+			paths:   []string{"a"},
+		},
+	} {
+		alg, _ := AlgorithmOf(tc.name)
+		m, err := Parse(strings.NewReader(tc.content), alg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if m.Algorithm != tc.alg || len(m.Entries) != len(tc.paths) {
+			t.Fatalf("%s: unexpected manifest %+v", tc.name, m)
+		}
+		for i, p := range tc.paths {
+			if e := m.Entries[i]; e.Path != p || strings.ToLower(e.Checksum) != e.Checksum {
+				t.Errorf("%s: unexpected entry %+v", tc.name, e)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"", "not a manifest", "d41d8cd98f00b204e9800998ecf8427e"} {
+		if _, err := Parse(strings.NewReader(invalid), ""); !errors.Is(err, fbErrors.ErrInvalidDataType) {
+			t.Errorf("%q: expected ErrInvalidDataType, got %v", invalid, err)
+		}
+	}
+	if _, err := Parse(strings.NewReader("a 1A2B3C4D\n"), MD5); !errors.Is(err, fbErrors.ErrInvalidDataType) {
+		t.Errorf("expected an SFV line to be rejected from an MD5 manifest, got %v", err)
+	}
+}
+
+func TestGenerateAndVerify(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeFile(t, fs, "/music/album/01.flac", "one")
+	writeFile(t, fs, "/music/album/cd2/02.flac", "two")
+	writeFile(t, fs, "/music/album/private/notes.txt", "notes")
+
+	for _, alg := range []Algorithm{SFV, MD5, SHA256} {
+		dst := "/music/album/" + alg.DefaultName("/music/album")
+		opts := Options{Workers: 2, Checker: denyPrefix("/music/album/private")}
+		m, err := Generate(context.Background(), fs, alg, "/music/album", dst, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if len(m.Entries) != 2 || m.Entries[0].Path != "01.flac" || m.Entries[1].Path != "cd2/02.flac" {
+			t.Fatalf("%s: unexpected entries %+v", alg, m.Entries)
+		}
+
+		loaded, err := Load(fs, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := Verify(context.Background(), fs, loaded, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.OK != 2 || report.Failed() {
+			t.Errorf("%s: unexpected report %+v", alg, report)
+		}
+		if err := fs.Remove(dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := Generate(context.Background(), fs, SFV, "/music/album", "/music/album/album.sfv", Options{Checker: denyPrefix("/music/album/private")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Entries[0].Checksum != "7a6c86f1" {
+		t.Errorf("unexpected CRC32 %s", m.Entries[0].Checksum)
+	}
+
+	// Manifests of a parent directory list paths relative to it.
+	m, err = Generate(context.Background(), fs, SFV, "/music/album/cd2", "/music/cd2.sfv", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 1 || m.Entries[0].Path != "album/cd2/02.flac" {
+		t.Errorf("unexpected entries %+v", m.Entries)
+	}
+	if _, err := Generate(context.Background(), fs, SFV, "/music/album", "/music/album/cd2/x.sfv", Options{}); !errors.Is(err, fbErrors.ErrInvalidRequestParams) {
+		t.Errorf("expected ErrInvalidRequestParams, got %v", err)
+	}
+
+	writeFile(t, fs, "/music/album/01.flac", "ONE")
+	if err := fs.Remove("/music/album/cd2/02.flac"); err != nil {
+		t.Fatal(err)
+	}
+	m, err = Load(fs, "/music/album/album.sfv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	escaping, err := Parse(strings.NewReader("../../etc/passwd 0123ABCD\n"), SFV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Entries = append(m.Entries, escaping.Entries...)
+
+	report, err := Verify(context.Background(), fs, m, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK != 0 || report.Mismatch != 1 || report.Missing != 2 || len(report.Results) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if r := report.Results[0]; r.Path != "01.flac" || r.Status != Mismatch || r.Actual == r.Expected {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Verify(ctx, fs, m, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
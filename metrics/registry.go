@@ -0,0 +1,270 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default histogram buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry the metrics of File Browser are registered in.
+var Default = NewRegistry()
+
+type metric interface {
+	write(w *Writer)
+}
+
+// Registry holds a set of metrics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric of the registry in the text format.
+func (r *Registry) WriteText(out io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	w := NewWriter(out)
+	for _, m := range metrics {
+		m.write(w)
+	}
+	return w.Flush()
+}
+
+// desc describes a metric and holds its labeled values.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string][]string // label values by key
+}
+
+func newDesc(name, help string, labels []string) desc {
+	return desc{name: name, help: help, labels: labels, values: map[string][]string{}}
+}
+
+// key returns the key of the label values, remembering them.
+// It must be called with mu held.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	if _, ok := d.values[key]; !ok {
+		d.values[key] = append([]string(nil), values...)
+	}
+	return key
+}
+
+// sortedKeys returns the keys in a stable order. It must be called with mu held.
+func (d *desc) sortedKeys() []string {
+	keys := make([]string, 0, len(d.values))
+	for k := range d.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *desc) sample(key string, v float64, extra ...Label) Sample {
+	values := d.values[key]
+	labels := make([]Label, 0, len(values)+len(extra))
+	for i, name := range d.labels {
+		labels = append(labels, Label{Name: name, Value: values[i]})
+	}
+	return Sample{Labels: append(labels, extra...), Value: v}
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	desc
+	counts map[string]float64
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: newDesc(name, help, labels), counts: map[string]float64{}}
+	r.register(name, c)
+	return c
+}
+
+// Inc increments the counter with the given label values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the counter
+// with the given label values.
+func (c *CounterVec) Add(v float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[c.key(values)] += v
+}
+
+func (c *CounterVec) write(w *Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	samples := []Sample{}
+	for _, k := range c.sortedKeys() {
+		samples = append(samples, c.sample(k, c.counts[k]))
+	}
+	if len(c.labels) == 0 && len(samples) == 0 {
+		samples = append(samples, Value(0))
+	}
+	w.Write(c.name, c.help, Counter, samples...)
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	desc
+	gauges map[string]float64
+}
+
+// NewGauge registers a gauge with the given label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: newDesc(name, help, labels), gauges: map[string]float64{}}
+	r.register(name, g)
+	return g
+}
+
+// Set sets the gauge with the given label values to v.
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gauges[g.key(values)] = v
+}
+
+// Add adds v to the gauge with the given label values.
+func (g *GaugeVec) Add(v float64, values ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gauges[g.key(values)] += v
+}
+
+func (g *GaugeVec) write(w *Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	samples := []Sample{}
+	for _, k := range g.sortedKeys() {
+		samples = append(samples, g.sample(k, g.gauges[k]))
+	}
+	if len(g.labels) == 0 && len(samples) == 0 {
+		samples = append(samples, Value(0))
+	}
+	w.Write(g.name, g.help, Gauge, samples...)
+}
+
+type gaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn
+// every time the metrics are written.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) write(w *Writer) {
+	w.Write(g.name, g.help, Gauge, Value(g.fn()))
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given buckets, which
+// must be sorted, and label names. Nil buckets use DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	h := &HistogramVec{desc: newDesc(name, help, labels), buckets: buckets, series: map[string]*histogram{}}
+	r.register(name, h)
+	return h
+}
+
+// Observe adds v to the histogram with the given label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := h.key(values)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) write(w *Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := h.sortedKeys()
+	if len(keys) == 0 {
+		return
+	}
+
+	w.Header(h.name, h.help, Histogram)
+	for _, k := range keys {
+		s := h.series[k]
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			le := Label{Name: "le", Value: strconv.FormatFloat(upper, 'g', -1, 64)}
+			w.Sample(h.name+"_bucket", h.sample(k, float64(cumulative), le))
+		}
+		inf := Label{Name: "le", Value: formatValue(math.Inf(1))}
+		w.Sample(h.name+"_bucket", h.sample(k, float64(s.count), inf))
+		w.Sample(h.name+"_sum", h.sample(k, s.sum))
+		w.Sample(h.name+"_count", h.sample(k, float64(s.count)))
+	}
+}
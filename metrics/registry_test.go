@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounter("requests_total", "Requests.", "route", "code")
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "404")
+	requests.Inc("/b", "200")
+
+	active := r.NewGauge("active", "Active\nthings.")
+	active.Add(3)
+	active.Add(-1)
+
+	r.NewGaugeFunc("temperature", "Temperature.", func() float64 { return 21.5 })
+
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.1, "/a")
+	latency.Observe(5, "/a")
+
+	r.NewHistogram("unused_seconds", "Unused.", nil)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a",code="404"} 2
+requests_total{route="/b",code="200"} 2
+# HELP active Active\nthings.
+# TYPE active gauge
+active 2
+# HELP temperature Temperature.
+# TYPE temperature gauge
+temperature 21.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 5.15
+latency_seconds_count{route="/a"} 3
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryPanicsOnMisuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("total", "Total.", "label")
+
+	for name, fn := range map[string]func(){
+		"duplicate name":     func() { r.NewGauge("total", "Total.") },
+		"wrong label values": func() { c.Inc("a", "b") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
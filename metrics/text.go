@@ -0,0 +1,138 @@
+// Package metrics writes metrics in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Type is the type of a metric.
+type Type string
+
+const (
+	Gauge     Type = "gauge"
+	Counter   Type = "counter"
+	Histogram Type = "histogram"
+)
+
+// Label is a metric label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a metric.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Writer writes metrics in the text exposition format.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter creates a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes a metric with its samples. Metrics without
+// samples are skipped.
+func (w *Writer) Write(name, help string, typ Type, samples ...Sample) {
+	if w.err != nil || len(samples) == 0 {
+		return
+	}
+
+	w.Header(name, help, typ)
+	for _, s := range samples {
+		w.Sample(name, s)
+	}
+}
+
+// Header writes the help and type lines of a metric.
+func (w *Writer) Header(name, help string, typ Type) {
+	w.writeString("# HELP ", name, " ", escapeHelp(help), "\n")
+	w.writeString("# TYPE ", name, " ", string(typ), "\n")
+}
+
+// Sample writes a single sample line. Histograms write their
+// series as samples of name with the _bucket, _sum and _count suffixes.
+func (w *Writer) Sample(name string, s Sample) {
+	w.writeString(name)
+	if len(s.Labels) > 0 {
+		w.writeString("{")
+		for i, l := range s.Labels {
+			if i > 0 {
+				w.writeString(",")
+			}
+			w.writeString(l.Name, `="`, escapeLabel(l.Value), `"`)
+		}
+		w.writeString("}")
+	}
+	w.writeString(" ", formatValue(s.Value), "\n")
+}
+
+// Value is a shorthand for a Sample without labels.
+func Value(v float64) Sample {
+	return Sample{Value: v}
+}
+
+// Labeled is a shorthand for a Sample with labels given as name, value pairs.
+func Labeled(v float64, pairs ...string) Sample {
+	labels := make([]Label, 0, len(pairs)/2) //nolint:gomnd
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, Label{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return Sample{Labels: labels, Value: v}
+}
+
+// Flush flushes the writer, returning the first error that happened.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) writeString(parts ...string) {
+	for _, p := range parts {
+		if w.err != nil {
+			return
+		}
+		_, w.err = w.w.WriteString(p)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,278 @@
+// Package quota tracks how much is stored in the scopes of users, so
+// that their quotas can be enforced without walking them on every
+// write.
+package quota
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+)
+
+// DefaultInterval is how long the usage of a scope that changed is
+// trusted before it's walked again.
+const DefaultInterval = 30 * time.Second
+
+// Usage is how much is stored in a scope. Only regular files count.
+type Usage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// Tracker caches the usage of scopes. Scopes are walked the first
+// time their usage is needed, and then kept up to date with what's
+// written through Add. Other changes published to the hub the tracker
+// is started with make it walk them again: right away for deletions
+// and renames, which free space, and at most every Interval otherwise,
+// so that uploads in chunks don't walk them for every chunk.
+//
+// Files written in parts, such as resumable uploads, can have their
+// final size reserved up front, so that concurrent writes can't each
+// fit in what's left. Reservations count in the usage of their scope,
+// walked or not, until they're written, released or the file is
+// deleted or renamed.
+type Tracker struct {
+	// Interval is how long the usage of a scope that changed is
+	// trusted, DefaultInterval if zero.
+	Interval time.Duration
+
+	mux    sync.Mutex
+	scopes map[string]*scope
+	// reserved holds the bytes reserved for files by scope and then
+	// by the path of the file on the host.
+	reserved map[string]map[string]int64
+}
+
+type scope struct {
+	usage  Usage
+	walked time.Time
+	// stale scopes are walked again before their usage is used, and
+	// changed ones once they were walked more than Interval ago.
+	stale   bool
+	changed bool
+}
+
+// NewTracker creates a tracker without any scope.
+func NewTracker() *Tracker {
+	return &Tracker{scopes: map[string]*scope{}, reserved: map[string]map[string]int64{}}
+}
+
+// Start keeps the tracker up to date with the changes published to
+// hub until ctx is done.
+func (t *Tracker) Start(ctx context.Context, hub *events.Hub) {
+	sub := hub.Subscribe(events.DefaultBuffer)
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if sub.Dropped() > 0 {
+					t.invalidateAll()
+				}
+				t.update(e)
+			}
+		}
+	}()
+}
+
+// Usage returns the usage of the directory root on the host, walking
+// it if it's not known or out of date.
+func (t *Tracker) Usage(root string) (Usage, error) {
+	root = filepath.Clean(root)
+
+	t.mux.Lock()
+	s, ok := t.scopes[root]
+	fresh := ok && !s.stale && (!s.changed || time.Since(s.walked) < t.interval())
+	if fresh {
+		usage := s.usage
+		usage.Bytes += t.reservedIn(root)
+		t.mux.Unlock()
+		return usage, nil
+	}
+	t.mux.Unlock()
+
+	started := time.Now()
+	usage, err := walk(root)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.scopes[root] = &scope{usage: usage, walked: started}
+	usage.Bytes += t.reservedIn(root)
+	return usage, nil
+}
+
+// Add accounts for bytes and files written to root, or removed from
+// it if they're negative.
+func (t *Tracker) Add(root string, bytes, files int64) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if s, ok := t.scopes[filepath.Clean(root)]; ok {
+		s.usage.Bytes = max(s.usage.Bytes+bytes, 0)
+		s.usage.Files = max(s.usage.Files+files, 0)
+	}
+}
+
+// Reserve reserves bytes in root for the file at name, replacing what
+// was reserved for it before.
+func (t *Tracker) Reserve(root, name string, bytes int64) {
+	root, name = filepath.Clean(root), filepath.Clean(name)
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if bytes <= 0 {
+		t.release(root, name)
+		return
+	}
+	if t.reserved[root] == nil {
+		t.reserved[root] = map[string]int64{}
+	}
+	t.reserved[root][name] = bytes
+}
+
+// Reserved returns how many bytes are still reserved in root for the
+// file at name.
+func (t *Tracker) Reserved(root, name string) int64 {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.reserved[filepath.Clean(root)][filepath.Clean(name)]
+}
+
+// Use accounts for bytes written to the file at name in root, taking
+// them out of what's reserved for it first.
+func (t *Tracker) Use(root, name string, bytes int64) {
+	root, name = filepath.Clean(root), filepath.Clean(name)
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if s, ok := t.scopes[root]; ok {
+		s.usage.Bytes = max(s.usage.Bytes+bytes, 0)
+	}
+	if left := t.reserved[root][name] - bytes; left > 0 {
+		t.reserved[root][name] = left
+	} else {
+		t.release(root, name)
+	}
+}
+
+// Release gives back what's still reserved in root for the file at
+// name.
+func (t *Tracker) Release(root, name string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.release(filepath.Clean(root), filepath.Clean(name))
+}
+
+func (t *Tracker) release(root, name string) {
+	delete(t.reserved[root], name)
+	if len(t.reserved[root]) == 0 {
+		delete(t.reserved, root)
+	}
+}
+
+func (t *Tracker) reservedIn(root string) int64 {
+	var bytes int64
+	for _, n := range t.reserved[root] {
+		bytes += n
+	}
+	return bytes
+}
+
+// Invalidate makes the tracker walk root again the next time its usage
+// is needed.
+func (t *Tracker) Invalidate(root string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if s, ok := t.scopes[filepath.Clean(root)]; ok {
+		s.stale = true
+	}
+}
+
+func (t *Tracker) interval() time.Duration {
+	if t.Interval == 0 {
+		return DefaultInterval
+	}
+	return t.Interval
+}
+
+func (t *Tracker) invalidateAll() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for _, s := range t.scopes {
+		s.stale = true
+	}
+}
+
+func (t *Tracker) update(e events.Event) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for root, s := range t.scopes {
+		affected := overlaps(root, e.Path) || (e.Dst != "" && overlaps(root, e.Dst))
+		switch {
+		case !affected:
+		case e.Type == events.Delete || e.Type == events.Rename:
+			s.stale = true
+		default:
+			s.changed = true
+		}
+	}
+
+	// What was reserved for files that are gone won't be written.
+	if e.Type == events.Delete || e.Type == events.Rename {
+		for root, names := range t.reserved {
+			for name := range names {
+				if within(e.Path, name) {
+					t.release(root, name)
+				}
+			}
+		}
+	}
+}
+
+// overlaps reports whether one of root and path holds the other.
+func overlaps(root, path string) bool {
+	return within(root, path) || within(path, root)
+}
+
+func within(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func walk(root string) (Usage, error) {
+	var usage Usage
+	err := filepath.WalkDir(root, func(_ string, entry fs.DirEntry, err error) error {
+		switch {
+		case os.IsNotExist(err):
+			// Removed while walking.
+			return nil
+		case err != nil:
+			return err
+		case !entry.Type().IsRegular():
+			return nil
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		usage.Bytes += info.Size()
+		usage.Files++
+		return nil
+	})
+	return usage, err
+}
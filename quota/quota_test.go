@@ -0,0 +1,131 @@
+package quota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+)
+
+func TestTracker(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub, tracker := events.NewHub(), NewTracker()
+	tracker.Start(ctx, hub)
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(want Usage) {
+		t.Helper()
+		usage, err := tracker.Usage(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage != want {
+			t.Errorf("expected %+v, got %+v", want, usage)
+		}
+	}
+
+	write("a.txt", "0123456789")
+	write("dir/b.txt", "01234")
+	expect(Usage{Bytes: 15, Files: 2})
+
+	// Writes are accounted for without walking again.
+	write("dir/c.txt", "012")
+	expect(Usage{Bytes: 15, Files: 2})
+	tracker.Add(root, 3, 1)
+	expect(Usage{Bytes: 18, Files: 3})
+
+	// Deletions are walked again right away.
+	if err := os.Remove(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(events.Event{Type: events.Delete, Path: filepath.Join(root, "a.txt")})
+	deadline := time.Now().Add(time.Second)
+	for {
+		usage, err := tracker.Usage(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage == (Usage{Bytes: 8, Files: 2}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the deletion to be walked, got %+v", usage)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Other changes are walked again once the interval is over.
+	write("d.txt", "0")
+	hub.Publish(events.Event{Type: events.Create, Path: filepath.Join(root, "d.txt")})
+	tracker.Interval = time.Nanosecond
+	deadline = time.Now().Add(time.Second)
+	for {
+		usage, _ := tracker.Usage(root)
+		if usage == (Usage{Bytes: 9, Files: 3}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the change to be walked, got %+v", usage)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	root := t.TempDir()
+	name := filepath.Join(root, "upload.bin")
+	if err := os.WriteFile(name, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewTracker()
+	expect := func(want Usage) {
+		t.Helper()
+		usage, err := tracker.Usage(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage != want {
+			t.Errorf("expected %+v, got %+v", want, usage)
+		}
+	}
+
+	tracker.Reserve(root, name, 10)
+	expect(Usage{Bytes: 10, Files: 1})
+
+	// Writes are taken out of the reservation.
+	if err := os.WriteFile(name, []byte("0123"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Use(root, name, 4)
+	expect(Usage{Bytes: 10, Files: 1})
+	if reserved := tracker.Reserved(root, name); reserved != 6 {
+		t.Errorf("expected 6 bytes left reserved, got %d", reserved)
+	}
+
+	// Reservations survive walks.
+	tracker.Invalidate(root)
+	expect(Usage{Bytes: 10, Files: 1})
+
+	tracker.Release(root, name)
+	expect(Usage{Bytes: 4, Files: 1})
+
+	// And are dropped with their file.
+	tracker.Reserve(root, name, 10)
+	tracker.update(events.Event{Type: events.Delete, Path: name})
+	if reserved := tracker.Reserved(root, name); reserved != 0 {
+		t.Errorf("expected the reservation to be dropped, got %d", reserved)
+	}
+}
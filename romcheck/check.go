@@ -0,0 +1,412 @@
+package romcheck
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/archive"
+	"github.com/filebrowser/filebrowser/v2/rules"
+)
+
+// Status is the result of the verification of a game or ROM.
+type Status string
+
+const (
+	// Complete games have all their ROMs, with the right CRC32.
+	Complete Status = "complete"
+	// Missing games have none of their ROMs.
+	Missing Status = "missing"
+	// BadDump games have some of their ROMs missing or with a CRC32
+	// other than the one of the DAT file.
+	BadDump Status = "bad-dump"
+	// Unknown files and sets of the directory match no game.
+	Unknown Status = "unknown"
+)
+
+// ROMResult is the verification of a ROM of a game.
+type ROMResult struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// Path is the file the ROM was found in, with the name of the
+	// entry for ROMs inside archives.
+	Path string `json:"path,omitempty"`
+	// CRC is the lower case hex CRC32 of what was found.
+	CRC string `json:"crc,omitempty"`
+}
+
+// Result is the verification of a game. Unknown results have no
+// game, only the path of what wasn't recognized.
+type Result struct {
+	Game   string       `json:"game,omitempty"`
+	Status Status       `json:"status"`
+	Path   string       `json:"path,omitempty"`
+	ROMs   []*ROMResult `json:"roms,omitempty"`
+}
+
+// Report is the verification of a directory against a DAT file.
+type Report struct {
+	Dat      string    `json:"dat"`
+	Path     string    `json:"path"`
+	Complete int       `json:"complete"`
+	Missing  int       `json:"missing"`
+	BadDump  int       `json:"badDump"`
+	Unknown  int       `json:"unknown"`
+	Results  []*Result `json:"results"`
+}
+
+// member is a file of a set.
+type member struct {
+	name string
+	path string
+	crc  uint32
+}
+
+// set is a zip, 7z or other archive, or a directory, holding the ROMs
+// of a game. Loose ROMs, stored right in the checked directory, are
+// sets of their own.
+type set struct {
+	path    string
+	archive bool
+	dir     bool
+	loaded  bool
+	used    bool
+	members []*member
+}
+
+type checker struct {
+	ctx     context.Context
+	fs      afero.Fs
+	rules   rules.Checker
+	sets    map[string]*set
+	loose   map[string]*set
+	ordered []*set
+}
+
+// Check verifies the sets in the directory dir of fs against the
+// games of dat. Each game is looked for in an archive or directory
+// named after it and then, for sets of a single ROM, as a loose file
+// named after the ROM. Everything else in dir is reported unknown.
+// Paths rejected by checker, if set, are ignored.
+func Check(ctx context.Context, fs afero.Fs, dir string, dat *Dat, checker rules.Checker) (*Report, error) {
+	dir = path.Clean("/" + dir)
+	c, err := newChecker(ctx, fs, dir, checker)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Dat: dat.Name, Path: dir, Results: []*Result{}}
+	for _, game := range dat.Games {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result, err := c.checkGame(game)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	for _, s := range c.ordered {
+		if !s.used {
+			report.Results = append(report.Results, &Result{Status: Unknown, Path: s.path})
+		}
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case Complete:
+			report.Complete++
+		case Missing:
+			report.Missing++
+		case BadDump:
+			report.BadDump++
+		case Unknown:
+			report.Unknown++
+		}
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Game != b.Game {
+			return a.Game < b.Game
+		}
+		return a.Path < b.Path
+	})
+	return report, nil
+}
+
+func newChecker(ctx context.Context, fs afero.Fs, dir string, rulesChecker rules.Checker) (*checker, error) {
+	infos, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{
+		ctx:   ctx,
+		fs:    fs,
+		rules: rulesChecker,
+		sets:  map[string]*set{},
+		loose: map[string]*set{},
+	}
+	for _, info := range infos {
+		name := info.Name()
+		p := path.Join(dir, name)
+		if strings.HasPrefix(name, ".") || !c.allowed(p) {
+			continue
+		}
+
+		s := &set{path: p}
+		switch {
+		case info.IsDir():
+			s.dir = true
+			c.sets[strings.ToLower(name)] = s
+		case archive.IsArchive(name):
+			s.archive = true
+			c.sets[strings.ToLower(setName(name))] = s
+			// A zipped No-Intro ROM might be named after the ROM too.
+			c.loose[strings.ToLower(name)] = s
+		case info.Mode().IsRegular():
+			c.loose[strings.ToLower(name)] = s
+		default:
+			continue
+		}
+		c.ordered = append(c.ordered, s)
+	}
+
+	return c, nil
+}
+
+// setName strips the archive extension off name.
+func setName(name string) string {
+	format, _ := archive.FormatOf(name)
+	ext := format.Extension()
+	if strings.HasSuffix(strings.ToLower(name), ext) {
+		return name[:len(name)-len(ext)]
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+func (c *checker) allowed(p string) bool {
+	return c.rules == nil || c.rules.Check(p)
+}
+
+func (c *checker) checkGame(game *Game) (*Result, error) {
+	result := &Result{Game: game.Name}
+
+	candidates := []*set{}
+	ownSet, hasOwn := c.sets[strings.ToLower(game.Name)]
+	if hasOwn {
+		candidates = append(candidates, ownSet)
+		result.Path = ownSet.path
+	}
+	if game.CloneOf != "" {
+		// Merged sets keep clones in the set of their parent, split
+		// ones only the ROMs they share with it.
+		if parent, ok := c.sets[strings.ToLower(game.CloneOf)]; ok {
+			candidates = append(candidates, parent)
+		}
+	}
+	for _, s := range candidates {
+		if err := c.load(s); err != nil {
+			return nil, err
+		}
+	}
+
+	// ROMs shared with the parent don't tell whether the clone
+	// itself is there.
+	present, presentOwn, unmerged := 0, 0, 0
+	for _, rom := range game.ROMs {
+		if rom.Status == StatusNoDump {
+			continue
+		}
+		if rom.Merge == "" {
+			unmerged++
+		}
+
+		rr, err := c.checkROM(rom, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if rr.Status != Missing {
+			present++
+			if rom.Merge == "" {
+				presentOwn++
+			}
+		}
+		result.ROMs = append(result.ROMs, rr)
+	}
+
+	switch {
+	case !hasOwn && (present == 0 || (unmerged > 0 && presentOwn == 0)) && len(result.ROMs) > 0:
+		result.Status = Missing
+	case allComplete(result.ROMs):
+		result.Status = Complete
+	default:
+		result.Status = BadDump
+	}
+
+	// Only problems are worth detailing.
+	if result.Status != BadDump {
+		result.ROMs = nil
+	}
+	if hasOwn {
+		ownSet.used = true
+	}
+	return result, nil
+}
+
+func allComplete(roms []*ROMResult) bool {
+	for _, rr := range roms {
+		if rr.Status != Complete {
+			return false
+		}
+	}
+	return true
+}
+
+// checkROM looks for rom in candidates, by name and then by CRC32 in
+// case it was renamed, and then for a loose file named after it.
+func (c *checker) checkROM(rom *ROM, candidates []*set) (*ROMResult, error) {
+	rr := &ROMResult{Name: rom.Name, Status: Missing}
+
+	names := []string{strings.ToLower(rom.Name)}
+	if rom.Merge != "" {
+		names = append(names, strings.ToLower(rom.Merge))
+	}
+
+	for _, s := range candidates {
+		for _, m := range s.members {
+			for _, name := range names {
+				if strings.ToLower(m.name) == name {
+					return found(rr, rom, m), nil
+				}
+			}
+		}
+	}
+	if rom.CRC != "" {
+		for _, s := range candidates {
+			for _, m := range s.members {
+				if fmt.Sprintf("%08x", m.crc) == rom.CRC {
+					return found(rr, rom, m), nil
+				}
+			}
+		}
+	}
+
+	if s, ok := c.loose[strings.ToLower(rom.Name)]; ok {
+		if err := c.load(s); err != nil {
+			return nil, err
+		}
+		s.used = true
+		// A single ROM, loose or zipped.
+		if len(s.members) == 1 {
+			return found(rr, rom, s.members[0]), nil
+		}
+	}
+
+	return rr, nil
+}
+
+func found(rr *ROMResult, rom *ROM, m *member) *ROMResult {
+	rr.Path = m.path
+	rr.CRC = fmt.Sprintf("%08x", m.crc)
+	if rom.CRC == "" || rr.CRC == rom.CRC {
+		rr.Status = Complete
+	} else {
+		rr.Status = BadDump
+	}
+	return rr
+}
+
+// load reads the names and CRC32 of the members of s.
+func (c *checker) load(s *set) error {
+	if s.loaded {
+		return nil
+	}
+	s.loaded = true
+
+	switch {
+	case s.archive:
+		return archive.Walk(c.fs, s.path, func(e *archive.Entry, r io.Reader) error {
+			if err := c.ctx.Err(); err != nil {
+				return err
+			}
+			if e.IsDir || !e.Mode.IsRegular() {
+				return nil
+			}
+
+			sum := e.CRC32
+			if sum == 0 && e.Size > 0 {
+				// Only zip and 7z archives store checksums.
+				h := crc32.NewIEEE()
+				if _, err := io.Copy(h, r); err != nil {
+					return err
+				}
+				sum = h.Sum32()
+			}
+			s.members = append(s.members, &member{name: path.Base(e.Name), path: s.path + "/" + e.Name, crc: sum})
+			return nil
+		})
+	case s.dir:
+		infos, err := afero.ReadDir(c.fs, s.path)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			p := path.Join(s.path, info.Name())
+			if !info.Mode().IsRegular() || !c.allowed(p) {
+				continue
+			}
+			if err := c.addFile(s, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return c.addFile(s, s.path)
+	}
+}
+
+func (c *checker) addFile(s *set, p string) error {
+	sum, err := CRC32(c.ctx, c.fs, p)
+	if err != nil {
+		return err
+	}
+	s.members = append(s.members, &member{name: path.Base(p), path: p, crc: sum})
+	return nil
+}
+
+// CRC32 returns the IEEE CRC32 of the file at name.
+func CRC32(ctx context.Context, fs afero.Fs, name string) (uint32, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, &ctxReader{ctx: ctx, r: f}); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
@@ -0,0 +1,346 @@
+// Package romcheck verifies ROM sets against DAT files, the lists of
+// known good dumps published by projects such as MAME, No-Intro and
+// Redump in the Logiqx XML or ClrMamePro formats.
+package romcheck
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+// Statuses a DAT file can give to a ROM.
+const (
+	StatusGood    = "good"
+	StatusBadDump = "baddump"
+	StatusNoDump  = "nodump"
+)
+
+// Dat is a parsed DAT file.
+type Dat struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Version     string  `json:"version"`
+	Games       []*Game `json:"games"`
+}
+
+// Game is a game, or machine, of a DAT file.
+type Game struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// CloneOf is the name of the parent of clones. Their set may
+	// rely on ROMs stored in the set of the parent.
+	CloneOf string `json:"cloneOf,omitempty"`
+	ROMs    []*ROM `json:"roms"`
+}
+
+// ROM is a file of a game.
+type ROM struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// CRC is the lower case hex CRC32 of the ROM.
+	CRC  string `json:"crc"`
+	SHA1 string `json:"sha1,omitempty"`
+	// Merge is the name of the ROM in the set of the parent.
+	Merge  string `json:"merge,omitempty"`
+	Status string `json:"status"`
+}
+
+// Load reads the DAT file at path.
+func Load(path string) (*Dat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dat, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return dat, nil
+}
+
+// Parse reads a DAT file in the Logiqx XML or ClrMamePro format.
+func Parse(r io.Reader) (*Dat, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("empty DAT file: %w", fbErrors.ErrInvalidDataType)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == 0xEF || b == 0xBB || b == 0xBF {
+			// Whitespace and the UTF-8 byte order mark.
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return nil, err
+		}
+
+		if b == '<' {
+			return parseXML(br)
+		}
+		return parseClrMamePro(br)
+	}
+}
+
+type xmlDatafile struct {
+	Header struct {
+		Name        string `xml:"name"`
+		Description string `xml:"description"`
+		Version     string `xml:"version"`
+	} `xml:"header"`
+	Games    []xmlGame `xml:"game"`
+	Machines []xmlGame `xml:"machine"`
+}
+
+type xmlGame struct {
+	Name        string `xml:"name,attr"`
+	CloneOf     string `xml:"cloneof,attr"`
+	Description string `xml:"description"`
+	ROMs        []struct {
+		Name   string `xml:"name,attr"`
+		Size   int64  `xml:"size,attr"`
+		CRC    string `xml:"crc,attr"`
+		SHA1   string `xml:"sha1,attr"`
+		Merge  string `xml:"merge,attr"`
+		Status string `xml:"status,attr"`
+	} `xml:"rom"`
+}
+
+func parseXML(r io.Reader) (*Dat, error) {
+	var df xmlDatafile
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&df); err != nil {
+		return nil, fmt.Errorf("invalid XML DAT file: %v: %w", err, fbErrors.ErrInvalidDataType)
+	}
+
+	dat := &Dat{
+		Name:        df.Header.Name,
+		Description: df.Header.Description,
+		Version:     df.Header.Version,
+	}
+	for _, g := range append(df.Games, df.Machines...) {
+		game := &Game{Name: g.Name, Description: g.Description, CloneOf: g.CloneOf}
+		for _, rom := range g.ROMs {
+			game.ROMs = append(game.ROMs, newROM(rom.Name, rom.Size, rom.CRC, rom.SHA1, rom.Merge, rom.Status))
+		}
+		dat.Games = append(dat.Games, game)
+	}
+
+	return dat, nil
+}
+
+func newROM(name string, size int64, crc, sha1, merge, status string) *ROM {
+	if status == "" {
+		status = StatusGood
+	}
+	crc = strings.ToLower(crc)
+	if crc != "" && len(crc) < 8 {
+		crc = strings.Repeat("0", 8-len(crc)) + crc
+	}
+	return &ROM{
+		Name:   name,
+		Size:   size,
+		CRC:    crc,
+		SHA1:   strings.ToLower(sha1),
+		Merge:  merge,
+		Status: status,
+	}
+}
+
+// cmpNode is a value of a ClrMamePro DAT file: either a string or a
+// parenthesized list of key and value pairs.
+type cmpNode struct {
+	key      string
+	value    string
+	children []*cmpNode
+}
+
+func (n *cmpNode) get(key string) string {
+	for _, c := range n.children {
+		if c.key == key {
+			return c.value
+		}
+	}
+	return ""
+}
+
+func parseClrMamePro(r io.Reader) (*Dat, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &cmpTokenizer{data: data}
+	root, err := t.parseBlock(true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ClrMamePro DAT file: %v: %w", err, fbErrors.ErrInvalidDataType)
+	}
+
+	dat := &Dat{}
+	for _, block := range root.children {
+		switch block.key {
+		case "clrmamepro":
+			dat.Name = block.get("name")
+			dat.Description = block.get("description")
+			dat.Version = block.get("version")
+		case "game", "machine", "resource":
+			game := &Game{
+				Name:        block.get("name"),
+				Description: block.get("description"),
+				CloneOf:     block.get("cloneof"),
+			}
+			for _, rom := range block.children {
+				if rom.key != "rom" {
+					continue
+				}
+				size, _ := strconv.ParseInt(rom.get("size"), 10, 64)
+				status := rom.get("status")
+				if flags := rom.get("flags"); flags != "" {
+					status = flags
+				}
+				game.ROMs = append(game.ROMs, newROM(rom.get("name"), size, rom.get("crc"), rom.get("sha1"), rom.get("merge"), status))
+			}
+			dat.Games = append(dat.Games, game)
+		}
+	}
+
+	if len(dat.Games) == 0 && dat.Name == "" {
+		return nil, fmt.Errorf("no games in DAT file: %w", fbErrors.ErrInvalidDataType)
+	}
+	return dat, nil
+}
+
+type cmpTokenizer struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next token, quoted reporting whether it was a
+// quoted string. It returns "" at the end of the data.
+func (t *cmpTokenizer) next() (token string, quoted bool, err error) {
+	for t.pos < len(t.data) && isSpace(t.data[t.pos]) {
+		t.pos++
+	}
+	if t.pos >= len(t.data) {
+		return "", false, nil
+	}
+
+	switch c := t.data[t.pos]; c {
+	case '(', ')':
+		t.pos++
+		return string(c), false, nil
+	case '"':
+		end := bytes.IndexByte(t.data[t.pos+1:], '"')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated string at byte %d", t.pos)
+		}
+		token = string(t.data[t.pos+1 : t.pos+1+end])
+		t.pos += end + 2
+		return token, true, nil
+	default:
+		start := t.pos
+		for t.pos < len(t.data) && !isSpace(t.data[t.pos]) && t.data[t.pos] != '(' && t.data[t.pos] != ')' {
+			t.pos++
+		}
+		return string(t.data[start:t.pos]), false, nil
+	}
+}
+
+// parseBlock parses key and value pairs up to the closing
+// parenthesis or, for the top level, the end of the data.
+func (t *cmpTokenizer) parseBlock(top bool) (*cmpNode, error) {
+	node := &cmpNode{}
+	for {
+		key, quoted, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case key == "" && !quoted:
+			if !top {
+				return nil, fmt.Errorf("missing closing parenthesis")
+			}
+			return node, nil
+		case key == ")" && !quoted:
+			if top {
+				return nil, fmt.Errorf("unexpected closing parenthesis at byte %d", t.pos)
+			}
+			return node, nil
+		}
+
+		value, quoted, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case value == "(" && !quoted:
+			child, err := t.parseBlock(false)
+			if err != nil {
+				return nil, err
+			}
+			child.key = key
+			node.children = append(node.children, child)
+		case (value == "" || value == ")") && !quoted:
+			return nil, fmt.Errorf("missing value for %q", key)
+		default:
+			node.children = append(node.children, &cmpNode{key: key, value: value})
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// DatFile describes a DAT file of a directory.
+type DatFile struct {
+	File        string `json:"file"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Games       int    `json:"games"`
+}
+
+// List returns the DAT files, named *.dat or *.xml, in dir sorted by
+// file name. Files that can't be parsed are left out.
+func List(dir string) ([]*DatFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*DatFile{}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".dat" && ext != ".xml") {
+			continue
+		}
+
+		dat, err := Load(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		list = append(list, &DatFile{
+			File:        e.Name(),
+			Name:        dat.Name,
+			Description: dat.Description,
+			Version:     dat.Version,
+			Games:       len(dat.Games),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+	return list, nil
+}
@@ -0,0 +1,218 @@
+package romcheck
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+func crc(s string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(s)))
+}
+
+const clrMameProDat = `clrmamepro (
+	name "Arcade"
+	description "Arcade (test)"
+	version 0.1
+)
+
+game (
+	name pacman
+	description "Pac-Man"
+	rom ( name pacman.6e size 6 crc %s )
+	rom ( name pacman.6f size 4 crc %s )
+)
+
+game (
+	name puckman
+	cloneof pacman
+	rom ( name pacman.6e merge pacman.6e size 6 crc %s )
+	rom ( name puckman.6h size 7 crc %s )
+)
+
+game (
+	name galaga
+	rom ( name galaga.1 size 6 crc %s )
+	rom ( name galaga.2 size 0 flags nodump )
+)
+
+game (
+	name tetris
+	rom ( name tetris.gb size 6 crc %s )
+)
+
+game (
+	name dkong
+	rom ( name dkong.5h size 5 crc %s )
+)
+`
+
+func TestParse(t *testing.T) {
+	xmlDat := `<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Game Boy</name>
+		<version>20260101</version>
+	</header>
+	<game name="Tetris (World)">
+		<description>Tetris (World)</description>
+		<rom name="Tetris (World).gb" size="32768" crc="46DF91AD" sha1="74591CC9501AF93873F9A5D3EB12DA12C0723BBC"/>
+	</game>
+	<machine name="clone" cloneof="parent">
+		<rom name="a.bin" size="1" crc="ff" status="baddump"/>
+	</machine>
+</datafile>`
+
+	dat, err := Parse(strings.NewReader(xmlDat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dat.Name != "Nintendo - Game Boy" || dat.Version != "20260101" || len(dat.Games) != 2 {
+		t.Fatalf("unexpected DAT %+v", dat)
+	}
+	rom := dat.Games[0].ROMs[0]
+	if rom.CRC != "46df91ad" || rom.Size != 32768 || rom.Status != StatusGood || rom.SHA1 != "74591cc9501af93873f9a5d3eb12da12c0723bbc" {
+		t.Errorf("unexpected ROM %+v", rom)
+	}
+	if g := dat.Games[1]; g.CloneOf != "parent" || g.ROMs[0].CRC != "000000ff" || g.ROMs[0].Status != StatusBadDump {
+		t.Errorf("unexpected machine %+v", g)
+	}
+
+	dat, err = Parse(strings.NewReader(fmt.Sprintf(clrMameProDat, "1", "2", "3", "4", "5", "6", "7")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dat.Name != "Arcade" || dat.Description != "Arcade (test)" || dat.Version != "0.1" || len(dat.Games) != 5 {
+		t.Fatalf("unexpected DAT %+v", dat)
+	}
+	puckman := dat.Games[1]
+	if puckman.CloneOf != "pacman" || len(puckman.ROMs) != 2 || puckman.ROMs[0].Merge != "pacman.6e" || puckman.ROMs[1].CRC != "00000004" {
+		t.Errorf("unexpected clone %+v", puckman)
+	}
+	if rom := dat.Games[2].ROMs[1]; rom.Status != StatusNoDump {
+		t.Errorf("unexpected ROM %+v", rom)
+	}
+
+	for _, invalid := range []string{"", "game ( name x", "<datafile>"} {
+		if _, err := Parse(strings.NewReader(invalid)); !errors.Is(err, fbErrors.ErrInvalidDataType) {
+			t.Errorf("%q: expected ErrInvalidDataType, got %v", invalid, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dat, err := Parse(strings.NewReader(fmt.Sprintf(clrMameProDat,
+		crc("pacman"), crc("6f.."), crc("pacman"), crc("puckman"), crc("galaga"), crc("tetris"), crc("dkong"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := afero.NewMemMapFs()
+	// A merged set, with the clone inside the set of its parent.
+	writeZip(t, fs, "/roms/pacman.zip", map[string]string{
+		"pacman.6e":  "pacman",
+		"pacman.6f":  "6f..",
+		"puckman.6h": "puckman",
+	})
+	// A renamed ROM with a bad dump, found by name.
+	writeZip(t, fs, "/roms/galaga.zip", map[string]string{"galaga.1": "galaxy"})
+	writeFile(t, fs, "/roms/tetris.gb", "tetris")
+	writeFile(t, fs, "/roms/readme.txt", "hello")
+	writeFile(t, fs, "/roms/.hidden", "hidden")
+
+	report, err := Check(context.Background(), fs, "roms", dat, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Dat != "Arcade" || report.Path != "/roms" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Complete != 3 || report.Missing != 1 || report.BadDump != 1 || report.Unknown != 1 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+
+	statuses := map[string]Status{}
+	for _, r := range report.Results {
+		statuses[r.Game+"|"+r.Path] = r.Status
+		if r.Game == "galaga" {
+			if len(r.ROMs) != 1 || r.ROMs[0].Status != BadDump || r.ROMs[0].CRC != crc("galaxy") || r.ROMs[0].Path != "/roms/galaga.zip/galaga.1" {
+				t.Errorf("unexpected ROMs %+v", r.ROMs[0])
+			}
+		} else if len(r.ROMs) != 0 {
+			t.Errorf("%s: only bad dumps should detail their ROMs", r.Game)
+		}
+	}
+	for key, status := range map[string]Status{
+		"pacman|/roms/pacman.zip": Complete,
+		"puckman|":                Complete,
+		"galaga|/roms/galaga.zip": BadDump,
+		"tetris|":                 Complete,
+		"dkong|":                  Missing,
+		"|/roms/readme.txt":       Unknown,
+	} {
+		if statuses[key] != status {
+			t.Errorf("%s: expected %s, got %q", key, status, statuses[key])
+		}
+	}
+
+	denyTetris := checkerFunc(func(p string) bool { return p != "/roms/tetris.gb" })
+	report, err = Check(context.Background(), fs, "/roms", dat, denyTetris)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing != 2 {
+		t.Errorf("expected denied files to be ignored, got %+v", report)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Check(ctx, fs, "/roms", dat, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+type checkerFunc func(string) bool
+
+func (f checkerFunc) Check(p string) bool {
+	return f(p)
+}
+
+func writeFile(t *testing.T, fs afero.Fs, name, content string) {
+	t.Helper()
+	if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, fs afero.Fs, name string, entries map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for entry, content := range entries {
+		w, err := zw.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, name, buf.String())
+}
@@ -0,0 +1,81 @@
+package rules
+
+// Match is a rule that matches a path.
+type Match struct {
+	// Source is the name of the source of the rule.
+	Source string `json:"source"`
+	// Index is the position of the rule in its list.
+	Index int  `json:"index"`
+	Rule  Rule `json:"rule"`
+}
+
+// Decision is whether something is allowed, and which rule decided it.
+type Decision struct {
+	Allow bool `json:"allow"`
+	// Rule is the index in Matches of the rule that decided, or -1 if
+	// none did and it's allowed by default.
+	Rule int `json:"rule"`
+}
+
+// Explanation tells which rules match a path, and what they decide.
+type Explanation struct {
+	Path string `json:"path"`
+	// Dotfile is set when the path is hidden because it's a dotfile
+	// and the user hides them, whatever the rules say.
+	Dotfile bool                `json:"dotfile"`
+	Matches []Match             `json:"matches"`
+	Visible Decision            `json:"visible"`
+	Actions map[Action]Decision `json:"actions"`
+}
+
+// Source is a list of rules, named after where it comes from.
+type Source struct {
+	Name  string
+	Rules []Rule
+}
+
+// Explain tells which rules of the sources match path, and whether it
+// can be seen and each action done on it as a result. The rules of the
+// last sources take precedence, as with Allowed. Actions can't be done
+// on what can't be seen, so they're decided by whatever hides the path
+// then.
+func Explain(path string, hideDotfiles bool, sources ...Source) *Explanation {
+	e := &Explanation{
+		Path:    path,
+		Dotfile: hideDotfiles && MatchHidden(path),
+		Matches: []Match{},
+		Visible: Decision{Allow: true, Rule: -1},
+		Actions: map[Action]Decision{},
+	}
+	for _, action := range Actions {
+		e.Actions[action] = Decision{Allow: true, Rule: -1}
+	}
+
+	for _, source := range sources {
+		for i := range source.Rules {
+			rule := source.Rules[i]
+			if !rule.Matches(path) {
+				continue
+			}
+
+			e.Matches = append(e.Matches, Match{Source: source.Name, Index: i, Rule: rule})
+			decision := Decision{Allow: rule.Allow, Rule: len(e.Matches) - 1}
+			if rule.Decides("") {
+				e.Visible = decision
+			}
+			for _, action := range rule.Actions {
+				e.Actions[action] = decision
+			}
+		}
+	}
+
+	if e.Dotfile {
+		e.Visible = Decision{Allow: false, Rule: -1}
+	}
+	if !e.Visible.Allow {
+		for action := range e.Actions {
+			e.Actions[action] = e.Visible
+		}
+	}
+	return e
+}
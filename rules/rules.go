@@ -1,9 +1,14 @@
 package rules
 
 import (
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
 )
 
 // Checker is a Rules checker.
@@ -11,12 +16,105 @@ type Checker interface {
 	Check(path string) bool
 }
 
-// Rule is a allow/disallow rule.
+// ActionChecker is a Rules checker that also tells which actions can
+// be done on the paths it lets through.
+type ActionChecker interface {
+	Checker
+	CheckAction(path string, action Action) bool
+}
+
+// ForAction returns a Checker letting through the paths on which c
+// allows action, or nil if c is nil.
+func ForAction(c ActionChecker, action Action) Checker {
+	if c == nil {
+		return nil
+	}
+	return actionChecker{c, action}
+}
+
+type actionChecker struct {
+	c      ActionChecker
+	action Action
+}
+
+func (a actionChecker) Check(path string) bool {
+	return a.c.CheckAction(path, a.action)
+}
+
+// Action is something done to files that rules can allow or deny.
+type Action string
+
+const (
+	// Read is downloading files and listing what directories hold.
+	Read Action = "read"
+	// Create is adding files, be it by uploading, copying, moving or
+	// extracting them.
+	Create Action = "create"
+	// Modify is overwriting files.
+	Modify Action = "modify"
+	// Delete is deleting files, or moving them away.
+	Delete Action = "delete"
+)
+
+// Actions are the actions rules can allow or deny.
+var Actions = []Action{Read, Create, Modify, Delete}
+
+// ParseAction parses the name of an action.
+func ParseAction(name string) (Action, error) {
+	for _, action := range Actions {
+		if string(action) == name {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("unknown action %q: %w", name, fbErrors.ErrInvalidOption)
+}
+
+// Rule is a allow/disallow rule. Rules without actions hide the paths
+// they match unless they allow them. Rules with actions leave the
+// paths visible, and only allow or deny these actions on them.
+//
+// Rules match the paths that start with Path, unless they're Regex
+// rules, which match those that match Regexp, or Glob rules, which
+// match the paths within those that match the doublestar pattern in
+// Path, such as "**/*.srm".
 type Rule struct {
-	Regex  bool    `json:"regex"`
-	Allow  bool    `json:"allow"`
-	Path   string  `json:"path"`
-	Regexp *Regexp `json:"regexp"`
+	Regex   bool     `json:"regex"`
+	Glob    bool     `json:"glob"`
+	Allow   bool     `json:"allow"`
+	Path    string   `json:"path"`
+	Regexp  *Regexp  `json:"regexp"`
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// Decides reports whether the rule decides if action can be done on
+// the paths it matches. The empty action is seeing them.
+func (r *Rule) Decides(action Action) bool {
+	if action == "" {
+		return len(r.Actions) == 0
+	}
+
+	for _, a := range r.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether action can be done on path, or if it can be
+// seen for the empty action, according to lists of rules. Everything
+// is allowed unless a rule denies it, and the rules of the last lists
+// take precedence, as do the last rules of each list.
+func Allowed(path string, action Action, lists ...[]Rule) bool {
+	allow := true
+	for _, list := range lists {
+		for i := range list {
+			if list[i].Decides(action) && list[i].Matches(path) {
+				allow = list[i].Allow
+			}
+		}
+	}
+	return allow
 }
 
 // MatchHidden matches paths with a basename
@@ -27,13 +125,68 @@ func MatchHidden(path string) bool {
 
 // Matches matches a path against a rule.
 func (r *Rule) Matches(path string) bool {
-	if r.Regex {
+	switch {
+	case r.Regex:
 		return r.Regexp.MatchString(path)
+	case r.Glob:
+		return matchGlob(r.Path, path)
 	}
 
 	return strings.HasPrefix(path, r.Path)
 }
 
+// matchGlob reports whether path or one of the directories it's in
+// matches pattern. The leading slash of both is optional.
+func matchGlob(pattern, path string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	path = strings.Trim(path, "/")
+	for {
+		if ok, _ := doublestar.Match(pattern, path); ok {
+			return true
+		}
+		i := strings.LastIndexByte(path, '/')
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+// Compile checks the rule, and compiles its regular expression so
+// that it's not done when it's first matched.
+func (r *Rule) Compile() error {
+	for _, action := range r.Actions {
+		if _, err := ParseAction(string(action)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case r.Regex && r.Glob:
+		return fmt.Errorf("rule is both a regex and a glob: %w", fbErrors.ErrInvalidOption)
+	case r.Regex:
+		if r.Regexp == nil {
+			return fmt.Errorf("regex rule without expression: %w", fbErrors.ErrInvalidOption)
+		}
+		return r.Regexp.Compile()
+	case r.Glob:
+		if !doublestar.ValidatePattern(strings.TrimPrefix(r.Path, "/")) {
+			return fmt.Errorf("invalid glob %q: %w", r.Path, fbErrors.ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// Compile compiles a list of rules, failing on the first invalid one.
+func Compile(list []Rule) error {
+	for i := range list {
+		if err := list[i].Compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
 // Regexp is a wrapper to the native regexp type where we
 // save the raw expression.
 type Regexp struct {
@@ -41,10 +194,24 @@ type Regexp struct {
 	regexp *regexp.Regexp
 }
 
-// MatchString checks if a string matches the regexp.
+// Compile compiles the expression.
+func (r *Regexp) Compile() error {
+	exp, err := regexp.Compile(r.Raw)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %w", r.Raw, fbErrors.ErrInvalidOption)
+	}
+	r.regexp = exp
+	return nil
+}
+
+// MatchString checks if a string matches the regexp. Invalid
+// expressions, which can't be saved, match nothing.
 func (r *Regexp) MatchString(s string) bool {
-	if r.regexp == nil {
-		r.regexp = regexp.MustCompile(r.Raw)
+	if r == nil {
+		return false
+	}
+	if r.regexp == nil && r.Compile() != nil {
+		return false
 	}
 
 	return r.regexp.MatchString(s)
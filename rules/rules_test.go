@@ -1,6 +1,11 @@
 package rules
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
 
 func TestMatchHidden(t *testing.T) {
 	cases := map[string]bool{
@@ -21,3 +26,130 @@ func TestMatchHidden(t *testing.T) {
 		}
 	}
 }
+
+func TestAllowed(t *testing.T) {
+	global := []Rule{
+		{Path: "/private"},
+		{Path: "/boot", Actions: []Action{Create, Modify, Delete}},
+		{Regex: true, Regexp: &Regexp{Raw: `\.bin$`}, Actions: []Action{Delete}},
+	}
+	user := []Rule{
+		{Path: "/incoming", Actions: []Action{Read}},
+		{Path: "/boot/config", Allow: true, Actions: []Action{Modify}},
+	}
+
+	cases := []struct {
+		path   string
+		action Action
+		want   bool
+	}{
+		{"/private/a", "", false},
+		{"/boot/kernel", "", true},
+		{"/boot/kernel", Read, true},
+		{"/boot/kernel", Modify, false},
+		{"/boot/kernel", Delete, false},
+		{"/boot/config/cmdline.txt", Modify, true},
+		{"/boot/config/cmdline.txt", Create, false},
+		{"/roms/bios/scph1001.bin", Delete, false},
+		{"/roms/bios/scph1001.bin", Modify, true},
+		{"/incoming", "", true},
+		{"/incoming/upload.zip", Read, false},
+		{"/incoming/upload.zip", Create, true},
+		{"/roms", Delete, true},
+	}
+
+	for _, c := range cases {
+		if got := Allowed(c.path, c.action, global, user); got != c.want {
+			t.Errorf("Allowed(%s, %q)=%v; want %v", c.path, c.action, got, c.want)
+		}
+	}
+
+	if _, err := ParseAction("write"); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"**/*.srm", "/saves/snes/zelda.srm", true},
+		{"**/*.srm", "/zelda.srm", true},
+		{"**/*.srm", "/saves/zelda.sfc", false},
+		{"/roms/*/bios", "/roms/psx/bios", true},
+		{"/roms/*/bios", "/roms/psx/bios/scph1001.bin", true},
+		{"/roms/*/bios", "/roms/bios", false},
+		{"*.txt", "/notes/a.txt", false},
+		{"/saves/**", "/saves", true},
+	}
+
+	for _, c := range cases {
+		rule := Rule{Glob: true, Path: c.pattern}
+		if got := rule.Matches(c.path); got != c.want {
+			t.Errorf("Matches(%s, %s)=%v; want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	valid := []Rule{
+		{Path: "/boot"},
+		{Glob: true, Path: "**/*.{srm,sav}"},
+		{Regex: true, Regexp: &Regexp{Raw: `\.bin$`}, Actions: []Action{Delete}},
+	}
+	if err := Compile(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	for _, rule := range []Rule{
+		{Regex: true, Regexp: &Regexp{Raw: "["}},
+		{Regex: true},
+		{Glob: true, Path: "/roms/["},
+		{Regex: true, Glob: true, Regexp: &Regexp{Raw: "a"}},
+		{Path: "/boot", Actions: []Action{"write"}},
+	} {
+		if err := rule.Compile(); !errors.Is(err, fbErrors.ErrInvalidOption) {
+			t.Errorf("expected %+v to be invalid, got %v", rule, err)
+		}
+	}
+
+	// Invalid expressions that were saved before match nothing.
+	rule := Rule{Regex: true, Regexp: &Regexp{Raw: "["}}
+	if rule.Matches("[") {
+		t.Error("expected an invalid regex not to match")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	global := []Rule{
+		{Path: "/saves"},
+		{Glob: true, Path: "**/*.srm", Actions: []Action{Delete}},
+	}
+	user := []Rule{
+		{Path: "/saves/snes", Allow: true},
+		{Path: "/private"},
+	}
+
+	sources := []Source{{Name: "global", Rules: global}, {Name: "user", Rules: user}}
+	e := Explain("/saves/snes/zelda.srm", false, sources...)
+	if len(e.Matches) != 3 || e.Matches[2].Source != "user" || e.Matches[2].Index != 0 {
+		t.Fatalf("unexpected matches %+v", e.Matches)
+	}
+	if e.Visible != (Decision{Allow: true, Rule: 2}) {
+		t.Errorf("unexpected visibility %+v", e.Visible)
+	}
+	if e.Actions[Delete] != (Decision{Allow: false, Rule: 1}) || e.Actions[Read] != (Decision{Allow: true, Rule: -1}) {
+		t.Errorf("unexpected actions %+v", e.Actions)
+	}
+
+	e = Explain("/private/.notes", false, sources...)
+	if e.Visible != (Decision{Allow: false, Rule: 0}) || e.Actions[Read] != e.Visible {
+		t.Errorf("expected the hiding rule to decide, got %+v", e)
+	}
+
+	e = Explain("/.notes", true, sources...)
+	if !e.Dotfile || e.Visible.Allow || e.Actions[Create].Allow {
+		t.Errorf("expected hidden dotfiles, got %+v", e)
+	}
+}
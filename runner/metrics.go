@@ -0,0 +1,10 @@
+package runner
+
+import "github.com/filebrowser/filebrowser/v2/metrics"
+
+var (
+	hookDuration = metrics.Default.NewHistogram("filebrowser_hook_duration_seconds",
+		"Time hook commands took to run, by trigger.", nil, "trigger")
+	hookFailures = metrics.Default.NewCounter("filebrowser_hook_failures_total",
+		"Number of hook commands that failed, by trigger.", "trigger")
+)
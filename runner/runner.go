@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/users"
@@ -25,7 +26,7 @@ func (r *Runner) RunHook(fn func() error, evt, path, dst string, user *users.Use
 	if r.Enabled {
 		if val, ok := r.Commands["before_"+evt]; ok {
 			for _, command := range val {
-				err := r.exec(command, "before_"+evt, path, dst, user)
+				err := r.timedExec(command, "before_"+evt, path, dst, user)
 				if err != nil {
 					return err
 				}
@@ -41,7 +42,7 @@ func (r *Runner) RunHook(fn func() error, evt, path, dst string, user *users.Use
 	if r.Enabled {
 		if val, ok := r.Commands["after_"+evt]; ok {
 			for _, command := range val {
-				err := r.exec(command, "after_"+evt, path, dst, user)
+				err := r.timedExec(command, "after_"+evt, path, dst, user)
 				if err != nil {
 					return err
 				}
@@ -52,6 +53,16 @@ func (r *Runner) RunHook(fn func() error, evt, path, dst string, user *users.Use
 	return nil
 }
 
+func (r *Runner) timedExec(raw, evt, path, dst string, user *users.User) error {
+	start := time.Now()
+	err := r.exec(raw, evt, path, dst, user)
+	hookDuration.Observe(time.Since(start).Seconds(), evt)
+	if err != nil {
+		hookFailures.Inc(evt)
+	}
+	return err
+}
+
 func (r *Runner) exec(raw, evt, path, dst string, user *users.User) error {
 	blocking := true
 
@@ -1,102 +1,231 @@
 package search
 
 import (
+	"fmt"
 	"mime"
+	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
-)
+	"time"
 
-var (
-	typeRegexp = regexp.MustCompile(`type:(\w+)`)
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
 )
 
-type condition func(path string) bool
+// entry is a file or directory the query is matched against.
+type entry struct {
+	// path is relative to the root of the filesystem, name is the
+	// base name of the file.
+	path string
+	name string
+	info os.FileInfo
+	// content holds the lines matching each content pattern of the
+	// query, once the file was read.
+	content [][]Match
+	grepped bool
+}
+
+type condition func(e *entry) bool
 
 func extensionCondition(extension string) condition {
-	return func(path string) bool {
-		return filepath.Ext(path) == "."+extension
+	return func(e *entry) bool {
+		return filepath.Ext(e.name) == "."+extension
 	}
 }
 
-func imageCondition(path string) bool {
-	extension := filepath.Ext(path)
+func imageCondition(e *entry) bool {
+	extension := filepath.Ext(e.name)
 	mimetype := mime.TypeByExtension(extension)
 
 	return strings.HasPrefix(mimetype, "image")
 }
 
-func audioCondition(path string) bool {
-	extension := filepath.Ext(path)
+func audioCondition(e *entry) bool {
+	extension := filepath.Ext(e.name)
 	mimetype := mime.TypeByExtension(extension)
 
 	return strings.HasPrefix(mimetype, "audio")
 }
 
-func videoCondition(path string) bool {
-	extension := filepath.Ext(path)
+func videoCondition(e *entry) bool {
+	extension := filepath.Ext(e.name)
 	mimetype := mime.TypeByExtension(extension)
 
 	return strings.HasPrefix(mimetype, "video")
 }
 
-func parseSearch(value string) *searchOptions {
-	opts := &searchOptions{
-		CaseSensitive: strings.Contains(value, "case:sensitive"),
-		Conditions:    []condition{},
-		Terms:         []string{},
+func typeCondition(value string) condition {
+	switch value {
+	case "image":
+		return imageCondition
+	case "audio", "music":
+		return audioCondition
+	case "video":
+		return videoCondition
+	default:
+		return extensionCondition(value)
 	}
+}
 
-	// removes the options from the value
-	value = strings.Replace(value, "case:insensitive", "", -1)
-	value = strings.Replace(value, "case:sensitive", "", -1)
-	value = strings.TrimSpace(value)
+// termCondition matches the names holding term.
+func termCondition(term string, caseSensitive bool) condition {
+	if !caseSensitive {
+		term = strings.ToLower(term)
+	}
+	return func(e *entry) bool {
+		name := e.name
+		if !caseSensitive {
+			name = strings.ToLower(name)
+		}
+		return strings.Contains(name, term)
+	}
+}
 
-	types := typeRegexp.FindAllStringSubmatch(value, -1)
-	for _, t := range types {
-		if len(t) == 1 {
-			continue
+// pathCondition matches the paths holding value, such as roms/snes.
+func pathCondition(value string, caseSensitive bool) condition {
+	value = strings.Trim(value, "/")
+	if !caseSensitive {
+		value = strings.ToLower(value)
+	}
+	return func(e *entry) bool {
+		p := e.path
+		if !caseSensitive {
+			p = strings.ToLower(p)
 		}
+		return strings.Contains(p, value)
+	}
+}
 
-		switch t[1] {
-		case "image":
-			opts.Conditions = append(opts.Conditions, imageCondition)
-		case "audio", "music":
-			opts.Conditions = append(opts.Conditions, audioCondition)
-		case "video":
-			opts.Conditions = append(opts.Conditions, videoCondition)
-		default:
-			opts.Conditions = append(opts.Conditions, extensionCondition(t[1]))
+// extCondition matches the extensions of a comma separated list, such
+// as zip,7z.
+func extCondition(value string) condition {
+	exts := map[string]bool{}
+	for _, ext := range strings.Split(value, ",") {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); ext != "" {
+			exts["."+strings.ToLower(ext)] = true
 		}
 	}
+	return func(e *entry) bool {
+		return !e.info.IsDir() && exts[strings.ToLower(path.Ext(e.name))]
+	}
+}
+
+func isCondition(value string) (condition, error) {
+	switch value {
+	case "dir":
+		return func(e *entry) bool { return e.info.IsDir() }, nil
+	case "file":
+		return func(e *entry) bool { return !e.info.IsDir() }, nil
+	default:
+		return nil, fmt.Errorf("invalid filter is:%s: %w", value, fbErrors.ErrInvalidRequestParams)
+	}
+}
 
-	if len(types) > 0 {
-		// Remove the fields from the search value.
-		value = typeRegexp.ReplaceAllString(value, "")
+// splitOperator splits a comparison such as >=100M into its operator
+// and value. Values without operator are compared for equality.
+func splitOperator(value string) (op, rest string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
 	}
+	return "=", value
+}
 
-	// If it's case insensitive, put everything in lowercase.
-	if !opts.CaseSensitive {
-		value = strings.ToLower(value)
+func compare[T int64 | float64](op string, a, b T) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	default:
+		return a == b
 	}
+}
 
-	// Remove the spaces from the search value.
-	value = strings.TrimSpace(value)
+var sizeUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
 
-	if value == "" {
-		return opts
+// sizeCondition matches the files whose size compares to the value,
+// such as >100M, in bytes or with a K, M, G or T binary suffix.
+func sizeCondition(value string) (condition, error) {
+	op, rest := splitOperator(value)
+	i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(rest)
+	}
+	n, err := strconv.ParseFloat(rest[:i], 64)
+	unit, ok := sizeUnits[strings.ToLower(rest[i:])]
+	if err != nil || !ok || n < 0 {
+		return nil, fmt.Errorf("invalid filter size:%s: %w", value, fbErrors.ErrInvalidRequestParams)
 	}
 
-	// if the value starts with " and finishes what that character, we will
-	// only search for that term
-	if value[0] == '"' && value[len(value)-1] == '"' {
-		unique := strings.TrimPrefix(value, "\"")
-		unique = strings.TrimSuffix(unique, "\"")
+	size := int64(n * float64(unit))
+	return func(e *entry) bool {
+		return !e.info.IsDir() && compare(op, e.info.Size(), size)
+	}, nil
+}
+
+var durationUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+	'y': 365 * 24 * time.Hour,
+}
 
-		opts.Terms = []string{unique}
-		return opts
+// modifiedCondition matches the modification times of a comparison
+// with an age, such as <7d for the last week, or with a date, such as
+// >2024-01-31. Ages without operator, or with =, mean "within".
+func modifiedCondition(value string, now time.Time) (condition, error) {
+	op, rest := splitOperator(value)
+
+	if day, err := time.ParseInLocation("2006-01-02", rest, now.Location()); err == nil {
+		next := day.AddDate(0, 0, 1)
+		return func(e *entry) bool {
+			t := e.info.ModTime()
+			switch op {
+			case ">":
+				return !t.Before(next)
+			case ">=":
+				return !t.Before(day)
+			case "<":
+				return t.Before(day)
+			case "<=":
+				return t.Before(next)
+			default:
+				return !t.Before(day) && t.Before(next)
+			}
+		}, nil
+	}
+
+	invalid := fmt.Errorf("invalid filter modified:%s: %w", value, fbErrors.ErrInvalidRequestParams)
+	if len(rest) < 2 { //nolint:gomnd
+		return nil, invalid
+	}
+	unit, ok := durationUnits[rest[len(rest)-1]]
+	n, err := strconv.ParseFloat(rest[:len(rest)-1], 64)
+	if !ok || err != nil || n < 0 {
+		return nil, invalid
+	}
+	if op == "=" {
+		op = "<"
 	}
 
-	opts.Terms = strings.Split(value, " ")
-	return opts
+	age := n * float64(unit)
+	return func(e *entry) bool {
+		return compare(op, float64(now.Sub(e.info.ModTime())), age)
+	}, nil
 }
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/afero"
+
+	"github.com/filebrowser/filebrowser/v2/files"
+)
+
+// MaxContentSize is the size above which files aren't searched by
+// content.
+var MaxContentSize int64 = 10 * 1024 * 1024
+
+const (
+	// maxMatches is the number of matching lines returned per file.
+	maxMatches = 50
+	// maxSnippet is the length, in bytes, of the snippets of the
+	// matching lines.
+	maxSnippet = 200
+	// maxLine is the length of the longest line read. Files with
+	// longer lines are only searched up to them.
+	maxLine = 1024 * 1024
+	// checkEvery is the number of lines read between checks of whether
+	// the search was cancelled.
+	checkEvery = 1024
+)
+
+// Match is a line of a file matching a content search.
+type Match struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// contentPattern is what a content: or grep: operator looks for.
+type contentPattern struct {
+	re *regexp.Regexp
+}
+
+func parseContentPattern(value string, caseSensitive bool) (*contentPattern, error) {
+	var expr string
+	switch {
+	case len(value) >= 2 && value[0] == '/' && value[len(value)-1] == '/':
+		expr = value[1 : len(value)-1]
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		expr = regexp.QuoteMeta(value[1 : len(value)-1])
+	default:
+		expr = regexp.QuoteMeta(value)
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &contentPattern{re: re}, nil
+}
+
+// grep returns the lines of the file at name matching each of the
+// patterns. Binary files and files bigger than MaxContentSize match
+// none. Reading stops, with the error of ctx, once it's done.
+func grep(ctx context.Context, fs afero.Fs, name string, size int64, patterns []*contentPattern) ([][]Match, error) {
+	matches := make([][]Match, len(patterns))
+	if size > MaxContentSize {
+		return matches, nil
+	}
+
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	head, err := br.Peek(512) //nolint:gomnd
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if files.IsBinary(head) {
+		return matches, nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine) //nolint:gomnd
+	for line := 1; scanner.Scan(); line++ {
+		if line%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		text := scanner.Bytes()
+		for i, p := range patterns {
+			if len(matches[i]) >= maxMatches {
+				continue
+			}
+			if loc := p.re.FindIndex(text); loc != nil {
+				matches[i] = append(matches[i], Match{Line: line, Text: snippet(text, loc[0], loc[1])})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// snippet returns line, or the part of it around the match between
+// start and end if it's too long.
+func snippet(line []byte, start, end int) string {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > maxSnippet {
+		from := start - (maxSnippet-(end-start))/2 //nolint:gomnd
+		if from < 0 {
+			from = 0
+		}
+		to := from + maxSnippet
+		if to > len(line) {
+			to, from = len(line), len(line)-maxSnippet
+		}
+		// Don't cut runes in half.
+		for from > 0 && !utf8.RuneStart(line[from]) {
+			from--
+		}
+		for to < len(line) && !utf8.RuneStart(line[to]) {
+			to--
+		}
+		line = line[from:to]
+	}
+	return strings.TrimSpace(string(line))
+}
@@ -0,0 +1,322 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+// node is a part of a parsed query.
+type node interface {
+	match(q *query, e *entry) bool
+	// costly reports whether matching the node reads files.
+	costly() bool
+}
+
+type andNode []node
+
+func (n andNode) match(q *query, e *entry) bool {
+	for _, child := range n {
+		if !child.match(q, e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n andNode) costly() bool {
+	for _, child := range n {
+		if child.costly() {
+			return true
+		}
+	}
+	return false
+}
+
+type orNode []node
+
+func (n orNode) match(q *query, e *entry) bool {
+	for _, child := range n {
+		if child.match(q, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n orNode) costly() bool {
+	return andNode(n).costly()
+}
+
+type notNode struct{ node }
+
+func (n notNode) match(q *query, e *entry) bool {
+	return !n.node.match(q, e)
+}
+
+type conditionNode condition
+
+func (n conditionNode) match(_ *query, e *entry) bool {
+	return n(e)
+}
+
+func (conditionNode) costly() bool {
+	return false
+}
+
+// contentNode matches the files with lines matching the pattern at
+// index of the query.
+type contentNode int
+
+func (n contentNode) match(q *query, e *entry) bool {
+	return len(q.grep(e)[n]) > 0
+}
+
+func (contentNode) costly() bool {
+	return true
+}
+
+// query is a parsed search query. Space separated filters must all
+// match, unless they're separated by OR. They can be grouped with
+// parentheses and negated with a leading minus sign.
+type query struct {
+	root          node
+	caseSensitive bool
+	// patterns are the content: and grep: patterns, negated reporting
+	// which of them are under a negation.
+	patterns []*contentPattern
+	negated  []bool
+	grep     func(e *entry) [][]Match
+}
+
+type queryParser struct {
+	tokens        []string
+	pos           int
+	caseSensitive bool
+	now           time.Time
+	q             *query
+}
+
+func parseSearch(value string) (*query, error) {
+	tokens, err := tokenize(value)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{now: time.Now(), q: &query{}}
+	for _, token := range tokens {
+		switch token {
+		case "case:sensitive":
+			p.caseSensitive = true
+		case "case:insensitive":
+		default:
+			p.tokens = append(p.tokens, token)
+		}
+	}
+	p.q.caseSensitive = p.caseSensitive
+
+	root, err := p.parseOr(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in query: %w", p.tokens[p.pos], fbErrors.ErrInvalidRequestParams)
+	}
+	p.q.root = root
+	return p.q, nil
+}
+
+// parseOr parses filters separated by OR, up to the end of the query
+// or, for groups, the closing parenthesis.
+func (p *queryParser) parseOr(negated bool) (node, error) {
+	var or orNode
+	for {
+		and, err := p.parseAnd(negated)
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+
+		if p.pos < len(p.tokens) && isOr(p.tokens[p.pos]) {
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func isOr(token string) bool {
+	return token == "OR" || token == "|"
+}
+
+func (p *queryParser) parseAnd(negated bool) (node, error) {
+	var cheap, costly andNode
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		if token == ")" || isOr(token) {
+			break
+		}
+
+		n, err := p.parseUnary(negated)
+		if err != nil {
+			return nil, err
+		}
+		// Files are only read once everything else matched.
+		if n.costly() {
+			costly = append(costly, n)
+		} else {
+			cheap = append(cheap, n)
+		}
+	}
+	return append(cheap, costly...), nil
+}
+
+func (p *queryParser) parseUnary(negated bool) (node, error) {
+	token := p.tokens[p.pos]
+	p.pos++
+	return p.parseToken(token, negated)
+}
+
+func (p *queryParser) parseToken(token string, negated bool) (node, error) {
+	if token != "-" && strings.HasPrefix(token, "-") {
+		n, err := p.parseToken(token[1:], !negated)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+
+	if token == "(" {
+		n, err := p.parseOr(negated)
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in query: %w", fbErrors.ErrInvalidRequestParams)
+		}
+		p.pos++
+		return n, nil
+	}
+
+	return p.parseFilter(token, negated)
+}
+
+func (p *queryParser) parseFilter(token string, negated bool) (node, error) {
+	key, value, ok := strings.Cut(token, ":")
+	if !ok || value == "" {
+		return conditionNode(termCondition(unquote(token), p.caseSensitive)), nil
+	}
+
+	var c condition
+	var err error
+	switch key {
+	case "type":
+		c = typeCondition(value)
+	case "ext":
+		c = extCondition(value)
+	case "size":
+		c, err = sizeCondition(value)
+	case "modified":
+		c, err = modifiedCondition(value, p.now)
+	case "path":
+		c = pathCondition(unquote(value), p.caseSensitive)
+	case "is":
+		c, err = isCondition(value)
+	case "content", "grep":
+		pattern, err := parseContentPattern(value, p.caseSensitive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content pattern %s: %v: %w", value, err, fbErrors.ErrInvalidRequestParams)
+		}
+		p.q.patterns = append(p.q.patterns, pattern)
+		p.q.negated = append(p.q.negated, negated)
+		return contentNode(len(p.q.patterns) - 1), nil
+	default:
+		// Names can hold colons too.
+		c = termCondition(unquote(token), p.caseSensitive)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conditionNode(c), nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' { //nolint:gomnd
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// tokenize splits a query on spaces and around parentheses. Double
+// quotes, and the slashes around content regexps, keep their contents
+// in a single token.
+func tokenize(value string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case c == '"':
+			end := strings.IndexByte(value[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in query: %w", fbErrors.ErrInvalidRequestParams)
+			}
+			current.WriteString(value[i : i+end+2])
+			i += end + 1
+		case c == '/' && isContentKey(current.String()):
+			end := regexpEnd(value, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated regexp in query: %w", fbErrors.ErrInvalidRequestParams)
+			}
+			current.WriteString(value[i : end+1])
+			i = end
+		case c == '(' && (current.Len() == 0 || current.String() == "-"):
+			// Groups, or negated groups.
+			current.WriteByte(c)
+			flush()
+		case c == ')':
+			flush()
+			tokens = append(tokens, ")")
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+func isContentKey(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return s == "content:" || s == "grep:"
+}
+
+// regexpEnd returns the index of the slash closing the regexp that
+// starts at start, or -1.
+func regexpEnd(value string, start int) int {
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		}
+	}
+	return -1
+}
@@ -1,9 +1,12 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/afero"
@@ -11,26 +14,60 @@ import (
 	"github.com/filebrowser/filebrowser/v2/rules"
 )
 
-type searchOptions struct {
-	CaseSensitive bool
-	Conditions    []condition
-	Terms         []string
+// SkipAll can be returned by the found function of Search to stop
+// searching without failing, once enough results were found.
+var SkipAll = errors.New("skip everything and stop the search") //nolint:revive,stylecheck
+
+// Index lists the files of a filesystem without walking it.
+type Index interface {
+	// Walk walks dir like afero.Walk. It reports false, without calling
+	// fn, if the index can't tell what dir holds.
+	Walk(dir string, fn filepath.WalkFunc) (bool, error)
 }
 
-// Search searches for a query in a fs.
-func Search(fs afero.Fs, scope, query string, checker rules.Checker, found func(path string, f os.FileInfo) error) error {
-	search := parseSearch(query)
+// Search searches for a query in a fs. For content searches, found
+// gets the matching lines of the files. The files are listed by idx,
+// if not nil and up to date, or by walking the fs. The search stops,
+// with the error of ctx, once ctx is done.
+//
+// Queries are made of space separated filters that must all match:
+// words the names must hold, and type:, ext:, size:, modified:, path:,
+// is:, content: and grep: filters. Filters can be separated by OR,
+// grouped with parentheses and negated with a leading minus sign.
+func Search(ctx context.Context, fs afero.Fs, idx Index, scope, query string, checker rules.Checker, found func(path string, f os.FileInfo, matches []Match) error) error {
+	q, err := parseSearch(query)
+	if err != nil {
+		return err
+	}
 
 	scope = filepath.ToSlash(filepath.Clean(scope))
 	scope = path.Join("/", scope)
 
-	return afero.Walk(fs, scope, func(fPath string, f os.FileInfo, _ error) error {
+	q.grep = func(e *entry) [][]Match {
+		if !e.grepped {
+			e.grepped = true
+			if e.info.Mode().IsRegular() {
+				// Unreadable files don't stop the search.
+				e.content, _ = grep(ctx, fs, e.path, e.info.Size(), q.patterns)
+			}
+			if e.content == nil {
+				e.content = make([][]Match, len(q.patterns))
+			}
+		}
+		return e.content
+	}
+
+	walkFn := func(fPath string, f os.FileInfo, _ error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		fPath = filepath.ToSlash(filepath.Clean(fPath))
 		fPath = path.Join("/", fPath)
 		relativePath := strings.TrimPrefix(fPath, scope)
 		relativePath = strings.TrimPrefix(relativePath, "/")
 
-		if fPath == scope {
+		if fPath == scope || f == nil {
 			return nil
 		}
 
@@ -38,35 +75,51 @@ func Search(fs afero.Fs, scope, query string, checker rules.Checker, found func(
 			return nil
 		}
 
-		if len(search.Conditions) > 0 {
-			match := false
+		e := &entry{path: fPath, name: path.Base(fPath), info: f}
+		if !q.root.match(q, e) {
+			return nil
+		}
+
+		return found(relativePath, f, q.matches(e))
+	}
 
-			for _, t := range search.Conditions {
-				if t(fPath) {
-					match = true
-					break
-				}
-			}
+	indexed := false
+	if idx != nil {
+		indexed, err = idx.Walk(scope, walkFn)
+	}
+	if !indexed {
+		err = afero.Walk(fs, scope, walkFn)
+	}
+	if errors.Is(err, SkipAll) {
+		return nil
+	}
+	return err
+}
 
-			if !match {
-				return nil
-			}
-		}
+// matches returns the lines of the file of e matching the content
+// patterns of the query, other than the negated ones.
+func (q *query) matches(e *entry) []Match {
+	if !e.grepped {
+		return nil
+	}
 
-		if len(search.Terms) > 0 {
-			for _, term := range search.Terms {
-				_, fileName := path.Split(fPath)
-				if !search.CaseSensitive {
-					fileName = strings.ToLower(fileName)
-					term = strings.ToLower(term)
-				}
-				if strings.Contains(fileName, term) {
-					return found(relativePath, f)
-				}
+	var matches []Match
+	seen := map[int]bool{}
+	for i, lines := range e.content {
+		if q.negated[i] {
+			continue
+		}
+		for _, m := range lines {
+			if !seen[m.Line] {
+				seen[m.Line] = true
+				matches = append(matches, m)
 			}
-			return nil
 		}
+	}
 
-		return found(relativePath, f)
-	})
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Line < matches[j].Line })
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+	return matches
 }
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+type allowAll struct{}
+
+func (allowAll) Check(string) bool { return true }
+
+func TestContentSearch(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for name, content := range map[string]string{
+		"/retroarch/retroarch.cfg":          "video_fullscreen = \"false\"\nvideo_vsync = \"true\"\n",
+		"/retroarch/config/snes.cfg":        "# Overrides\r\nVideo_Fullscreen = \"true\"\r\n",
+		"/emulationstation/es_settings.cfg": "<bool name=\"VSync\" value=\"true\" />\n",
+		"/roms/zelda.sfc":                   "video_fullscreen\x00\x01\x02",
+		"/long.txt":                         strings.Repeat("a", 300) + "needle" + strings.Repeat("b", 300),
+	} {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(query string) map[string][]Match {
+		t.Helper()
+		results := map[string][]Match{}
+		err := Search(context.Background(), fs, nil, "/", query, allowAll{}, func(p string, _ os.FileInfo, matches []Match) error {
+			results[p] = matches
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return results
+	}
+
+	results := search("content:video_fullscreen")
+	if len(results) != 2 {
+		t.Fatalf("expected the two text files, got %v", results)
+	}
+	if m := results["retroarch/config/snes.cfg"]; len(m) != 1 || m[0].Line != 2 || m[0].Text != `Video_Fullscreen = "true"` {
+		t.Errorf("unexpected matches %+v", m)
+	}
+
+	results = search("content:video_fullscreen case:sensitive")
+	if len(results) != 1 || results["retroarch/retroarch.cfg"] == nil {
+		t.Errorf("expected a case sensitive match, got %v", results)
+	}
+
+	results = search(`grep:/vsync.*"true"/ es_settings`)
+	if len(results) != 1 || results["emulationstation/es_settings.cfg"][0].Line != 1 {
+		t.Errorf("expected the content and the name to match, got %v", results)
+	}
+
+	results = search(`content:"video_vsync =" content:fullscreen type:cfg`)
+	if len(results) != 1 || len(results["retroarch/retroarch.cfg"]) != 2 {
+		t.Errorf("expected both patterns to match, got %v", results)
+	}
+
+	results = search("grep:needle")
+	if m := results["long.txt"]; len(m) != 1 || len(m[0].Text) != maxSnippet || !strings.Contains(m[0].Text, "needle") {
+		t.Errorf("unexpected snippet %+v", m)
+	}
+
+	err := Search(context.Background(), fs, nil, "/", "content:/[/", allowAll{}, func(string, os.FileInfo, []Match) error { return nil })
+	if !errors.Is(err, fbErrors.ErrInvalidRequestParams) {
+		t.Errorf("expected ErrInvalidRequestParams, got %v", err)
+	}
+}
+
+func TestQueryLanguage(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	now := time.Now()
+	for name, file := range map[string]struct {
+		size int
+		age  time.Duration
+	}{
+		"/roms/snes/Zelda.sfc":      {size: 1 << 20, age: time.Hour},
+		"/roms/snes/mario.sfc":      {size: 512 << 10, age: 30 * 24 * time.Hour},
+		"/roms/snes/zelda.zip":      {size: 300 << 10, age: 2 * time.Hour},
+		"/roms/gba/zelda.gba":       {size: 8 << 20, age: 400 * 24 * time.Hour},
+		"/movies/zelda-trailer.mp4": {size: 200 << 20, age: 24 * time.Hour},
+	} {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afero.WriteFile(fs, name, make([]byte, file.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(name, now.Add(-file.age), now.Add(-file.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(query string) string {
+		t.Helper()
+		var results []string
+		err := Search(context.Background(), fs, nil, "/", query, allowAll{}, func(p string, _ os.FileInfo, _ []Match) error {
+			results = append(results, p)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		sort.Strings(results)
+		return strings.Join(results, ",")
+	}
+
+	for query, expected := range map[string]string{
+		"zelda size:>100M":                    "movies/zelda-trailer.mp4",
+		"zelda size:<=1M ext:SFC,gba":         "roms/snes/Zelda.sfc",
+		"modified:<3h -is:dir":                "roms/snes/Zelda.sfc,roms/snes/zelda.zip",
+		"modified:>1y":                        "roms/gba/zelda.gba",
+		"path:roms/snes -zip is:file":         "roms/snes/Zelda.sfc,roms/snes/mario.sfc",
+		"zelda case:sensitive -type:video":    "roms/gba/zelda.gba,roms/snes/zelda.zip",
+		"ext:gba OR type:video":               "movies/zelda-trailer.mp4,roms/gba/zelda.gba",
+		"snes (mario | ext:zip)":              "",
+		"path:snes (mario | ext:zip)":         "roms/snes/mario.sfc,roms/snes/zelda.zip",
+		"zelda -(ext:zip OR path:movies) sfc": "roms/snes/Zelda.sfc",
+		"is:dir gba":                          "roms/gba",
+		`"zelda-trailer" modified:` + now.Add(-24*time.Hour).Format("2006-01-02"): "movies/zelda-trailer.mp4",
+	} {
+		if results := search(query); results != expected {
+			t.Errorf("%s: expected %q, got %q", query, expected, results)
+		}
+	}
+
+	count := 0
+	err := Search(context.Background(), fs, nil, "/", "zelda", allowAll{}, func(string, os.FileInfo, []Match) error {
+		count++
+		return SkipAll
+	})
+	if err != nil || count != 1 {
+		t.Errorf("expected the search to stop at the first result, got %d results and %v", count, err)
+	}
+
+	for _, invalid := range []string{"size:>lots", "modified:<7x", "is:link", "(zelda", "zelda)", `"zelda`} {
+		err := Search(context.Background(), fs, nil, "/", invalid, allowAll{}, func(string, os.FileInfo, []Match) error { return nil })
+		if !errors.Is(err, fbErrors.ErrInvalidRequestParams) {
+			t.Errorf("%s: expected ErrInvalidRequestParams, got %v", invalid, err)
+		}
+	}
+}
+
+func TestCancelledSearch(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for i := 0; i < 10; i++ {
+		if err := afero.WriteFile(fs, path.Join("/roms", strings.Repeat("a", i+1)+".sfc"), []byte("zelda"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := Search(ctx, fs, nil, "/", "sfc content:zelda", allowAll{}, func(string, os.FileInfo, []Match) error {
+		count++
+		if count == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || count != 3 {
+		t.Errorf("expected the search to stop once cancelled, got %d results and %v", count, err)
+	}
+}
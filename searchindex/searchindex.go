@@ -0,0 +1,546 @@
+// Package searchindex keeps a list of the files under a directory in
+// a bolt database, so that searches don't have to walk the directory.
+// The list is built by crawling the directory and then kept up to date
+// with the events of an events.Hub, published both by the server's
+// handlers and by the filesystem watcher.
+package searchindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+)
+
+// State tells whether an index can be used.
+type State string
+
+const (
+	// Stale indexes may have missed changes and need to be rebuilt.
+	Stale State = "stale"
+	// Building indexes are crawling their root.
+	Building State = "building"
+	// Ready indexes are in sync with their root.
+	Ready State = "ready"
+	// Failed indexes couldn't crawl their root.
+	Failed State = "failed"
+)
+
+const (
+	// batchSize is the number of entries written per transaction while
+	// crawling, and read per transaction while walking.
+	batchSize = 1000
+	// maxPending is the number of events kept while crawling. The
+	// crawl starts over if more happen.
+	maxPending = 10000
+	// entrySize is the size of the encoded entries.
+	entrySize = 20
+)
+
+var (
+	metaBucket = []byte("meta")
+	// Entries are crawled into one of two buckets while the other one
+	// is used, and the buckets are swapped once the crawl is done.
+	entriesBuckets = [][]byte{[]byte("entries0"), []byte("entries1")}
+
+	rootKey     = []byte("root")
+	bucketKey   = []byte("bucket")
+	builtAtKey  = []byte("builtAt")
+	durationKey = []byte("duration")
+)
+
+// Status describes an index.
+type Status struct {
+	Root    string    `json:"root"`
+	State   State     `json:"state"`
+	Error   string    `json:"error,omitempty"`
+	Entries int       `json:"entries"`
+	BuiltAt time.Time `json:"builtAt"`
+	// Duration is how long the last crawl took.
+	Duration  time.Duration `json:"duration"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	// Crawling is set while the root is crawled. Ready indexes are
+	// used, and kept up to date, in the meantime.
+	Crawling bool `json:"crawling"`
+}
+
+// Index is the list of the files under the root directory, keyed by
+// their slash separated path relative to it, starting with a slash.
+type Index struct {
+	db   *bolt.DB
+	root string
+	ctx  context.Context
+
+	mu        sync.Mutex
+	state     State
+	err       error
+	bucket    []byte
+	builtAt   time.Time
+	duration  time.Duration
+	updatedAt time.Time
+	building  bool
+	// pending holds the events received while crawling, applied once
+	// the crawl is done. dirty is set when some were lost.
+	pending []events.Event
+	dirty   bool
+}
+
+// Open opens, or creates, the index database at name for the
+// directory root. The index is stale until it's built.
+func Open(name, root string) (*Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(name, 0600, &bolt.Options{Timeout: time.Second}) //nolint:gomnd
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Index{db: db, root: root, ctx: context.Background(), state: Stale, bucket: entriesBuckets[0]}
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		if string(meta.Get(rootKey)) != root {
+			// Built for another directory.
+			for _, name := range entriesBuckets {
+				if err := tx.DeleteBucket(name); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+					return err
+				}
+			}
+			if err := meta.Delete(builtAtKey); err != nil {
+				return err
+			}
+			return meta.Put(rootKey, []byte(root))
+		}
+
+		if b := meta.Get(bucketKey); b != nil {
+			i.bucket = append([]byte(nil), b...)
+		}
+		if v := meta.Get(builtAtKey); v != nil {
+			_ = i.builtAt.UnmarshalBinary(v)
+		}
+		if v := meta.Get(durationKey); len(v) == 8 { //nolint:gomnd
+			i.duration = time.Duration(binary.BigEndian.Uint64(v)) //nolint:gosec
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Close closes the index database.
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// Root returns the directory the index lists.
+func (i *Index) Root() string {
+	return i.root
+}
+
+// Start builds the index in the background and keeps it up to date
+// with the events of hub until ctx is done.
+func (i *Index) Start(ctx context.Context, hub *events.Hub) {
+	i.mu.Lock()
+	i.ctx = ctx
+	i.mu.Unlock()
+
+	sub := hub.Subscribe(events.DefaultBuffer)
+	go i.watch(ctx, sub)
+	i.Rebuild()
+}
+
+// Rebuild crawls the root again in the background, unless it's
+// already being crawled. The index is used as is until it's done.
+func (i *Index) Rebuild() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rebuild()
+}
+
+func (i *Index) rebuild() {
+	if i.building {
+		return
+	}
+
+	i.building = true
+	if i.state != Ready {
+		i.state = Building
+	}
+	go i.crawl(i.ctx)
+}
+
+// Build crawls the root and waits for it to be done.
+func (i *Index) Build(ctx context.Context) error {
+	i.mu.Lock()
+	if i.building {
+		i.mu.Unlock()
+		return errors.New("the index is already being built")
+	}
+	i.building = true
+	if i.state != Ready {
+		i.state = Building
+	}
+	i.mu.Unlock()
+
+	return i.crawl(ctx)
+}
+
+// Status returns the status of the index.
+func (i *Index) Status() (*Status, error) {
+	i.mu.Lock()
+	s := &Status{
+		Root:      i.root,
+		State:     i.state,
+		BuiltAt:   i.builtAt,
+		Duration:  i.duration,
+		UpdatedAt: i.updatedAt,
+		Crawling:  i.building,
+	}
+	if i.err != nil {
+		s.Error = i.err.Error()
+	}
+	bucket := i.bucket
+	i.mu.Unlock()
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucket); b != nil {
+			s.Entries = b.Stats().KeyN
+		}
+		return nil
+	})
+	return s, err
+}
+
+// crawl lists the root into the unused bucket, and swaps the buckets
+// once done.
+func (i *Index) crawl(ctx context.Context) error {
+	i.mu.Lock()
+	next := entriesBuckets[0]
+	if bytes.Equal(i.bucket, next) {
+		next = entriesBuckets[1]
+	}
+	i.mu.Unlock()
+
+	start := time.Now()
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(next); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(next)
+		return err
+	})
+	if err == nil {
+		w := &batchWriter{db: i.db, bucket: next}
+		err = i.walkHost(ctx, i.root, w.put)
+		if err == nil {
+			err = w.flush()
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pending, dirty := i.pending, i.dirty
+	i.pending, i.dirty, i.building = nil, false, false
+
+	if err != nil {
+		log.Printf("searchindex: can't index %s: %v", i.root, err)
+		i.state, i.err = Failed, err
+		return err
+	}
+
+	now := time.Now()
+	err = i.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(next)
+		for _, e := range pending {
+			if err := i.apply(ctx, b, e); err != nil {
+				return err
+			}
+		}
+
+		meta := tx.Bucket(metaBucket)
+		builtAt, err := now.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		duration := make([]byte, 8)                                  //nolint:gomnd
+		binary.BigEndian.PutUint64(duration, uint64(now.Sub(start))) //nolint:gosec
+		if err := meta.Put(bucketKey, next); err != nil {
+			return err
+		}
+		if err := meta.Put(builtAtKey, builtAt); err != nil {
+			return err
+		}
+		if err := meta.Put(durationKey, duration); err != nil {
+			return err
+		}
+
+		if err := tx.DeleteBucket(i.bucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("searchindex: can't index %s: %v", i.root, err)
+		i.state, i.err = Failed, err
+		return err
+	}
+
+	i.bucket = next
+	i.state, i.err = Ready, nil
+	i.builtAt, i.updatedAt, i.duration = now, now, now.Sub(start)
+
+	if dirty {
+		// Changes were lost while crawling.
+		i.state = Stale
+		i.rebuild()
+	}
+	return nil
+}
+
+// watch applies the events of sub to the index until ctx is done.
+func (i *Index) watch(ctx context.Context, sub *events.Subscription) {
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+
+			// Apply the events that came together at once.
+			batch := []events.Event{e}
+		drain:
+			for len(batch) < batchSize {
+				select {
+				case e, ok := <-sub.Events():
+					if !ok {
+						break drain
+					}
+					batch = append(batch, e)
+				default:
+					break drain
+				}
+			}
+
+			i.handle(ctx, batch, sub.Dropped())
+		}
+	}
+}
+
+func (i *Index) handle(ctx context.Context, batch []events.Event, dropped int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("searchindex: %d changes were missed, rebuilding the index", dropped)
+		if i.building {
+			i.dirty = true
+		} else {
+			i.state = Stale
+			i.rebuild()
+		}
+		return
+	}
+
+	if i.building {
+		i.pending = append(i.pending, batch...)
+		if len(i.pending) > maxPending {
+			i.pending, i.dirty = nil, true
+		}
+	}
+	if i.state != Ready {
+		return
+	}
+
+	err := i.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(i.bucket)
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+		for _, e := range batch {
+			if err := i.apply(ctx, b, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("searchindex: can't update the index, rebuilding it: %v", err)
+		if i.building {
+			i.dirty = true
+		} else {
+			i.state = Stale
+			i.rebuild()
+		}
+		return
+	}
+	i.updatedAt = time.Now()
+}
+
+// apply updates b with the change of e.
+func (i *Index) apply(ctx context.Context, b *bolt.Bucket, e events.Event) error {
+	key, ok := i.key(e.Path)
+	if !ok {
+		return nil
+	}
+
+	switch e.Type {
+	case events.Delete:
+		return deleteTree(b, key)
+	case events.Rename:
+		if err := deleteTree(b, key); err != nil {
+			return err
+		}
+		if e.Dst == "" {
+			// The watcher publishes the new path on its own.
+			return nil
+		}
+		return i.index(ctx, b, e.Dst, true)
+	case events.Create:
+		return i.index(ctx, b, e.Path, true)
+	default:
+		return i.index(ctx, b, e.Path, false)
+	}
+}
+
+// index adds the file at the host path name to b, along with its
+// content if it's a directory and recursive is set. Files that
+// vanished are removed.
+func (i *Index) index(ctx context.Context, b *bolt.Bucket, name string, recursive bool) error {
+	key, ok := i.key(name)
+	if !ok {
+		return nil
+	}
+
+	info, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return deleteTree(b, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !recursive || !info.IsDir() {
+		return b.Put([]byte(key), encode(info))
+	}
+	return i.walkHost(ctx, name, func(key string, info fs.FileInfo) error {
+		return b.Put([]byte(key), encode(info))
+	})
+}
+
+// walkHost calls fn with the key and the information of dir and of
+// everything under it. Unreadable subdirectories are skipped.
+func (i *Index) walkHost(ctx context.Context, dir string, fn func(key string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == dir {
+				return err
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		key, ok := i.key(name)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Gone since it was listed.
+			return nil
+		}
+		return fn(key, info)
+	})
+}
+
+// key returns the key of the host path name, if it's under the root.
+func (i *Index) key(name string) (string, bool) {
+	rel, err := filepath.Rel(i.root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path.Join("/", filepath.ToSlash(rel)), true
+}
+
+// deleteTree removes key and everything under it from b.
+func deleteTree(b *bolt.Bucket, key string) error {
+	keys := [][]byte{[]byte(key)}
+	prefix := []byte(strings.TrimSuffix(key, "/") + "/")
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchWriter writes entries in transactions of batchSize.
+type batchWriter struct {
+	db     *bolt.DB
+	bucket []byte
+	keys   []string
+	values [][]byte
+}
+
+func (w *batchWriter) put(key string, info fs.FileInfo) error {
+	w.keys = append(w.keys, key)
+	w.values = append(w.values, encode(info))
+	if len(w.keys) >= batchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *batchWriter) flush() error {
+	if len(w.keys) == 0 {
+		return nil
+	}
+
+	err := w.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(w.bucket)
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+		for n, key := range w.keys {
+			if err := b.Put([]byte(key), w.values[n]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	w.keys, w.values = w.keys[:0], w.values[:0]
+	return err
+}
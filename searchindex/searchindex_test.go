@@ -0,0 +1,222 @@
+package searchindex
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filebrowser/filebrowser/v2/events"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openIndex(t *testing.T, root string) *Index {
+	t.Helper()
+	idx, err := Open(filepath.Join(t.TempDir(), "index.db"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { idx.Close() })
+	return idx
+}
+
+type walker interface {
+	Walk(dir string, fn filepath.WalkFunc) (bool, error)
+}
+
+// list returns the sorted paths walked from dir, or "-" if the index
+// can't answer.
+func list(t *testing.T, w walker, dir string) string {
+	t.Helper()
+	var paths []string
+	ok, err := w.Walk(dir, func(p string, info fs.FileInfo, _ error) error {
+		if info.IsDir() {
+			p += "/"
+		}
+		paths = append(paths, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		return "-"
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func TestBuildAndWalk(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "roms/snes/mario.sfc"), "mario")
+	writeFile(t, filepath.Join(root, "roms/snes/zelda.sfc"), "zelda")
+	writeFile(t, filepath.Join(root, "roms/gba/zelda.gba"), "zelda")
+	writeFile(t, filepath.Join(root, "roms.txt"), "list")
+
+	idx := openIndex(t, root)
+	if got := list(t, idx, "/"); got != "-" {
+		t.Fatalf("expected a stale index not to answer, got %q", got)
+	}
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for dir, expected := range map[string]string{
+		"/":          "//,/roms.txt,/roms/,/roms/gba/,/roms/gba/zelda.gba,/roms/snes/,/roms/snes/mario.sfc,/roms/snes/zelda.sfc",
+		"/roms/snes": "/roms/snes/,/roms/snes/mario.sfc,/roms/snes/zelda.sfc",
+		"/roms.txt":  "/roms.txt",
+		"/missing":   "-",
+	} {
+		if got := list(t, idx, dir); got != expected {
+			t.Errorf("%s: expected %q, got %q", dir, expected, got)
+		}
+	}
+
+	sub, ok := idx.Sub(filepath.Join(root, "roms"))
+	if !ok {
+		t.Fatal("expected a sub index")
+	}
+	if got, expected := list(t, sub, "/snes"), "/snes/,/snes/mario.sfc,/snes/zelda.sfc"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if _, ok := idx.Sub(filepath.Dir(root)); ok {
+		t.Error("expected no sub index outside of the root")
+	}
+
+	var walked []string
+	_, err := idx.Walk("/roms", func(p string, info fs.FileInfo, _ error) error {
+		walked = append(walked, p)
+		if p == "/roms/gba" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil || strings.Contains(strings.Join(walked, ","), "zelda.gba") {
+		t.Errorf("expected the skipped directory not to be walked, got %v and %v", walked, err)
+	}
+
+	var info fs.FileInfo
+	_, _ = idx.Walk("/roms/snes/mario.sfc", func(_ string, i fs.FileInfo, _ error) error {
+		info = i
+		return nil
+	})
+	if info == nil || info.Name() != "mario.sfc" || info.Size() != 5 || info.IsDir() {
+		t.Errorf("unexpected file info %+v", info)
+	}
+
+	status, err := idx.Status()
+	if err != nil || status.State != Ready || status.Entries != 8 || status.BuiltAt.IsZero() {
+		t.Errorf("unexpected status %+v and %v", status, err)
+	}
+}
+
+// Siblings sharing the name of a directory sort between it and what
+// it holds, and must not end its walk.
+func TestWalkSiblings(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "roms/a.zip"), "a")
+	writeFile(t, filepath.Join(root, "roms/b.zip"), "b")
+	writeFile(t, filepath.Join(root, "roms.bak/c"), "c")
+	writeFile(t, filepath.Join(root, "roms-old/d"), "d")
+	writeFile(t, filepath.Join(root, "roms (2)/e"), "e")
+
+	idx := openIndex(t, root)
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := list(t, idx, "/roms"), "/roms/,/roms/a.zip,/roms/b.zip"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	sub, ok := idx.Sub(filepath.Join(root, "roms"))
+	if !ok {
+		t.Fatal("expected a sub index")
+	}
+	if got, expected := list(t, sub, "/"), "//,/a.zip,/b.zip"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "roms/snes/mario.sfc"), "mario")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := events.NewHub()
+	idx := openIndex(t, root)
+	idx.Start(ctx, hub)
+	waitFor(t, func() bool { return list(t, idx, "/") != "-" })
+
+	// Events are applied in the background.
+	expect := func(expected string) {
+		t.Helper()
+		waitFor(t, func() bool { return list(t, idx, "/") == expected })
+	}
+
+	writeFile(t, filepath.Join(root, "roms/gba/zelda.gba"), "zelda")
+	hub.Publish(events.Event{Type: events.Create, Path: filepath.Join(root, "roms/gba"), IsDir: true})
+	expect("//,/roms/,/roms/gba/,/roms/gba/zelda.gba,/roms/snes/,/roms/snes/mario.sfc")
+
+	if err := os.Rename(filepath.Join(root, "roms/gba"), filepath.Join(root, "gba")); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(events.Event{Type: events.Rename, Path: filepath.Join(root, "roms/gba"), Dst: filepath.Join(root, "gba"), IsDir: true})
+	expect("//,/gba/,/gba/zelda.gba,/roms/,/roms/snes/,/roms/snes/mario.sfc")
+
+	if err := os.RemoveAll(filepath.Join(root, "roms")); err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(events.Event{Type: events.Delete, Path: filepath.Join(root, "roms"), IsDir: true})
+	expect("//,/gba/,/gba/zelda.gba")
+
+	// Changes outside the root are ignored.
+	hub.Publish(events.Event{Type: events.Create, Path: filepath.Dir(root)})
+	writeFile(t, filepath.Join(root, "gba/zelda.gba"), "zelda dx")
+	hub.Publish(events.Event{Type: events.Modify, Path: filepath.Join(root, "gba/zelda.gba")})
+	waitFor(t, func() bool {
+		var size int64
+		_, _ = idx.Walk("/gba/zelda.gba", func(_ string, info fs.FileInfo, _ error) error {
+			size = info.Size()
+			return nil
+		})
+		return size == 8
+	})
+
+	// The index is emptied when it's opened for another root.
+	name := idx.db.Path()
+	cancel()
+	idx.Close()
+	other, err := Open(name, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if status, err := other.Status(); err != nil || status.Entries != 0 || !status.BuiltAt.IsZero() {
+		t.Errorf("expected an empty index, got %+v and %v", status, err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,170 @@
+package searchindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Walk walks the directory dir, relative to the root, like afero.Walk
+// walks a filesystem rooted there, except that files don't come in
+// lexical order. It reports false, without calling fn, if the index
+// isn't ready or doesn't hold dir.
+//
+// The index is read in batches, so that fn can take its time without
+// holding a transaction open.
+func (i *Index) Walk(dir string, fn filepath.WalkFunc) (bool, error) {
+	i.mu.Lock()
+	ready, bucket := i.state == Ready, i.bucket
+	i.mu.Unlock()
+	if !ready {
+		return false, nil
+	}
+
+	dir = path.Join("/", dir)
+	prefix := []byte(strings.TrimSuffix(dir, "/") + "/")
+
+	var skip []byte
+	from := []byte(dir)
+	for first := true; from != nil; first = false {
+		var batch []*fileInfo
+		var found bool
+		err := i.db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(bucket)
+			if b == nil {
+				return nil
+			}
+
+			c := b.Cursor()
+			k, v := c.Seek(from)
+			if first {
+				if !bytes.Equal(k, from) {
+					return nil
+				}
+				if info, ok := decode(string(k), v); ok {
+					batch = append(batch, info)
+				}
+				// Siblings such as /dir.bak sort between dir and what's
+				// in it, so seek past them.
+				k, v = c.Seek(prefix)
+			}
+			if bytes.Equal(k, from) {
+				// Read in the previous batch, or dir itself at the root.
+				k, v = c.Next()
+			}
+			found = true
+
+			from = nil
+			for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				if info, ok := decode(string(k), v); ok {
+					batch = append(batch, info)
+				}
+				if len(batch) == batchSize {
+					from = append([]byte(nil), k...)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return true, err
+		}
+		if !found {
+			return false, nil
+		}
+
+		for _, info := range batch {
+			if skip != nil && bytes.HasPrefix([]byte(info.path), skip) {
+				continue
+			}
+
+			err := fn(info.path, info, nil)
+			switch {
+			case errors.Is(err, filepath.SkipAll):
+				return true, nil
+			case errors.Is(err, filepath.SkipDir) && info.path == dir:
+				return true, nil
+			case errors.Is(err, filepath.SkipDir) && info.IsDir():
+				skip = []byte(info.path + "/")
+			case errors.Is(err, filepath.SkipDir):
+				skip = []byte(path.Dir(info.path) + "/")
+			case err != nil:
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Sub is the part of an index under one of its directories.
+type Sub struct {
+	idx *Index
+	dir string
+}
+
+// Sub returns the part of the index under the host path dir, or false
+// if it's not under the root.
+func (i *Index) Sub(dir string) (*Sub, bool) {
+	key, ok := i.key(dir)
+	if !ok {
+		return nil, false
+	}
+	return &Sub{idx: i, dir: key}, true
+}
+
+// Walk is like Index.Walk, with paths relative to the directory of s.
+func (s *Sub) Walk(dir string, fn filepath.WalkFunc) (bool, error) {
+	if s.dir == "/" {
+		return s.idx.Walk(dir, fn)
+	}
+
+	return s.idx.Walk(path.Join(s.dir, dir), func(p string, info fs.FileInfo, err error) error {
+		p = strings.TrimPrefix(p, s.dir)
+		if p == "" {
+			p = "/"
+		}
+		return fn(p, info, err)
+	})
+}
+
+// fileInfo is an entry of the index.
+type fileInfo struct {
+	path    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f *fileInfo) Name() string       { return path.Base(f.path) }
+func (f *fileInfo) Size() int64        { return f.size }
+func (f *fileInfo) Mode() fs.FileMode  { return f.mode }
+func (f *fileInfo) ModTime() time.Time { return f.modTime }
+func (f *fileInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f *fileInfo) Sys() interface{}   { return nil }
+
+func encode(info fs.FileInfo) []byte {
+	v := make([]byte, entrySize)
+	binary.BigEndian.PutUint64(v, uint64(info.Size()))                   //nolint:gosec
+	binary.BigEndian.PutUint64(v[8:], uint64(info.ModTime().UnixNano())) //nolint:gosec
+	binary.BigEndian.PutUint32(v[16:], uint32(info.Mode()))
+	return v
+}
+
+func decode(key string, v []byte) (*fileInfo, bool) {
+	if len(v) != entrySize {
+		return nil, false
+	}
+	return &fileInfo{
+		path:    key,
+		size:    int64(binary.BigEndian.Uint64(v)),                   //nolint:gosec
+		modTime: time.Unix(0, int64(binary.BigEndian.Uint64(v[8:]))), //nolint:gosec
+		mode:    fs.FileMode(binary.BigEndian.Uint32(v[16:])),
+	}, true
+}
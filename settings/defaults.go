@@ -17,6 +17,7 @@ type UserDefaults struct {
 	Commands     []string          `json:"commands"`
 	HideDotfiles bool              `json:"hideDotfiles"`
 	DateFormat   bool              `json:"dateFormat"`
+	Quota        users.Quota       `json:"quota"`
 }
 
 // Apply applies the default options to a user.
@@ -30,4 +31,5 @@ func (d *UserDefaults) Apply(u *users.User) {
 	u.Commands = d.Commands
 	u.HideDotfiles = d.HideDotfiles
 	u.DateFormat = d.DateFormat
+	u.Quota = d.Quota
 }
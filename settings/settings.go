@@ -3,6 +3,7 @@ package settings
 import (
 	"crypto/rand"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,9 @@ type Settings struct {
 	Commands         map[string][]string `json:"commands"`
 	Shell            []string            `json:"shell"`
 	Rules            []rules.Rule        `json:"rules"`
+	Virtual          []VirtualDir        `json:"virtual"`
+	SupportSecret    string              `json:"supportSecret"`
+	Trash            Trash               `json:"trash"`
 }
 
 // GetRules implements rules.Provider.
@@ -50,6 +54,12 @@ type Server struct {
 	TypeDetectionByHeader bool   `json:"typeDetectionByHeader"`
 	AuthHook              string `json:"authHook"`
 	TokenExpirationTime   string `json:"tokenExpirationTime"`
+	SupportSessionTimeout string `json:"supportSessionTimeout"`
+	MetricsToken          string `json:"metricsToken"`
+	MetricsAddress        string `json:"metricsAddress"`
+	DatDir                string `json:"datDir"`
+	SearchIndex           string `json:"searchIndex"`
+	WatchLimit            string `json:"watchLimit"`
 }
 
 // Clean cleans any variables that might need cleaning.
@@ -70,6 +80,35 @@ func (s *Server) GetTokenExpirationTime(fallback time.Duration) time.Duration {
 	return duration
 }
 
+func (s *Server) GetSupportSessionTimeout(fallback time.Duration) time.Duration {
+	if s.SupportSessionTimeout == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(s.SupportSessionTimeout)
+	if err != nil {
+		log.Printf("[WARN] Failed to parse supportSessionTimeout: %v", err)
+		return fallback
+	}
+	return duration
+}
+
+// GetWatchLimit returns how many directories of the root are watched
+// for changes at most, or fallback if it isn't set. A negative limit
+// disables watching.
+func (s *Server) GetWatchLimit(fallback int) int {
+	if s.WatchLimit == "" {
+		return fallback
+	}
+
+	limit, err := strconv.Atoi(s.WatchLimit)
+	if err != nil {
+		log.Printf("[WARN] Failed to parse watchLimit: %v", err)
+		return fallback
+	}
+	return limit
+}
+
 // GenerateKey generates a key of 512 bits.
 func GenerateKey() ([]byte, error) {
 	b := make([]byte, 64) //nolint:gomnd
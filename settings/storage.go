@@ -39,6 +39,9 @@ func (s *Storage) Get() (*Settings, error) {
 			RetryCount: DefaultTusRetryCount,
 		}
 	}
+	if set.Virtual == nil {
+		set.Virtual = DefaultVirtualDirs()
+	}
 	return set, nil
 }
 
@@ -48,6 +51,9 @@ var defaultEvents = []string{
 	"rename",
 	"upload",
 	"delete",
+	"extract",
+	"compress",
+	"manifest",
 }
 
 // Save saves the settings for the current instance.
@@ -72,6 +78,22 @@ func (s *Storage) Save(set *Settings) error {
 		set.Rules = []rules.Rule{}
 	}
 
+	if err := rules.Compile(set.Rules); err != nil {
+		return err
+	}
+
+	if set.Virtual == nil {
+		set.Virtual = DefaultVirtualDirs()
+	}
+
+	if set.SupportSecret == "" {
+		key, err := GenerateSupportSecret()
+		if err != nil {
+			return err
+		}
+		set.SupportSecret = key.Secret()
+	}
+
 	if set.Shell == nil {
 		set.Shell = []string{}
 	}
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// GenerateSupportSecret generates a new TOTP key for support logins.
+func GenerateSupportSecret() (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      "File Browser",
+		AccountName: "support",
+	})
+}
+
+// SupportSecret returns the TOTP secret for support logins, generating
+// and saving one if this instance doesn't have it yet.
+func (s *Storage) SupportSecret() (string, error) {
+	set, err := s.Get()
+	if err != nil {
+		return "", err
+	}
+
+	if set.SupportSecret == "" {
+		if err := s.Save(set); err != nil {
+			return "", err
+		}
+	}
+
+	return set.SupportSecret, nil
+}
+
+// RotateSupportSecret replaces the TOTP secret for support logins
+// with a new one and returns its key.
+func (s *Storage) RotateSupportSecret() (*otp.Key, error) {
+	set, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := GenerateSupportSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	set.SupportSecret = key.Secret()
+	if err := s.Save(set); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
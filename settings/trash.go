@@ -0,0 +1,32 @@
+package settings
+
+import (
+	"log"
+	"time"
+)
+
+const DefaultTrashMaxAge = 30 * 24 * time.Hour // 30 days
+
+// Trash contains the recycle bin settings of the app.
+type Trash struct {
+	// MaxAge is how long deleted files are kept, e.g. "720h".
+	// Empty uses DefaultTrashMaxAge and "0" keeps them forever.
+	MaxAge string `json:"maxAge"`
+	// MaxSize is the size in bytes past which the oldest deleted
+	// files of a user are purged. Zero means no limit.
+	MaxSize uint64 `json:"maxSize"`
+}
+
+// GetMaxAge returns the parsed MaxAge.
+func (t *Trash) GetMaxAge() time.Duration {
+	if t.MaxAge == "" {
+		return DefaultTrashMaxAge
+	}
+
+	duration, err := time.ParseDuration(t.MaxAge)
+	if err != nil {
+		log.Printf("[WARN] Failed to parse trash maxAge: %v", err)
+		return DefaultTrashMaxAge
+	}
+	return duration
+}
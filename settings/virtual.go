@@ -0,0 +1,41 @@
+package settings
+
+// VirtualProvider is the kind of provider backing a virtual directory.
+type VirtualProvider string
+
+const (
+	// VirtualProviderFiles exposes a fixed list of real files.
+	VirtualProviderFiles VirtualProvider = "files"
+	// VirtualProviderDir exposes the regular files of a real directory.
+	VirtualProviderDir VirtualProvider = "dir"
+	// VirtualProviderCommand exposes the output of commands as files.
+	VirtualProviderCommand VirtualProvider = "command"
+)
+
+// VirtualDir describes a directory mounted under /virtual.
+type VirtualDir struct {
+	Name     string              `json:"name"`
+	Provider VirtualProvider     `json:"provider"`
+	Files    []string            `json:"files,omitempty"`
+	Path     string              `json:"path,omitempty"`
+	Commands map[string][]string `json:"commands,omitempty"`
+}
+
+// DefaultVirtualDirs returns the virtual directories mounted
+// when none are configured.
+func DefaultVirtualDirs() []VirtualDir {
+	return []VirtualDir{
+		{
+			Name:     "logs",
+			Provider: VirtualProviderFiles,
+			Files: []string{
+				"/rcade/share/.emulationstation/es_log.txt",
+				"/rcade/share/.emulationstation/es_log.txt.bak",
+				"/rcade/share/.emulationstation/upgrade.log",
+				"/tmp/last_game_launch.log",
+				"/tmp/rcade-usbmount.log",
+				"/var/log/messages",
+			},
+		},
+	}
+}
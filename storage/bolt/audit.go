@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	"github.com/asdine/storm/v3/q"
+
+	"github.com/filebrowser/filebrowser/v2/audit"
+)
+
+type auditBackend struct {
+	db *storm.DB
+}
+
+func (a auditBackend) Save(e *audit.Entry) error {
+	return a.db.Save(e)
+}
+
+func (a auditBackend) Find(f audit.Filter) ([]*audit.Entry, error) {
+	var matchers []q.Matcher
+	if f.Session != "" {
+		matchers = append(matchers, q.Eq("Session", f.Session))
+	}
+	if !f.Since.IsZero() {
+		matchers = append(matchers, q.Gte("Time", f.Since))
+	}
+	if !f.Until.IsZero() {
+		matchers = append(matchers, q.Lt("Time", f.Until))
+	}
+
+	query := a.db.Select(matchers...).OrderBy("ID").Reverse()
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+
+	v := []*audit.Entry{}
+	err := query.Find(&v)
+	if errors.Is(err, storm.ErrNotFound) {
+		return []*audit.Entry{}, nil
+	}
+
+	return v, err
+}
+
+func (a auditBackend) DeleteBefore(t time.Time) error {
+	err := a.db.Select(q.Lt("Time", t)).Delete(&audit.Entry{})
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -3,7 +3,10 @@ package bolt
 import (
 	"github.com/asdine/storm/v3"
 
+	"github.com/filebrowser/filebrowser/v2/audit"
 	"github.com/filebrowser/filebrowser/v2/auth"
+	"github.com/filebrowser/filebrowser/v2/groups"
+	"github.com/filebrowser/filebrowser/v2/jobs"
 	"github.com/filebrowser/filebrowser/v2/settings"
 	"github.com/filebrowser/filebrowser/v2/share"
 	"github.com/filebrowser/filebrowser/v2/storage"
@@ -16,6 +19,9 @@ func NewStorage(db *storm.DB) (*storage.Storage, error) {
 	shareStore := share.NewStorage(shareBackend{db: db})
 	settingsStore := settings.NewStorage(settingsBackend{db: db})
 	authStore := auth.NewStorage(authBackend{db: db}, userStore)
+	auditStore := audit.NewStorage(auditBackend{db: db})
+	jobsStore := jobs.NewStorage(jobsBackend{db: db})
+	groupsStore := groups.NewStorage(groupsBackend{db: db}, userStore)
 
 	err := save(db, "version", 2)
 	if err != nil {
@@ -27,5 +33,8 @@ func NewStorage(db *storm.DB) (*storage.Storage, error) {
 		Users:    userStore,
 		Share:    shareStore,
 		Settings: settingsStore,
+		Audit:    auditStore,
+		Jobs:     jobsStore,
+		Groups:   groupsStore,
 	}, nil
 }
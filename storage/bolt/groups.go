@@ -0,0 +1,59 @@
+package bolt
+
+import (
+	"errors"
+
+	"github.com/asdine/storm/v3"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/groups"
+)
+
+type groupsBackend struct {
+	db *storm.DB
+}
+
+func (st groupsBackend) GetBy(i interface{}) (*groups.Group, error) {
+	var arg string
+	switch i.(type) {
+	case uint:
+		arg = "ID"
+	case string:
+		arg = "Name"
+	default:
+		return nil, fbErrors.ErrInvalidDataType
+	}
+
+	group := &groups.Group{}
+	err := st.db.One(arg, i, group)
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil, fbErrors.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (st groupsBackend) Gets() ([]*groups.Group, error) {
+	var all []*groups.Group
+	err := st.db.All(&all)
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return all, err
+}
+
+func (st groupsBackend) Save(group *groups.Group) error {
+	err := st.db.Save(group)
+	if errors.Is(err, storm.ErrAlreadyExists) {
+		return fbErrors.ErrExist
+	}
+	return err
+}
+
+func (st groupsBackend) DeleteByID(id uint) error {
+	return st.db.DeleteStruct(&groups.Group{ID: id})
+}
@@ -0,0 +1,56 @@
+package bolt
+
+import (
+	"errors"
+
+	"github.com/asdine/storm/v3"
+	"github.com/asdine/storm/v3/q"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/jobs"
+)
+
+type jobsBackend struct {
+	db *storm.DB
+}
+
+func (s jobsBackend) Save(j *jobs.Job) error {
+	return s.db.Save(j)
+}
+
+func (s jobsBackend) Get(id uint) (*jobs.Job, error) {
+	var v jobs.Job
+	err := s.db.One("ID", id, &v)
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return &v, err
+}
+
+func (s jobsBackend) Find(f jobs.Filter) ([]*jobs.Job, error) {
+	var matchers []q.Matcher
+	if f.UserID != 0 {
+		matchers = append(matchers, q.Eq("UserID", f.UserID))
+	}
+	if len(f.States) > 0 {
+		matchers = append(matchers, q.In("State", f.States))
+	}
+
+	v := []*jobs.Job{}
+	err := s.db.Select(matchers...).OrderBy("ID").Reverse().Find(&v)
+	if errors.Is(err, storm.ErrNotFound) {
+		return []*jobs.Job{}, nil
+	}
+
+	return v, err
+}
+
+func (s jobsBackend) Delete(id uint) error {
+	err := s.db.DeleteStruct(&jobs.Job{ID: id})
+	if errors.Is(err, storm.ErrNotFound) {
+		return fbErrors.ErrNotExist
+	}
+
+	return err
+}
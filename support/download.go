@@ -0,0 +1,83 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/google/go-github/github"
+)
+
+// DownloadCloudflared downloads the latest cloudflared release for
+// the current architecture to path.
+func DownloadCloudflared(ctx context.Context, path string) error {
+	client := github.NewClient(nil)
+
+	release, _, err := client.Repositories.GetLatestRelease(ctx, "cloudflare", "cloudflared")
+	if err != nil {
+		return fmt.Errorf("error getting latest release: %w", err)
+	}
+
+	assetName := "cloudflared-linux-" + runtime.GOARCH
+	var downloadURL string
+	for _, asset := range release.Assets {
+		if asset.GetName() == assetName {
+			downloadURL = asset.GetBrowserDownloadURL()
+			break
+		}
+	}
+
+	if downloadURL == "" {
+		return fmt.Errorf("release %s has no asset %s", release.GetTagName(), assetName)
+	}
+
+	log.Printf("Downloading cloudflared %s", release.GetTagName())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading the file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading the file: %s", resp.Status)
+	}
+
+	// Download next to the destination and rename at the end, so
+	// an interrupted download never leaves a broken binary behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil { //nolint:govet
+		tmp.Close()
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil { //nolint:govet
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil { //nolint:gosec,gomnd,govet
+		return fmt.Errorf("error setting file permissions: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	log.Printf("cloudflared downloaded to %s", path)
+	return nil
+}
@@ -0,0 +1,366 @@
+package support
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"os/exec"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultSessionTimeout is how long a support session lasts
+	// when no timeout is configured.
+	DefaultSessionTimeout = 2 * time.Hour
+	// DefaultConnectTimeout is how long the tunnel has to connect.
+	DefaultConnectTimeout = 30 * time.Second
+	// DefaultMaxRestarts is how many times a crashed tunnel is restarted
+	// before the session is torn down.
+	DefaultMaxRestarts = 5
+	// DefaultRestartDelay is how long to wait before restarting a crashed tunnel.
+	DefaultRestartDelay = 2 * time.Second
+
+	stopTimeout = 5 * time.Second
+)
+
+var (
+	// ErrTunnelExited is returned when the tunnel exits before connecting.
+	ErrTunnelExited = errors.New("tunnel exited before connecting")
+	// ErrConnectTimeout is returned when the tunnel takes too long to connect.
+	ErrConnectTimeout = errors.New("timed out waiting for the tunnel to connect")
+
+	urlRe       = regexp.MustCompile(`INF\s+\|\s+(https://\S+)\s+\|$`)
+	connectedRe = regexp.MustCompile(`INF Registered tunnel connection`)
+)
+
+// Status describes the current support session.
+type Status struct {
+	ProxyURL    string    `json:"proxyURL"`
+	Pid         int       `json:"pid"`
+	TimeStarted time.Time `json:"timeStarted"`
+	SessionCode string    `json:"sessionCode"`
+	Started     bool      `json:"started"`
+	Restarts    int       `json:"restarts"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Config configures a TunnelManager.
+type Config struct {
+	// BinaryPath is the path to the cloudflared binary.
+	BinaryPath string
+	// Download fetches the binary to BinaryPath when it's missing.
+	Download func(ctx context.Context, path string) error
+	// LocalURL is the URL exposed through the tunnel.
+	LocalURL string
+	// Args returns the arguments the binary is run with. It
+	// defaults to a cloudflared quick tunnel to LocalURL.
+	Args           func(localURL string) []string
+	SessionTimeout time.Duration
+	ConnectTimeout time.Duration
+	MaxRestarts    int
+	RestartDelay   time.Duration
+}
+
+// TunnelManager runs a cloudflared tunnel for remote support sessions.
+// It restarts the tunnel when it crashes and tears the session down
+// once it times out.
+type TunnelManager struct {
+	cfg Config
+
+	// sessionMu serializes Start and Stop.
+	sessionMu sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewTunnelManager creates a TunnelManager, filling the unset
+// config values with their defaults.
+func NewTunnelManager(cfg Config) *TunnelManager {
+	if cfg.Args == nil {
+		cfg.Args = func(localURL string) []string {
+			return []string{"tunnel", "--url", localURL}
+		}
+	}
+	if cfg.SessionTimeout <= 0 {
+		cfg.SessionTimeout = DefaultSessionTimeout
+	}
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.MaxRestarts < 0 {
+		cfg.MaxRestarts = 0
+	} else if cfg.MaxRestarts == 0 {
+		cfg.MaxRestarts = DefaultMaxRestarts
+	}
+	if cfg.RestartDelay <= 0 {
+		cfg.RestartDelay = DefaultRestartDelay
+	}
+
+	return &TunnelManager{cfg: cfg}
+}
+
+// Status returns the status of the current session.
+func (m *TunnelManager) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// SessionCode returns the code of the current session, or
+// an empty string if there is none.
+func (m *TunnelManager) SessionCode() string {
+	return m.Status().SessionCode
+}
+
+// Start starts a support session, unless one is already running,
+// and returns its status once the tunnel is connected.
+func (m *TunnelManager) Start(ctx context.Context) (Status, error) {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+
+	if m.done != nil {
+		return m.Status(), nil
+	}
+
+	if _, err := os.Stat(m.cfg.BinaryPath); errors.Is(err, os.ErrNotExist) && m.cfg.Download != nil {
+		if err := m.cfg.Download(ctx, m.cfg.BinaryPath); err != nil { //nolint:govet
+			return Status{}, fmt.Errorf("failed to download tunnel binary: %w", err)
+		}
+	} else if err != nil {
+		return Status{}, err
+	}
+
+	code, err := generateSessionCode()
+	if err != nil {
+		return Status{}, err
+	}
+
+	sessionCtx, cancel := context.WithTimeout(context.Background(), m.cfg.SessionTimeout)
+	p, err := m.launch(ctx, sessionCtx)
+	if err != nil {
+		cancel()
+		return Status{}, err
+	}
+
+	now := time.Now()
+	m.setStatus(func(s *Status) {
+		*s = Status{
+			ProxyURL:    p.url,
+			Pid:         p.pid,
+			TimeStarted: now,
+			SessionCode: code,
+			Started:     true,
+			ExpiresAt:   now.Add(m.cfg.SessionTimeout),
+		}
+	})
+
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	go m.supervise(sessionCtx, p, m.done)
+
+	log.Printf("Support session started (pid: %d) with URL: %s", p.pid, p.url)
+	return m.Status(), nil
+}
+
+// Stop tears down the current session, if any.
+func (m *TunnelManager) Stop() {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+
+	if m.done == nil {
+		return
+	}
+
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+	m.done = nil
+}
+
+func (m *TunnelManager) setStatus(fn func(s *Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(&m.status)
+}
+
+// supervise restarts the tunnel whenever it exits until the session
+// is over, either because it was stopped, it timed out or the tunnel
+// crashed too many times.
+func (m *TunnelManager) supervise(ctx context.Context, p *process, done chan struct{}) {
+	defer close(done)
+	defer m.setStatus(func(s *Status) { *s = Status{} })
+
+	restarts := 0
+	for {
+		select {
+		case <-ctx.Done():
+			<-p.done
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				log.Printf("Support session timed out after %s", m.cfg.SessionTimeout)
+				m.detach(done)
+			} else {
+				log.Printf("Support session stopped")
+			}
+			return
+		case <-p.done:
+		}
+
+		for {
+			restarts++
+			if restarts > m.cfg.MaxRestarts {
+				log.Printf("Tunnel exited %d times, ending the support session", restarts)
+				m.detach(done)
+				return
+			}
+
+			log.Printf("Tunnel exited (%v), restarting in %s", p.err, m.cfg.RestartDelay)
+			select {
+			case <-ctx.Done():
+				m.detach(done)
+				return
+			case <-time.After(m.cfg.RestartDelay):
+			}
+
+			next, err := m.launch(ctx, ctx)
+			if err != nil {
+				log.Printf("Failed to restart tunnel: %v", err)
+				continue
+			}
+
+			p = next
+			m.setStatus(func(s *Status) {
+				s.ProxyURL = p.url
+				s.Pid = p.pid
+				s.Restarts = restarts
+			})
+			break
+		}
+	}
+}
+
+// detach forgets the session identified by done from within its
+// supervisor, when it ends on its own, so that a new one can be started.
+func (m *TunnelManager) detach(done chan struct{}) {
+	go func() {
+		<-done
+
+		m.sessionMu.Lock()
+		defer m.sessionMu.Unlock()
+		if m.done != done {
+			return
+		}
+
+		m.cancel()
+		m.cancel = nil
+		m.done = nil
+	}()
+}
+
+// process is a running tunnel.
+type process struct {
+	pid  int
+	url  string
+	done chan struct{}
+	err  error
+}
+
+// launch starts the tunnel binary and waits, at most until ctx is done,
+// for it to connect. The process is bound to runCtx.
+func (m *TunnelManager) launch(ctx, runCtx context.Context) (*process, error) {
+	procCtx, cancel := context.WithCancel(runCtx)
+
+	cmd := exec.CommandContext(procCtx, m.cfg.BinaryPath, m.cfg.Args(m.cfg.LocalURL)...) //nolint:gosec
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = stopTimeout
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil { //nolint:govet
+		cancel()
+		return nil, err
+	}
+
+	p := &process{pid: cmd.Process.Pid, done: make(chan struct{})}
+	connected := make(chan string, 1)
+
+	go func() {
+		defer close(p.done)
+		defer cancel()
+
+		watchOutput(stderr, connected)
+		p.err = cmd.Wait()
+	}()
+
+	timer := time.NewTimer(m.cfg.ConnectTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.url = <-connected:
+		return p, nil
+	case <-p.done:
+		return nil, fmt.Errorf("%w: %v", ErrTunnelExited, p.err)
+	case <-timer.C:
+		err = ErrConnectTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cancel()
+	<-p.done
+	return nil, err
+}
+
+// watchOutput reads the tunnel output until it exits, sending the
+// proxy URL on connected once the tunnel is connected. The output
+// must be read until the end or the tunnel blocks writing to it.
+func watchOutput(r io.Reader, connected chan<- string) {
+	var proxyURL string
+	notified := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if notified {
+			continue
+		}
+
+		line := scanner.Text()
+		if matches := urlRe.FindStringSubmatch(line); matches != nil {
+			proxyURL = matches[1]
+		}
+		if connectedRe.MatchString(line) {
+			connected <- proxyURL
+			notified = true
+		}
+	}
+}
+
+func generateSessionCode() (string, error) {
+	parts := [3]int64{}
+	for i := range parts {
+		n, err := rand.Int(rand.Reader, big.NewInt(10000)) //nolint:gomnd
+		if err != nil {
+			return "", err
+		}
+		parts[i] = n.Int64()
+	}
+
+	return fmt.Sprintf("%04d-%04d-%04d", parts[0], parts[1], parts[2]), nil
+}
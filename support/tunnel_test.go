@@ -0,0 +1,151 @@
+package support
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const connectScript = `#!/bin/sh
+echo "2024-01-01T00:00:00Z INF |  https://fake.trycloudflare.com  |" >&2
+echo "2024-01-01T00:00:00Z INF Registered tunnel connection" >&2
+`
+
+// fakeTunnel writes a fake tunnel binary running body and returns its path.
+func fakeTunnel(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cloudflared")
+	if err := os.WriteFile(path, []byte(body), 0700); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	return path
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTunnelManagerStartStop(t *testing.T) {
+	m := NewTunnelManager(Config{
+		BinaryPath: fakeTunnel(t, connectScript+"exec sleep 60\n"),
+	})
+
+	status, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+
+	if !status.Started || status.ProxyURL != "https://fake.trycloudflare.com" || status.Pid == 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if !regexp.MustCompile(`^\d{4}-\d{4}-\d{4}$`).MatchString(status.SessionCode) {
+		t.Errorf("unexpected session code %q", status.SessionCode)
+	}
+
+	again, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Pid != status.Pid || again.SessionCode != status.SessionCode {
+		t.Errorf("second start didn't return the running session: %+v", again)
+	}
+
+	m.Stop()
+	if m.Status().Started || m.SessionCode() != "" {
+		t.Errorf("session still running after stop: %+v", m.Status())
+	}
+}
+
+func TestTunnelManagerRestartsOnCrash(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "crashed")
+	m := NewTunnelManager(Config{
+		BinaryPath:   fakeTunnel(t, connectScript+"[ -e \"$1\" ] && exec sleep 60\ntouch \"$1\"\nexit 1\n"),
+		Args:         func(string) []string { return []string{marker} },
+		RestartDelay: 10 * time.Millisecond,
+	})
+
+	status, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+
+	waitFor(t, "the tunnel to restart", func() bool { return m.Status().Restarts == 1 })
+
+	restarted := m.Status()
+	if !restarted.Started || restarted.Pid == status.Pid || restarted.SessionCode != status.SessionCode {
+		t.Errorf("unexpected status after restart: %+v", restarted)
+	}
+}
+
+func TestTunnelManagerGivesUpAfterMaxRestarts(t *testing.T) {
+	m := NewTunnelManager(Config{
+		BinaryPath:   fakeTunnel(t, connectScript+"exit 1\n"),
+		MaxRestarts:  2,
+		RestartDelay: 10 * time.Millisecond,
+	})
+
+	if _, err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+
+	waitFor(t, "the session to end", func() bool { return !m.Status().Started })
+	waitFor(t, "a new session to start", func() bool {
+		status, err := m.Start(context.Background())
+		return err == nil && status.Started
+	})
+}
+
+func TestTunnelManagerSessionTimeout(t *testing.T) {
+	m := NewTunnelManager(Config{
+		BinaryPath:     fakeTunnel(t, connectScript+"exec sleep 60\n"),
+		SessionTimeout: 200 * time.Millisecond,
+	})
+
+	if _, err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+
+	waitFor(t, "the session to time out", func() bool { return !m.Status().Started })
+}
+
+func TestTunnelManagerStartErrors(t *testing.T) {
+	m := NewTunnelManager(Config{BinaryPath: fakeTunnel(t, "#!/bin/sh\nexit 1\n")})
+	if _, err := m.Start(context.Background()); !errors.Is(err, ErrTunnelExited) {
+		t.Errorf("expected ErrTunnelExited, got %v", err)
+	}
+
+	m = NewTunnelManager(Config{
+		BinaryPath:     fakeTunnel(t, "#!/bin/sh\nexec sleep 60\n"),
+		ConnectTimeout: 100 * time.Millisecond,
+	})
+	if _, err := m.Start(context.Background()); !errors.Is(err, ErrConnectTimeout) {
+		t.Errorf("expected ErrConnectTimeout, got %v", err)
+	}
+
+	downloadErr := errors.New("offline")
+	m = NewTunnelManager(Config{
+		BinaryPath: filepath.Join(t.TempDir(), "missing"),
+		Download:   func(context.Context, string) error { return downloadErr },
+	})
+	if _, err := m.Start(context.Background()); !errors.Is(err, downloadErr) {
+		t.Errorf("expected the download error, got %v", err)
+	}
+	if m.Status().Started {
+		t.Error("session started despite errors")
+	}
+}
@@ -0,0 +1,285 @@
+// Package supportbundle builds archives with the diagnostic information
+// support needs to troubleshoot an installation.
+package supportbundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/mholt/archiver/v3"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/version"
+)
+
+// MaxFileSize is the maximum size of a single file in the bundle.
+// Larger files are cut down to their last MaxFileSize bytes.
+const MaxFileSize = 16 * 1024 * 1024 // 16 MB
+
+// ManifestName is the name of the manifest inside the bundle.
+const ManifestName = "manifest.json"
+
+// Format is the archive format of a bundle.
+type Format string
+
+const (
+	FormatZip   Format = "zip"
+	FormatTarGz Format = "targz"
+)
+
+// ParseFormat parses a bundle format, defaulting to zip.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatZip:
+		return FormatZip, nil
+	case FormatTarGz:
+		return FormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unknown bundle format %q: %w", s, fbErrors.ErrInvalidRequestParams)
+	}
+}
+
+// Extension returns the file extension of the format.
+func (f Format) Extension() string {
+	if f == FormatTarGz {
+		return ".tar.gz"
+	}
+	return ".zip"
+}
+
+func (f Format) writer() archiver.Writer {
+	if f == FormatTarGz {
+		return archiver.NewTarGz()
+	}
+	return archiver.NewZip()
+}
+
+// Collector gathers one kind of information into the bundle.
+type Collector interface {
+	// Name names the collector. Its files are stored in a
+	// directory with the same name.
+	Name() string
+	// Collect adds files to the bundle. Failing to collect some
+	// of them shouldn't stop the others: it should keep going and
+	// return all the errors joined together.
+	Collect(ctx context.Context, b *Bundle) error
+}
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	Created    time.Time `json:"created"`
+	Version    string    `json:"version"`
+	CommitSHA  string    `json:"commitSHA"`
+	Hostname   string    `json:"hostname"`
+	Format     Format    `json:"format"`
+	Collectors []*Report `json:"collectors"`
+}
+
+// Report describes what a collector added to the bundle.
+type Report struct {
+	Name     string        `json:"name"`
+	Files    []FileReport  `json:"files"`
+	Errors   []string      `json:"errors"`
+	Duration time.Duration `json:"duration"`
+}
+
+// FileReport describes a file in the bundle.
+type FileReport struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Bundle is the archive being written. Collectors add files to it.
+type Bundle struct {
+	ar      archiver.Writer
+	created time.Time
+	report  *Report
+}
+
+// Write streams a bundle with the files of every collector to w.
+// Collector failures don't stop the bundle from being written:
+// they're recorded in its manifest, which is also returned.
+func Write(ctx context.Context, w io.Writer, format Format, collectors []Collector) (*Manifest, error) {
+	ar := format.writer()
+	if err := ar.Create(w); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	m := &Manifest{
+		Created:    time.Now(),
+		Version:    version.Version,
+		CommitSHA:  version.CommitSHA,
+		Hostname:   hostname,
+		Format:     format,
+		Collectors: []*Report{},
+	}
+
+	b := &Bundle{ar: ar, created: m.Created}
+	for _, c := range collectors {
+		if err := ctx.Err(); err != nil {
+			ar.Close()
+			return nil, err
+		}
+
+		b.report = &Report{Name: c.Name(), Files: []FileReport{}, Errors: []string{}}
+		start := time.Now()
+
+		if err := c.Collect(ctx, b); err != nil {
+			b.report.Errors = append(b.report.Errors, errorStrings(err)...)
+		}
+
+		b.report.Duration = time.Since(start)
+		m.Collectors = append(m.Collectors, b.report)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		ar.Close()
+		return nil, err
+	}
+
+	if err := b.write(ManifestName, data, m.Created); err != nil {
+		ar.Close()
+		return nil, err
+	}
+
+	return m, ar.Close()
+}
+
+// Add adds the contents of r to the bundle as name, inside the directory
+// of the current collector. Only the last MaxFileSize bytes are kept.
+func (b *Bundle) Add(name string, r io.Reader) error {
+	var buf tailBuffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	data := buf.Bytes()
+	return b.addBytes(name, data, buf.truncated)
+}
+
+// AddFile adds the file at path to the bundle as name. Only the
+// last MaxFileSize bytes of the file are kept.
+func (b *Bundle) AddFile(name, filePath string) error {
+	fd, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+
+	truncated := false
+	if info.Size() > MaxFileSize {
+		if _, err := fd.Seek(-MaxFileSize, io.SeekEnd); err != nil { //nolint:govet
+			return err
+		}
+		truncated = true
+	}
+
+	data, err := io.ReadAll(io.LimitReader(fd, MaxFileSize))
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return b.addBytes(name, data, truncated)
+}
+
+// AddJSON adds v, encoded as indented JSON, to the bundle as name.
+func (b *Bundle) AddJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return b.addBytes(name, data, false)
+}
+
+func (b *Bundle) addBytes(name string, data []byte, truncated bool) error {
+	name = path.Join(b.report.Name, path.Clean("/"+name))
+	if err := b.write(name, data, b.created); err != nil {
+		return err
+	}
+
+	b.report.Files = append(b.report.Files, FileReport{
+		Name:      name,
+		Size:      int64(len(data)),
+		Truncated: truncated,
+	})
+	return nil
+}
+
+func (b *Bundle) write(name string, data []byte, modTime time.Time) error {
+	return b.ar.Write(archiver.File{
+		FileInfo: archiver.FileInfo{
+			FileInfo:   &entryInfo{name: path.Base(name), size: int64(len(data)), modTime: modTime},
+			CustomName: name,
+		},
+		ReadCloser: io.NopCloser(bytes.NewReader(data)),
+	})
+}
+
+// errorStrings flattens joined errors into their messages.
+func errorStrings(err error) []string {
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []string{err.Error()}
+	}
+
+	var out []string
+	for _, e := range joined.Unwrap() {
+		out = append(out, errorStrings(e)...)
+	}
+	return out
+}
+
+// tailBuffer keeps the last MaxFileSize bytes written to it.
+type tailBuffer struct {
+	buf       []byte
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	// Only compact once the buffer doubles, so that
+	// long streams aren't copied on every write.
+	if len(t.buf) > 2*MaxFileSize {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-MaxFileSize:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) Bytes() []byte {
+	if len(t.buf) > MaxFileSize {
+		t.truncated = true
+		return t.buf[len(t.buf)-MaxFileSize:]
+	}
+	return t.buf
+}
+
+type entryInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (e *entryInfo) Name() string       { return e.name }
+func (e *entryInfo) Size() int64        { return e.size }
+func (e *entryInfo) Mode() fs.FileMode  { return 0644 } //nolint:gomnd
+func (e *entryInfo) ModTime() time.Time { return e.modTime }
+func (e *entryInfo) IsDir() bool        { return false }
+func (e *entryInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,139 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/users"
+)
+
+func TestWriteZip(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "es_log.txt")
+	if err := os.WriteFile(logPath, []byte("log line\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	set := &settings.Settings{Key: []byte("key"), SupportSecret: "secret", Signup: true}
+	list := []*users.User{{ID: 1, Username: "admin", Password: "hash"}}
+
+	collectors := []Collector{
+		NewCollector("logs", func(_ context.Context, b *Bundle) error {
+			return errors.Join(
+				b.AddFile("es_log.txt", logPath),
+				b.AddFile("missing.txt", filepath.Join(t.TempDir(), "missing.txt")),
+			)
+		}),
+		Settings(set, &settings.Server{Port: "8080", MetricsToken: "bearer-token"}),
+		Users(list),
+	}
+
+	var buf bytes.Buffer
+	m, err := Write(context.Background(), &buf, FormatZip, collectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+
+	names := []string{}
+	for name := range contents {
+		names = append(names, name)
+	}
+	want := []string{"logs/es_log.txt", "settings/settings.json", "settings/server.json", "users/users.json", ManifestName}
+	if len(names) != len(want) {
+		t.Fatalf("got files %v; want %v", names, want)
+	}
+
+	if contents["logs/es_log.txt"] != "log line\n" {
+		t.Errorf("unexpected log contents %q", contents["logs/es_log.txt"])
+	}
+	if strings.Contains(contents["settings/settings.json"], "secret") || strings.Contains(contents["settings/settings.json"], `"key": "`) {
+		t.Errorf("settings weren't redacted: %s", contents["settings/settings.json"])
+	}
+	if strings.Contains(contents["settings/server.json"], "bearer-token") || !strings.Contains(contents["settings/server.json"], "8080") {
+		t.Errorf("server settings weren't redacted: %s", contents["settings/server.json"])
+	}
+	if strings.Contains(contents["users/users.json"], "hash") || !strings.Contains(contents["users/users.json"], "admin") {
+		t.Errorf("users weren't redacted: %s", contents["users/users.json"])
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(contents[ManifestName]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(manifest.Collectors[0].Files, m.Collectors[0].Files) {
+		t.Errorf("manifest files %v; want %v", manifest.Collectors[0].Files, m.Collectors[0].Files)
+	}
+	if len(manifest.Collectors[0].Errors) != 1 || !strings.Contains(manifest.Collectors[0].Errors[0], "missing.txt") {
+		t.Errorf("unexpected logs errors %v", manifest.Collectors[0].Errors)
+	}
+	if len(manifest.Collectors[1].Errors) != 0 || len(manifest.Collectors[2].Errors) != 0 {
+		t.Errorf("unexpected errors in %+v", manifest.Collectors)
+	}
+}
+
+func TestWriteTarGzTruncates(t *testing.T) {
+	big := strings.Repeat("a", MaxFileSize) + "tail"
+
+	var buf bytes.Buffer
+	m, err := Write(context.Background(), &buf, FormatTarGz, []Collector{
+		NewCollector("big", func(_ context.Context, b *Bundle) error {
+			return b.Add("big.txt", strings.NewReader(big))
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f := m.Collectors[0].Files[0]; !f.Truncated || f.Size != MaxFileSize {
+		t.Errorf("unexpected file report %+v", f)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "big/big.txt" || !strings.HasSuffix(string(data), "atail") || len(data) != MaxFileSize {
+		t.Errorf("unexpected entry %s with %d bytes", hdr.Name, len(data))
+	}
+
+	if hdr, err = tr.Next(); err != nil || hdr.Name != ManifestName {
+		t.Errorf("expected the manifest, got %v (%v)", hdr, err)
+	}
+}
@@ -0,0 +1,153 @@
+package supportbundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+
+	"github.com/filebrowser/filebrowser/v2/settings"
+	"github.com/filebrowser/filebrowser/v2/sysinfo"
+	"github.com/filebrowser/filebrowser/v2/users"
+	"github.com/filebrowser/filebrowser/v2/virtual"
+)
+
+type collector struct {
+	name string
+	fn   func(ctx context.Context, b *Bundle) error
+}
+
+func (c collector) Name() string {
+	return c.name
+}
+
+func (c collector) Collect(ctx context.Context, b *Bundle) error {
+	return c.fn(ctx, b)
+}
+
+// NewCollector creates a Collector named name that runs fn.
+func NewCollector(name string, fn func(ctx context.Context, b *Bundle) error) Collector {
+	return collector{name: name, fn: fn}
+}
+
+// Virtual collects the files of every virtual directory, such as the logs.
+func Virtual(registry *virtual.Registry) Collector {
+	return NewCollector("virtual", func(ctx context.Context, b *Bundle) error {
+		root, err := registry.Stat("/")
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, mount := range root.Items {
+			dir, err := registry.Stat(mount.Name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", mount.Name, err))
+				continue
+			}
+
+			for _, item := range dir.Items {
+				if ctx.Err() != nil {
+					return errors.Join(append(errs, ctx.Err())...)
+				}
+
+				name := path.Join(mount.Name, item.Name)
+				if err := addVirtual(b, registry, name); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				}
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+func addVirtual(b *Bundle, registry *virtual.Registry, name string) error {
+	if realPath, err := registry.RealPath(name); err == nil {
+		return b.AddFile(name, realPath)
+	}
+
+	r, err := registry.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return b.Add(name, r)
+}
+
+// Command collects the combined output of a command as file.
+func Command(name, file string, command ...string) Collector {
+	return NewCollector(name, func(ctx context.Context, b *Bundle) error {
+		out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput() //nolint:gosec
+		if len(out) > 0 || err == nil {
+			if err := b.addBytes(file, out, false); err != nil { //nolint:govet
+				return err
+			}
+		}
+		return err
+	})
+}
+
+// Sysinfo collects the system information, along with the
+// output of script if it's set.
+func Sysinfo(script []string) Collector {
+	return NewCollector("sysinfo", func(ctx context.Context, b *Bundle) error {
+		info := sysinfo.Collect(ctx, sysinfo.Options{Processes: true, Script: script})
+
+		var errs []error
+		if info.Script != nil {
+			errs = append(errs, b.addBytes("script.txt", []byte(info.Script.Output), false))
+			info.Script.Output = ""
+		}
+		errs = append(errs, b.AddJSON("sysinfo.json", info))
+
+		failed := make([]string, 0, len(info.Errors))
+		for name := range info.Errors {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+
+		for _, name := range failed {
+			errs = append(errs, fmt.Errorf("%s: %s", name, info.Errors[name]))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Settings collects the settings, without their secrets.
+func Settings(set *settings.Settings, server *settings.Server) Collector {
+	return NewCollector("settings", func(_ context.Context, b *Bundle) error {
+		redacted := *set
+		redacted.Key = nil
+		redacted.SupportSecret = ""
+
+		redactedServer := *server
+		redactedServer.MetricsToken = ""
+
+		return errors.Join(
+			b.AddJSON("settings.json", redacted),
+			b.AddJSON("server.json", redactedServer),
+		)
+	})
+}
+
+// redactedUser hides the password hash of a user.
+type redactedUser struct {
+	*users.User
+	Password string `json:"password,omitempty"`
+}
+
+// Users collects the users, without their password hashes.
+func Users(list []*users.User) Collector {
+	return NewCollector("users", func(_ context.Context, b *Bundle) error {
+		redacted := make([]redactedUser, 0, len(list))
+		for _, u := range list {
+			redacted = append(redacted, redactedUser{User: u})
+		}
+
+		return b.AddJSON("users.json", redacted)
+	})
+}
@@ -0,0 +1,97 @@
+package sysinfo
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/filebrowser/filebrowser/v2/metrics"
+)
+
+const prefix = "filebrowser_system_"
+
+// WritePrometheus writes info as metrics in the Prometheus text format.
+func WritePrometheus(w io.Writer, info *Info) error {
+	mw := metrics.NewWriter(w)
+	write := func(name, help string, typ metrics.Type, samples ...metrics.Sample) {
+		mw.Write(prefix+name, help, typ, samples...)
+	}
+
+	if _, failed := info.Errors["host"]; !failed {
+		write("uptime_seconds", "Time since the system booted.", metrics.Gauge,
+			metrics.Value(float64(info.Host.Uptime)))
+		write("boot_time_seconds", "Time the system booted, in seconds since the epoch.", metrics.Gauge,
+			metrics.Value(float64(info.Host.BootTime.Unix())))
+	}
+
+	write("cpu_cores", "Number of logical CPU cores.", metrics.Gauge, metrics.Value(float64(info.CPU.Cores)))
+	write("cpu_usage_percent", "CPU usage across all cores.", metrics.Gauge, metrics.Value(info.CPU.Percent))
+	write("load1", "1 minute load average.", metrics.Gauge, metrics.Value(info.CPU.Load1))
+	write("load5", "5 minutes load average.", metrics.Gauge, metrics.Value(info.CPU.Load5))
+	write("load15", "15 minutes load average.", metrics.Gauge, metrics.Value(info.CPU.Load15))
+
+	temps := make([]metrics.Sample, 0, len(info.Temperatures))
+	for _, t := range info.Temperatures {
+		temps = append(temps, metrics.Labeled(t.Celsius, "sensor", t.Sensor))
+	}
+	write("temperature_celsius", "Temperature sensor readings.", metrics.Gauge, temps...)
+
+	write("memory_total_bytes", "Total memory.", metrics.Gauge, metrics.Value(float64(info.Memory.Total)))
+	write("memory_available_bytes", "Memory available for new processes.", metrics.Gauge,
+		metrics.Value(float64(info.Memory.Available)))
+	write("memory_used_bytes", "Used memory.", metrics.Gauge, metrics.Value(float64(info.Memory.Used)))
+	write("swap_total_bytes", "Total swap space.", metrics.Gauge, metrics.Value(float64(info.Memory.SwapTotal)))
+	write("swap_used_bytes", "Used swap space.", metrics.Gauge, metrics.Value(float64(info.Memory.SwapUsed)))
+
+	var total, used, free []metrics.Sample
+	for _, d := range info.Disks {
+		labels := []string{"device", d.Device, "mountpoint", d.Mountpoint, "fstype", d.Fstype}
+		total = append(total, metrics.Labeled(float64(d.Total), labels...))
+		used = append(used, metrics.Labeled(float64(d.Used), labels...))
+		free = append(free, metrics.Labeled(float64(d.Free), labels...))
+	}
+	write("disk_total_bytes", "Filesystem size.", metrics.Gauge, total...)
+	write("disk_used_bytes", "Used filesystem space.", metrics.Gauge, used...)
+	write("disk_free_bytes", "Free filesystem space.", metrics.Gauge, free...)
+
+	var sent, recv []metrics.Sample
+	for _, iface := range info.Network {
+		sent = append(sent, metrics.Labeled(float64(iface.BytesSent), "interface", iface.Name))
+		recv = append(recv, metrics.Labeled(float64(iface.BytesRecv), "interface", iface.Name))
+	}
+	write("network_transmit_bytes_total", "Bytes sent by the network interface.", metrics.Counter, sent...)
+	write("network_receive_bytes_total", "Bytes received by the network interface.", metrics.Counter, recv...)
+
+	if info.Processes != nil {
+		write("processes", "Number of running processes.", metrics.Gauge,
+			metrics.Value(float64(len(info.Processes))))
+	}
+
+	if info.Script != nil {
+		write("script_return_code", "Return code of the sysinfo script.", metrics.Gauge,
+			metrics.Value(float64(info.Script.ReturnCode)))
+	}
+
+	failed := make([]string, 0, len(info.Errors))
+	for name := range info.Errors {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+
+	errs := make([]metrics.Sample, 0, len(failed))
+	for _, name := range failed {
+		errs = append(errs, metrics.Labeled(1, "section", name))
+	}
+	write("collect_errors", "Sections that failed to be collected.", metrics.Gauge, errs...)
+
+	write("info", "Information about the host.", metrics.Gauge, metrics.Labeled(1,
+		"hostname", info.Host.Hostname,
+		"platform", info.Host.Platform,
+		"platform_version", info.Host.PlatformVersion,
+		"kernel", info.Host.KernelVersion,
+		"arch", info.Host.Arch,
+		"version", strconv.Itoa(info.Version),
+	))
+
+	return mw.Flush()
+}
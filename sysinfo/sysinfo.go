@@ -0,0 +1,372 @@
+// Package sysinfo gathers information about the system File Browser runs on.
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Version is the version of the Info layout.
+const Version = 2
+
+// Info is a snapshot of the system state.
+type Info struct {
+	Version      int               `json:"version"`
+	Time         time.Time         `json:"time"`
+	Host         Host              `json:"host"`
+	CPU          CPU               `json:"cpu"`
+	Temperatures []Temperature     `json:"temperatures"`
+	Memory       Memory            `json:"memory"`
+	Disks        []Disk            `json:"disks"`
+	Network      []Interface       `json:"network"`
+	Processes    []Process         `json:"processes,omitempty"`
+	Script       *Script           `json:"script,omitempty"`
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// Host describes the host and its operating system.
+type Host struct {
+	Hostname        string    `json:"hostname"`
+	OS              string    `json:"os"`
+	Platform        string    `json:"platform"`
+	PlatformVersion string    `json:"platformVersion"`
+	KernelVersion   string    `json:"kernelVersion"`
+	Arch            string    `json:"arch"`
+	Uptime          uint64    `json:"uptime"`
+	BootTime        time.Time `json:"bootTime"`
+}
+
+// CPU describes the processor and its load.
+type CPU struct {
+	Model   string  `json:"model"`
+	Cores   int     `json:"cores"`
+	Percent float64 `json:"percent"`
+	Load1   float64 `json:"load1"`
+	Load5   float64 `json:"load5"`
+	Load15  float64 `json:"load15"`
+}
+
+// Temperature is the reading of a temperature sensor.
+type Temperature struct {
+	Sensor   string  `json:"sensor"`
+	Celsius  float64 `json:"celsius"`
+	High     float64 `json:"high,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// Memory describes the memory and swap usage, in bytes.
+type Memory struct {
+	Total       uint64  `json:"total"`
+	Available   uint64  `json:"available"`
+	Used        uint64  `json:"used"`
+	UsedPercent float64 `json:"usedPercent"`
+	SwapTotal   uint64  `json:"swapTotal"`
+	SwapUsed    uint64  `json:"swapUsed"`
+}
+
+// Disk describes the usage of a mounted filesystem, in bytes.
+type Disk struct {
+	Device      string  `json:"device"`
+	Mountpoint  string  `json:"mountpoint"`
+	Fstype      string  `json:"fstype"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Free        uint64  `json:"free"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+// Interface describes a network interface and its traffic.
+type Interface struct {
+	Name         string   `json:"name"`
+	HardwareAddr string   `json:"hardwareAddr"`
+	MTU          int      `json:"mtu"`
+	Flags        []string `json:"flags"`
+	Addrs        []string `json:"addrs"`
+	BytesSent    uint64   `json:"bytesSent"`
+	BytesRecv    uint64   `json:"bytesRecv"`
+}
+
+// Process describes a running process.
+type Process struct {
+	PID        int32     `json:"pid"`
+	Name       string    `json:"name"`
+	Username   string    `json:"username"`
+	Status     string    `json:"status"`
+	CPUPercent float64   `json:"cpuPercent"`
+	MemoryRSS  uint64    `json:"memoryRSS"`
+	Started    time.Time `json:"started"`
+}
+
+// Script is the output of the sysinfo script.
+type Script struct {
+	ReturnCode int    `json:"returnCode"`
+	Output     string `json:"output"`
+}
+
+// Options select the optional sections of Info.
+type Options struct {
+	// Processes includes the process list.
+	Processes bool
+	// Script, if set, is run and its output included.
+	Script []string
+}
+
+type section struct {
+	name string
+	fn   func(ctx context.Context, info *Info) error
+}
+
+// Collect gathers the system information. Sections that can't be
+// read are left empty and their errors reported in Info.Errors.
+func Collect(ctx context.Context, opts Options) *Info {
+	info := &Info{
+		Version:      Version,
+		Time:         time.Now(),
+		Temperatures: []Temperature{},
+		Disks:        []Disk{},
+		Network:      []Interface{},
+		Errors:       map[string]string{},
+	}
+
+	sections := []section{
+		{"host", collectHost},
+		{"cpu", collectCPU},
+		{"temperatures", collectTemperatures},
+		{"memory", collectMemory},
+		{"disks", collectDisks},
+		{"network", collectNetwork},
+	}
+	if opts.Processes {
+		sections = append(sections, section{"processes", collectProcesses})
+	}
+
+	for _, s := range sections {
+		if err := s.fn(ctx, info); err != nil {
+			info.Errors[s.name] = err.Error()
+		}
+	}
+
+	if len(opts.Script) > 0 {
+		info.Script = runScript(ctx, opts.Script)
+	}
+
+	return info
+}
+
+func collectHost(ctx context.Context, info *Info) error {
+	h, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	info.Host = Host{
+		Hostname:        h.Hostname,
+		OS:              h.OS,
+		Platform:        h.Platform,
+		PlatformVersion: h.PlatformVersion,
+		KernelVersion:   h.KernelVersion,
+		Arch:            h.KernelArch,
+		Uptime:          h.Uptime,
+		BootTime:        time.Unix(int64(h.BootTime), 0), //nolint:gosec
+	}
+	return nil
+}
+
+func collectCPU(ctx context.Context, info *Info) error {
+	var errs []error
+
+	if infos, err := cpu.InfoWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	} else if len(infos) > 0 {
+		info.CPU.Model = infos[0].ModelName
+	}
+
+	if cores, err := cpu.CountsWithContext(ctx, true); err != nil {
+		errs = append(errs, err)
+	} else {
+		info.CPU.Cores = cores
+	}
+
+	if percent, err := cpu.PercentWithContext(ctx, 0, false); err != nil {
+		errs = append(errs, err)
+	} else if len(percent) > 0 {
+		info.CPU.Percent = percent[0]
+	}
+
+	if avg, err := load.AvgWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		info.CPU.Load1, info.CPU.Load5, info.CPU.Load15 = avg.Load1, avg.Load5, avg.Load15
+	}
+
+	return errors.Join(errs...)
+}
+
+func collectTemperatures(ctx context.Context, info *Info) error {
+	temps, err := host.SensorsTemperaturesWithContext(ctx)
+	for _, t := range temps {
+		info.Temperatures = append(info.Temperatures, Temperature{
+			Sensor:   t.SensorKey,
+			Celsius:  t.Temperature,
+			High:     t.High,
+			Critical: t.Critical,
+		})
+	}
+
+	// Some sensors failing to be read isn't worth reporting
+	// as long as there are others.
+	if len(temps) > 0 {
+		return nil
+	}
+	return err
+}
+
+func collectMemory(ctx context.Context, info *Info) error {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	info.Memory = Memory{
+		Total:       vm.Total,
+		Available:   vm.Available,
+		Used:        vm.Used,
+		UsedPercent: vm.UsedPercent,
+	}
+
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	info.Memory.SwapTotal = swap.Total
+	info.Memory.SwapUsed = swap.Used
+	return nil
+}
+
+func collectDisks(ctx context.Context, info *Info) error {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range partitions {
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		info.Disks = append(info.Disks, Disk{
+			Device:      p.Device,
+			Mountpoint:  p.Mountpoint,
+			Fstype:      p.Fstype,
+			Total:       usage.Total,
+			Used:        usage.Used,
+			Free:        usage.Free,
+			UsedPercent: usage.UsedPercent,
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+func collectNetwork(ctx context.Context, info *Info) error {
+	ifaces, err := net.InterfacesWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	io := map[string]net.IOCountersStat{}
+	for _, c := range counters {
+		io[c.Name] = c
+	}
+
+	for _, iface := range ifaces {
+		addrs := make([]string, 0, len(iface.Addrs))
+		for _, a := range iface.Addrs {
+			addrs = append(addrs, a.Addr)
+		}
+
+		info.Network = append(info.Network, Interface{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr,
+			MTU:          iface.MTU,
+			Flags:        iface.Flags,
+			Addrs:        addrs,
+			BytesSent:    io[iface.Name].BytesSent,
+			BytesRecv:    io[iface.Name].BytesRecv,
+		})
+	}
+
+	return nil
+}
+
+func collectProcesses(ctx context.Context, info *Info) error {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	info.Processes = make([]Process, 0, len(procs))
+	for _, p := range procs {
+		// Processes can exit while they're being read, so
+		// their details are gathered on a best effort basis.
+		proc := Process{PID: p.Pid}
+		proc.Name, _ = p.NameWithContext(ctx)
+		proc.Username, _ = p.UsernameWithContext(ctx)
+		proc.CPUPercent, _ = p.CPUPercentWithContext(ctx)
+
+		if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+			proc.Status = status[0]
+		}
+		if mi, err := p.MemoryInfoWithContext(ctx); err == nil {
+			proc.MemoryRSS = mi.RSS
+		}
+		if created, err := p.CreateTimeWithContext(ctx); err == nil {
+			proc.Started = time.UnixMilli(created)
+		}
+
+		info.Processes = append(info.Processes, proc)
+	}
+
+	sort.Slice(info.Processes, func(i, j int) bool {
+		return info.Processes[i].CPUPercent > info.Processes[j].CPUPercent
+	})
+	return nil
+}
+
+func runScript(ctx context.Context, command []string) *Script {
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput() //nolint:gosec
+	script := &Script{Output: string(out)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		script.ReturnCode = exitErr.ExitCode()
+		script.Output = "ERROR: " + err.Error() + "\n\n" + string(out)
+	default:
+		script.ReturnCode = 999 //nolint:gomnd
+		script.Output = "Command error: " + err.Error()
+	}
+
+	return script
+}
@@ -0,0 +1,65 @@
+package sysinfo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollect(t *testing.T) {
+	info := Collect(context.Background(), Options{
+		Processes: true,
+		Script:    []string{"sh", "-c", "echo hello; exit 3"},
+	})
+
+	if info.Version != Version {
+		t.Errorf("got version %d; want %d", info.Version, Version)
+	}
+	if _, failed := info.Errors["processes"]; !failed && len(info.Processes) == 0 {
+		t.Error("expected at least the test process in the process list")
+	}
+	if info.Script == nil || info.Script.ReturnCode != 3 || !strings.Contains(info.Script.Output, "hello") {
+		t.Errorf("unexpected script section %+v", info.Script)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	info := &Info{
+		Version: Version,
+		Host: Host{
+			Hostname: "rcade",
+			Platform: "debian",
+			Uptime:   60,
+			BootTime: time.Unix(1000, 0),
+		},
+		CPU:          CPU{Cores: 4, Load1: 0.5},
+		Temperatures: []Temperature{{Sensor: `cpu "thermal"`, Celsius: 45.5}},
+		Disks:        []Disk{{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4", Total: 100, Used: 40, Free: 60}},
+		Errors:       map[string]string{"network": "boom"},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE filebrowser_system_uptime_seconds gauge\nfilebrowser_system_uptime_seconds 60\n",
+		"filebrowser_system_boot_time_seconds 1000\n",
+		"filebrowser_system_load1 0.5\n",
+		`filebrowser_system_temperature_celsius{sensor="cpu \"thermal\""} 45.5` + "\n",
+		`filebrowser_system_disk_used_bytes{device="/dev/sda1",mountpoint="/",fstype="ext4"} 40` + "\n",
+		`filebrowser_system_collect_errors{section="network"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "network_transmit_bytes_total") || strings.Contains(out, "filebrowser_system_processes") {
+		t.Errorf("output has metrics of empty sections:\n%s", out)
+	}
+}
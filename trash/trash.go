@@ -0,0 +1,327 @@
+// Package trash implements the per-user recycle bin deleted files
+// are moved to instead of being removed right away.
+//
+// Every bin lives under Dir in the scope of its owner and keeps the
+// deleted files under "files" and one JSON document per item, with
+// its original path and deletion time, under "info":
+//
+//	/.trash/<owner>/files/<id>
+//	/.trash/<owner>/info/<id>.json
+package trash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/fileutils"
+)
+
+// Dir is the directory, relative to the scope of a user, the
+// trash bins are kept in.
+const Dir = "/.trash"
+
+var validID = regexp.MustCompile(`^[0-9a-z]+-[0-9a-f]+$`)
+
+// BinPath returns the path of the bin of the user with the given id,
+// relative to the scope of the user.
+func BinPath(id uint) string {
+	return path.Join(Dir, strconv.FormatUint(uint64(id), 10))
+}
+
+// IsTrashPath reports whether p is one of bins or is inside of one.
+func IsTrashPath(p string, bins ...string) bool {
+	p = path.Clean("/" + p)
+	for _, bin := range bins {
+		if p == bin || strings.HasPrefix(p, bin+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Item is a file or directory in the trash.
+type Item struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	IsDir     bool      `json:"isDir"`
+	Size      int64     `json:"size"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Bin is the trash of a single owner.
+type Bin struct {
+	fs   afero.Fs
+	root string
+}
+
+// New returns the bin of owner in fs. Owners sharing a scope
+// get separate bins.
+func New(fs afero.Fs, owner string) *Bin {
+	return &Bin{fs: fs, root: path.Join(Dir, owner)}
+}
+
+// NewForUser returns the bin of the user with the given id.
+func NewForUser(fs afero.Fs, id uint) *Bin {
+	return &Bin{fs: fs, root: BinPath(id)}
+}
+
+// holds reports whether moving p would move the bin or something in
+// it.
+func (b *Bin) holds(p string) bool {
+	return IsTrashPath(p, b.root) || IsTrashPath(b.root, p)
+}
+
+func (b *Bin) filePath(id string) string {
+	return path.Join(b.root, "files", id)
+}
+
+func (b *Bin) infoPath(id string) string {
+	return path.Join(b.root, "info", id+".json")
+}
+
+// Move moves the file or directory at p into the bin. It fails with
+// ErrOtherDevice if p isn't on the same device as the bin.
+func (b *Bin) Move(p string) (*Item, error) {
+	p = path.Clean("/" + p)
+	if p == "/" || b.holds(p) {
+		return nil, fbErrors.ErrPermissionDenied
+	}
+
+	info, err := b.fs.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{
+		ID:        id,
+		Path:      p,
+		IsDir:     info.IsDir(),
+		Size:      size(b.fs, p, info),
+		DeletedAt: time.Now().UTC(),
+	}
+
+	if err := b.fs.MkdirAll(path.Join(b.root, "files"), files.PermDir); err != nil {
+		return nil, err
+	}
+	if err := b.writeInfo(item); err != nil {
+		return nil, err
+	}
+
+	// Never copy: files on other devices, such as USB sticks, would
+	// fill the device holding the bin.
+	if err := b.fs.Rename(p, b.filePath(id)); err != nil {
+		_ = b.fs.Remove(b.infoPath(id))
+		if errors.Is(err, syscall.EXDEV) {
+			return nil, fmt.Errorf("%s can't be moved to the trash: %w", p, fbErrors.ErrOtherDevice)
+		}
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// List returns the items in the bin, most recently deleted first.
+func (b *Bin) List() ([]*Item, error) {
+	entries, err := afero.ReadDir(b.fs, path.Join(b.root, "info"))
+	if os.IsNotExist(err) {
+		return []*Item{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		item, err := b.Get(id)
+		if err != nil {
+			// Leftovers of an interrupted move or purge.
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// Get returns the item with the given id.
+func (b *Bin) Get(id string) (*Item, error) {
+	if !validID.MatchString(id) {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	data, err := afero.ReadFile(b.fs, b.infoPath(id))
+	if os.IsNotExist(err) {
+		return nil, fbErrors.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, fmt.Errorf("trash item %s: %w", id, err)
+	}
+
+	if _, err := b.fs.Stat(b.filePath(id)); err != nil {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return item, nil
+}
+
+// Restore moves the item with the given id back to dst, or to its
+// original path if dst is empty. Missing parent directories are
+// created; an existing file at the destination is never replaced.
+func (b *Bin) Restore(id, dst string) (*Item, error) {
+	item, err := b.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dst == "" {
+		dst = item.Path
+	}
+	dst = path.Clean("/" + dst)
+	if dst == "/" || b.holds(dst) {
+		return nil, fbErrors.ErrPermissionDenied
+	}
+
+	if _, err := b.fs.Stat(dst); err == nil {
+		return nil, fbErrors.ErrExist
+	}
+	if err := b.fs.MkdirAll(path.Dir(dst), files.PermDir); err != nil {
+		return nil, err
+	}
+
+	if err := fileutils.MoveFile(b.fs, b.filePath(id), dst); err != nil {
+		return nil, err
+	}
+	if err := b.fs.Remove(b.infoPath(id)); err != nil {
+		return nil, err
+	}
+
+	item.Path = dst
+	return item, nil
+}
+
+// Purge permanently removes the item with the given id.
+func (b *Bin) Purge(id string) error {
+	if _, err := b.Get(id); err != nil {
+		return err
+	}
+	return b.remove(id)
+}
+
+// Empty permanently removes every item in the bin.
+func (b *Bin) Empty() error {
+	err := b.fs.RemoveAll(b.root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Prune permanently removes the items deleted more than maxAge ago
+// and then, oldest first, as many items as needed to bring the size
+// of the bin down to maxSize. Zero disables the respective limit.
+// It returns the removed items.
+func (b *Bin) Prune(maxAge time.Duration, maxSize int64) ([]*Item, error) {
+	items, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, item := range items {
+		total += item.Size
+	}
+
+	var pruned []*Item
+	cutoff := time.Now().Add(-maxAge)
+
+	// Items are sorted newest first, so walk them backwards.
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		expired := maxAge > 0 && item.DeletedAt.Before(cutoff)
+		oversize := maxSize > 0 && total > maxSize
+		if !expired && !oversize {
+			break
+		}
+
+		if err := b.remove(item.ID); err != nil {
+			return pruned, err
+		}
+		total -= item.Size
+		pruned = append(pruned, item)
+	}
+
+	return pruned, nil
+}
+
+func (b *Bin) remove(id string) error {
+	if err := b.fs.RemoveAll(b.filePath(id)); err != nil {
+		return err
+	}
+	return b.fs.Remove(b.infoPath(id))
+}
+
+func (b *Bin) writeInfo(item *Item) error {
+	if err := b.fs.MkdirAll(path.Join(b.root, "info"), files.PermDir); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(b.fs, b.infoPath(item.ID), data, files.PermFile)
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 4) //nolint:gomnd
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + hex.EncodeToString(buf), nil
+}
+
+// size returns the size of a file or the total size of the files
+// in a directory.
+func size(fs afero.Fs, p string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+
+	var total int64
+	_ = afero.Walk(fs, p, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
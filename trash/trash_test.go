@@ -0,0 +1,274 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+func newFs(t *testing.T) afero.Fs {
+	t.Helper()
+	return afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+}
+
+func writeFile(t *testing.T, fs afero.Fs, p, content string) {
+	t.Helper()
+	if err := fs.MkdirAll(path.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoveAndRestore(t *testing.T) {
+	fs := newFs(t)
+	writeFile(t, fs, "/roms/snes/zelda.sfc", "zelda")
+	writeFile(t, fs, "/roms/snes/mario.sfc", "mario!")
+
+	bin := NewForUser(fs, 1)
+	item, err := bin.Move("/roms/snes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Path != "/roms/snes" || !item.IsDir || item.Size != 11 {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if exists, _ := afero.Exists(fs, "/roms/snes"); exists {
+		t.Error("directory still exists after being moved to the trash")
+	}
+
+	items, err := bin.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != item.ID {
+		t.Fatalf("unexpected items %+v", items)
+	}
+
+	// Another owner sharing the scope doesn't see it.
+	if others, _ := NewForUser(fs, 2).List(); len(others) != 0 {
+		t.Errorf("item leaked into another bin: %+v", others)
+	}
+
+	writeFile(t, fs, "/roms/snes", "in the way")
+	if _, err := bin.Restore(item.ID, ""); !errors.Is(err, fbErrors.ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+	if err := fs.Remove("/roms/snes"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := bin.Restore(item.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Path != "/roms/snes" {
+		t.Errorf("restored to %s", restored.Path)
+	}
+	if data, _ := afero.ReadFile(fs, "/roms/snes/mario.sfc"); string(data) != "mario!" {
+		t.Errorf("unexpected content %q", data)
+	}
+	if items, _ := bin.List(); len(items) != 0 {
+		t.Errorf("bin not empty after restore: %+v", items)
+	}
+}
+
+func TestRestoreToDestination(t *testing.T) {
+	fs := newFs(t)
+	writeFile(t, fs, "/saves/game.srm", "save")
+
+	bin := NewForUser(fs, 1)
+	item, err := bin.Move("/saves/game.srm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bin.Restore(item.ID, "/.trash/1/x"); !errors.Is(err, fbErrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+
+	if _, err := bin.Restore(item.ID, "/backup/deep/game.srm"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := afero.Exists(fs, "/backup/deep/game.srm"); !exists {
+		t.Error("file wasn't restored to the destination")
+	}
+}
+
+func TestMoveRejectsTrash(t *testing.T) {
+	fs := newFs(t)
+	bin := NewForUser(fs, 1)
+
+	for _, p := range []string{"/", "/.trash", "/.trash/1/files"} {
+		if _, err := bin.Move(p); !errors.Is(err, fbErrors.ErrPermissionDenied) {
+			t.Errorf("%s: expected ErrPermissionDenied, got %v", p, err)
+		}
+	}
+	if _, err := bin.Get("../../etc"); !errors.Is(err, fbErrors.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestIsTrashPath(t *testing.T) {
+	fs := newFs(t)
+	writeFile(t, fs, "/users/bob/save.sav", "save")
+
+	// Bob's scope is inside of the admin's one.
+	bob := NewForUser(afero.NewBasePathFs(fs, "/users/bob"), 2)
+	item, err := bob.Move("/save.sav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bins := []string{BinPath(1), path.Join("/users/bob", BinPath(2))}
+
+	var inBin []string
+	err = afero.Walk(fs, "/users/bob/.trash", func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			inBin = append(inBin, p)
+		}
+		return err
+	})
+	if err != nil || len(inBin) == 0 {
+		t.Fatalf("expected the trashed file to be found, got %v and %v", inBin, err)
+	}
+	for _, p := range inBin {
+		if !IsTrashPath(p, bins...) {
+			t.Errorf("%s: expected a trash path for the admin", p)
+		}
+	}
+
+	// Folders that only share the name of the trash are left alone.
+	for p, want := range map[string]bool{
+		"/.trash/1":                     true,
+		"/users/bob/.trash/2/info":      true,
+		"users/bob/.trash/3/../2/files": true,
+		"/.trash":                       false,
+		"/.trash/notes.txt":             false,
+		"/roms/.trash/1":                false,
+		"/users/bob/.trash/1":           false,
+		"/":                             false,
+	} {
+		if got := IsTrashPath(p, bins...); got != want {
+			t.Errorf("%s: expected %t, got %t", p, want, got)
+		}
+	}
+
+	// Bins can't be moved into themselves.
+	if _, err := bob.Move("/.trash"); !errors.Is(err, fbErrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+	if _, err := bob.Restore(item.ID, ""); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPurgeAndEmpty(t *testing.T) {
+	fs := newFs(t)
+	writeFile(t, fs, "/a", "a")
+	writeFile(t, fs, "/b", "b")
+
+	bin := NewForUser(fs, 1)
+	a, err := bin.Move("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bin.Move("/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bin.Purge(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bin.Purge(a.ID); !errors.Is(err, fbErrors.ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+	if items, _ := bin.List(); len(items) != 1 {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	if err := bin.Empty(); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := bin.List(); len(items) != 0 {
+		t.Errorf("bin not empty: %+v", items)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	fs := newFs(t)
+	bin := NewForUser(fs, 1)
+
+	ids := map[string]string{}
+	for i, name := range []string{"/old", "/mid", "/new"} {
+		writeFile(t, fs, name, "0123456789")
+		item, err := bin.Move(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Backdate the items so they're 3, 2 and 1 days old.
+		item.DeletedAt = time.Now().Add(-time.Duration(3-i) * 24 * time.Hour)
+		if err := bin.writeInfo(item); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = item.ID
+	}
+
+	pruned, err := bin.Prune(60*time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].ID != ids["/old"] {
+		t.Errorf("unexpected pruned items by age %+v", pruned)
+	}
+
+	pruned, err = bin.Prune(0, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].ID != ids["/mid"] {
+		t.Errorf("unexpected pruned items by size %+v", pruned)
+	}
+
+	items, _ := bin.List()
+	if len(items) != 1 || items[0].ID != ids["/new"] {
+		t.Errorf("unexpected remaining items %+v", items)
+	}
+}
+
+// otherDeviceFs fails to rename across devices, like a USB stick
+// mounted in the scope.
+type otherDeviceFs struct {
+	afero.Fs
+}
+
+func (fs otherDeviceFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+}
+
+func TestMoveOtherDevice(t *testing.T) {
+	fs := newFs(t)
+	writeFile(t, fs, "/usb/roms/zelda.sfc", "zelda")
+
+	bin := NewForUser(otherDeviceFs{fs}, 1)
+	if _, err := bin.Move("/usb/roms"); !errors.Is(err, fbErrors.ErrOtherDevice) {
+		t.Fatalf("expected ErrOtherDevice, got %v", err)
+	}
+
+	if exists, _ := afero.Exists(fs, "/usb/roms/zelda.sfc"); !exists {
+		t.Error("expected the file to be left in place")
+	}
+	if items, err := bin.List(); err != nil || len(items) != 0 {
+		t.Errorf("expected an empty bin, got %+v and %v", items, err)
+	}
+	if entries, _ := afero.ReadDir(fs, "/.trash/1/files"); len(entries) != 0 {
+		t.Errorf("expected nothing copied into the bin, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,13 @@
+package users
+
+// Quota limits what a user stores in its scope. Zero values mean
+// there's no limit.
+type Quota struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// Unlimited reports whether the quota doesn't limit anything.
+func (q Quota) Unlimited() bool {
+	return q.Bytes <= 0 && q.Files <= 0
+}
@@ -1,10 +1,12 @@
 package users
 
 import (
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/rules"
 )
 
 // StorageBackend is the interface to implement for a users storage.
@@ -78,6 +80,12 @@ func (s *Storage) Update(user *User, fields ...string) error {
 		return err
 	}
 
+	if len(fields) == 0 || slices.Contains(fields, "Rules") {
+		if err := rules.Compile(user.Rules); err != nil {
+			return err
+		}
+	}
+
 	err = s.back.Update(user, fields...)
 	if err != nil {
 		return err
@@ -95,6 +103,10 @@ func (s *Storage) Save(user *User) error {
 		return err
 	}
 
+	if err := rules.Compile(user.Rules); err != nil {
+		return err
+	}
+
 	return s.back.Save(user)
 }
 
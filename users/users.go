@@ -36,6 +36,11 @@ type User struct {
 	Rules        []rules.Rule  `json:"rules"`
 	HideDotfiles bool          `json:"hideDotfiles"`
 	DateFormat   bool          `json:"dateFormat"`
+	Groups       []uint        `json:"groups"`
+	Quota        Quota         `json:"quota"`
+	// GroupRules are the rules of the groups of the user, set along
+	// with the rest of what it gets from them when they're applied.
+	GroupRules []rules.Rule `json:"-" yaml:"-"`
 }
 
 // GetRules implements rules.Provider.
@@ -51,6 +56,7 @@ var checkableFields = []string{
 	"Commands",
 	"Sorting",
 	"Rules",
+	"Groups",
 }
 
 // Clean cleans up a user and verifies if all its fields
@@ -88,6 +94,10 @@ func (u *User) Clean(baseScope string, fields ...string) error {
 			if u.Rules == nil {
 				u.Rules = []rules.Rule{}
 			}
+		case "Groups":
+			if u.Groups == nil {
+				u.Groups = []uint{}
+			}
 		}
 	}
 
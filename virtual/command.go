@@ -0,0 +1,92 @@
+package virtual
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+)
+
+// CommandTimeout is the maximum time a command backing
+// a virtual file is allowed to run.
+const CommandTimeout = 10 * time.Second
+
+// commandProvider exposes the output of commands as files, which
+// is useful to show system state. The commands are only run when
+// a file is opened, so listed sizes are always zero.
+type commandProvider struct {
+	base     string
+	commands map[string][]string
+}
+
+func newCommandProvider(base string, commands map[string][]string) *commandProvider {
+	return &commandProvider{base: base, commands: commands}
+}
+
+func (p *commandProvider) List() ([]*files.FileInfo, error) {
+	names := make([]string, 0, len(p.commands))
+	for name, command := range p.commands {
+		if validName(name) && len(command) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := make([]*files.FileInfo, 0, len(names))
+	for _, name := range names {
+		items = append(items, p.info(name))
+	}
+
+	return items, nil
+}
+
+func (p *commandProvider) Stat(name string) (*files.FileInfo, error) {
+	if command, ok := p.commands[name]; !ok || !validName(name) || len(command) == 0 {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return p.info(name), nil
+}
+
+func (p *commandProvider) Open(name string) (io.ReadCloser, error) {
+	command, ok := p.commands[name]
+	if !ok || !validName(name) || len(command) == 0 {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	defer cancel()
+
+	// The output is served even if the command fails, since
+	// that is usually what explains the failure.
+	out, _ := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput() //nolint:gosec
+	return output{bytes.NewReader(out)}, nil
+}
+
+func (p *commandProvider) info(name string) *files.FileInfo {
+	return &files.FileInfo{
+		Path:      path.Join(p.base, name),
+		Name:      name,
+		Extension: filepath.Ext(name),
+		ModTime:   time.Now(),
+		Mode:      modeFile,
+		Type:      detectType(name),
+	}
+}
+
+// output is the buffered output of a command. It is seekable so it
+// can be served with http.ServeContent like a regular file.
+type output struct {
+	*bytes.Reader
+}
+
+func (output) Close() error {
+	return nil
+}
@@ -0,0 +1,84 @@
+package virtual
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+)
+
+// dirProvider exposes the regular files found directly
+// inside a real directory, such as generated reports.
+type dirProvider struct {
+	base string
+	root string
+}
+
+func newDirProvider(base, root string) *dirProvider {
+	return &dirProvider{base: base, root: root}
+}
+
+func (p *dirProvider) List() ([]*files.FileInfo, error) {
+	entries, err := os.ReadDir(p.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*files.FileInfo{}, nil
+		}
+
+		return nil, err
+	}
+
+	items := []*files.FileInfo{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		items = append(items, fileInfo(p.base, info))
+	}
+
+	return items, nil
+}
+
+func (p *dirProvider) Stat(name string) (*files.FileInfo, error) {
+	realPath, err := p.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return fileInfo(p.base, info), nil
+}
+
+func (p *dirProvider) Open(name string) (io.ReadCloser, error) {
+	info, err := p.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(filepath.Join(p.root, info.Name))
+}
+
+// RealPath returns the path on disk of the entry with the given name.
+func (p *dirProvider) RealPath(name string) (string, error) {
+	if !validName(name) || p.root == "" {
+		return "", fbErrors.ErrNotExist
+	}
+
+	return filepath.Join(p.root, name), nil
+}
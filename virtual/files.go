@@ -0,0 +1,81 @@
+package virtual
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+)
+
+// filesProvider exposes a fixed list of real files, such as logs.
+// Files that don't exist are left out of the listing.
+type filesProvider struct {
+	base  string
+	paths []string
+}
+
+func newFilesProvider(base string, paths []string) *filesProvider {
+	return &filesProvider{base: base, paths: paths}
+}
+
+func (p *filesProvider) List() ([]*files.FileInfo, error) {
+	items := []*files.FileInfo{}
+	for _, realPath := range p.paths {
+		info, err := os.Stat(realPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		items = append(items, fileInfo(p.base, info))
+	}
+
+	return items, nil
+}
+
+func (p *filesProvider) Stat(name string) (*files.FileInfo, error) {
+	realPath, err := p.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileInfo(p.base, info), nil
+}
+
+func (p *filesProvider) Open(name string) (io.ReadCloser, error) {
+	realPath, err := p.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(realPath)
+}
+
+// RealPath returns the path on disk of the entry with the given name.
+func (p *filesProvider) RealPath(name string) (string, error) {
+	if !validName(name) {
+		return "", fbErrors.ErrNotExist
+	}
+
+	for _, realPath := range p.paths {
+		if filepath.Base(realPath) == name {
+			return realPath, nil
+		}
+	}
+
+	return "", fbErrors.ErrNotExist
+}
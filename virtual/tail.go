@@ -0,0 +1,304 @@
+package virtual
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+)
+
+const (
+	// DefaultTailLines is the number of lines sent
+	// before following a file when none is given.
+	DefaultTailLines = 100
+	// MaxTailLines caps the number of lines sent before following a file.
+	MaxTailLines = 10000
+	// DefaultPollInterval is how often a followed file is checked for changes.
+	DefaultPollInterval = 500 * time.Millisecond
+
+	tailChunkSize = 16 * 1024
+	// tailMaxLine caps the lines sent while following a file. Longer
+	// ones, such as those of files that never write a newline, are
+	// sent in pieces of this size.
+	tailMaxLine = 64 * 1024
+)
+
+// RealPather is implemented by the providers whose
+// entries are backed by files on disk.
+type RealPather interface {
+	RealPath(name string) (string, error)
+}
+
+// RealPath returns the path on disk of the file at a path relative to Root.
+func (r *Registry) RealPath(p string) (string, error) {
+	mount, name := split(p)
+	if mount == "" || name == "" {
+		return "", fbErrors.ErrIsDirectory
+	}
+
+	provider, ok := r.providers[mount]
+	if !ok {
+		return "", fbErrors.ErrNotExist
+	}
+
+	rp, ok := provider.(RealPather)
+	if !ok {
+		return "", fbErrors.ErrInvalidOption
+	}
+
+	return rp.RealPath(name)
+}
+
+// TailOptions are the options to follow a file.
+type TailOptions struct {
+	// Lines is the number of existing lines sent before
+	// following the file.
+	Lines int
+	// Filter, when set, drops the lines that don't match it.
+	Filter *regexp.Regexp
+	// Interval is how often the file is checked for changes.
+	Interval time.Duration
+}
+
+// Follow sends the last lines of the file at realPath and then every
+// line appended to it until ctx is done or send fails. Rotations, where
+// the file is renamed and a new one is created in its place, and
+// truncations are followed transparently.
+func Follow(ctx context.Context, realPath string, opts TailOptions, send func(line string) error) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultPollInterval
+	}
+
+	t := &tail{path: realPath, opts: opts, send: send}
+	if err := t.open(); err != nil {
+		return err
+	}
+	defer t.close()
+
+	lines, err := lastLines(t.fd, t.offset, opts.Lines, opts.Filter)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if err := send(line); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := t.poll(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type tail struct {
+	path    string
+	opts    TailOptions
+	send    func(line string) error
+	fd      *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+}
+
+func (t *tail) open() error {
+	fd, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	t.fd = fd
+	t.info = info
+	t.offset = info.Size()
+	return nil
+}
+
+func (t *tail) close() {
+	if t.fd != nil {
+		t.fd.Close()
+		t.fd = nil
+	}
+}
+
+func (t *tail) poll() error {
+	info, err := os.Stat(t.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// In the middle of a rotation: keep reading
+		// the old file until the new one shows up.
+		return t.read()
+	case err != nil:
+		return err
+	}
+
+	if t.fd == nil || !os.SameFile(t.info, info) {
+		// The file was rotated. Drain what was written to the old
+		// one and start over from the beginning of the new one.
+		if err := t.read(); err != nil {
+			return err
+		}
+		t.close()
+
+		fd, err := os.Open(t.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		t.fd = fd
+		t.info = info
+		t.offset = 0
+		t.partial = nil
+		return t.read()
+	}
+
+	if info.Size() < t.offset {
+		// The file was truncated in place.
+		t.offset = 0
+		t.partial = nil
+	}
+
+	return t.read()
+}
+
+func (t *tail) read() error {
+	if t.fd == nil {
+		return nil
+	}
+
+	if _, err := t.fd.Seek(t.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	buf := make([]byte, tailChunkSize)
+	for {
+		n, err := t.fd.Read(buf)
+		if n > 0 {
+			t.offset += int64(n)
+			if sendErr := t.emit(buf[:n]); sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// emit sends the complete lines in data, keeping the
+// trailing partial line until the rest of it is written
+// or it reaches tailMaxLine.
+func (t *tail) emit(data []byte) error {
+	t.partial = append(t.partial, data...)
+
+	for {
+		var line string
+		if i := bytes.IndexByte(t.partial, '\n'); i >= 0 {
+			line = string(bytes.TrimSuffix(t.partial[:i], []byte("\r")))
+			t.partial = t.partial[i+1:]
+		} else if len(t.partial) >= tailMaxLine {
+			line = string(t.partial[:tailMaxLine])
+			t.partial = t.partial[tailMaxLine:]
+		} else {
+			return nil
+		}
+
+		if t.opts.Filter != nil && !t.opts.Filter.MatchString(line) {
+			continue
+		}
+
+		if err := t.send(line); err != nil {
+			return err
+		}
+	}
+}
+
+// lastLines returns the last n lines before offset that match filter,
+// reading the file backwards so big files are never loaded whole.
+func lastLines(fd io.ReaderAt, offset int64, n int, filter *regexp.Regexp) ([]string, error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+
+	var (
+		lines []string
+		rest  []byte
+		pos   = offset
+	)
+
+	for pos > 0 && len(lines) < n {
+		size := int64(tailChunkSize)
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+
+		chunk := make([]byte, size, size+int64(len(rest)))
+		if _, err := fd.ReadAt(chunk, pos); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		rest = append(chunk, rest...)
+
+		// Everything after the first newline is made of whole lines, unless
+		// we reached the start of the file and the first line is whole too.
+		for len(lines) < n {
+			i := bytes.LastIndexByte(rest, '\n')
+			if i < 0 {
+				break
+			}
+
+			lines = appendLine(lines, rest[i+1:], offset, pos+int64(i)+1, filter)
+			rest = rest[:i]
+		}
+	}
+
+	if pos == 0 && len(lines) < n {
+		lines = appendLine(lines, rest, offset, 0, filter)
+	}
+
+	// lines were collected from the end of the file.
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines, nil
+}
+
+func appendLine(lines []string, line []byte, end, start int64, filter *regexp.Regexp) []string {
+	// The empty string after a trailing newline is not a line.
+	if len(line) == 0 && start == end {
+		return lines
+	}
+
+	s := string(bytes.TrimSuffix(line, []byte("\r")))
+	if filter != nil && !filter.MatchString(s) {
+		return lines
+	}
+
+	return append(lines, s)
+}
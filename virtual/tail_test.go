@@ -0,0 +1,123 @@
+package virtual
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastLines(t *testing.T) {
+	content := "one\ntwo\nthree error\nfour\nfive error\n"
+
+	cases := []struct {
+		n      int
+		filter *regexp.Regexp
+		want   []string
+	}{
+		{n: 2, want: []string{"four", "five error"}},
+		{n: 10, want: []string{"one", "two", "three error", "four", "five error"}},
+		{n: 0, want: []string{}},
+		{n: 10, filter: regexp.MustCompile("error"), want: []string{"three error", "five error"}},
+		{n: 1, filter: regexp.MustCompile("one"), want: []string{"one"}},
+	}
+
+	for _, tc := range cases {
+		got, err := lastLines(strings.NewReader(content), int64(len(content)), tc.n, tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("lastLines(%d, %v) = %q; want %q", tc.n, tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestFollowRotation(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "es_log.txt")
+	if err := os.WriteFile(logPath, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		opts := TailOptions{Lines: 10, Interval: 10 * time.Millisecond}
+		done <- Follow(ctx, logPath, opts, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("got line %q; want %q", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	expect("old")
+
+	appendTo(t, logPath, "before rotation\n")
+	expect("before rotation")
+
+	if err := os.Rename(logPath, logPath+".bak"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, logPath+".bak", "late write\n")
+	appendTo(t, logPath, "after rotation\n")
+	expect("late write")
+	expect("after rotation")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendTo(t *testing.T, name, s string) {
+	t.Helper()
+	fd, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if _, err := fd.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmitLongLines(t *testing.T) {
+	var lines []string
+	tl := &tail{send: func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}}
+
+	// Files that never write a newline are sent in pieces.
+	long := strings.Repeat("x", tailMaxLine)
+	for _, data := range []string{long[:tailChunkSize], long[tailChunkSize:] + "yz", "\nend"} {
+		if err := tl.emit([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(lines, []string{long, "yz"}) {
+		t.Errorf("expected a full piece and \"yz\", got %d lines", len(lines))
+	}
+	if string(tl.partial) != "end" {
+		t.Errorf("unexpected partial line %q", tl.partial)
+	}
+}
@@ -0,0 +1,215 @@
+package virtual
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/files"
+	"github.com/filebrowser/filebrowser/v2/settings"
+)
+
+// Root is the path under which the virtual directories are mounted.
+const Root = "/virtual"
+
+const (
+	dirSize  = 3488
+	modeFile = fs.FileMode(0444)
+	modeDir  = fs.FileMode(0555) | fs.ModeDir
+)
+
+// Provider serves the contents of a single virtual directory.
+type Provider interface {
+	// List returns the entries of the directory.
+	List() ([]*files.FileInfo, error)
+	// Stat returns the entry with the given name.
+	Stat(name string) (*files.FileInfo, error)
+	// Open opens the entry with the given name for reading.
+	Open(name string) (io.ReadCloser, error)
+}
+
+// Registry holds the providers mounted under Root.
+type Registry struct {
+	providers map[string]Provider
+	names     []string
+}
+
+// NewRegistry creates a registry from the configured virtual directories.
+func NewRegistry(dirs []settings.VirtualDir) (*Registry, error) {
+	r := &Registry{
+		providers: map[string]Provider{},
+		names:     []string{},
+	}
+
+	for _, dir := range dirs {
+		if dir.Name == "" || strings.ContainsAny(dir.Name, `/\`) || dir.Name == "." || dir.Name == ".." {
+			return nil, fmt.Errorf("invalid virtual directory name %q: %w", dir.Name, fbErrors.ErrInvalidOption)
+		}
+
+		if _, ok := r.providers[dir.Name]; ok {
+			return nil, fmt.Errorf("duplicate virtual directory %q: %w", dir.Name, fbErrors.ErrInvalidOption)
+		}
+
+		base := path.Join(Root, dir.Name)
+
+		var p Provider
+		switch dir.Provider {
+		case settings.VirtualProviderFiles:
+			p = newFilesProvider(base, dir.Files)
+		case settings.VirtualProviderDir:
+			p = newDirProvider(base, dir.Path)
+		case settings.VirtualProviderCommand:
+			p = newCommandProvider(base, dir.Commands)
+		default:
+			return nil, fmt.Errorf("unknown virtual provider %q: %w", dir.Provider, fbErrors.ErrInvalidOption)
+		}
+
+		r.providers[dir.Name] = p
+		r.names = append(r.names, dir.Name)
+	}
+
+	sort.Strings(r.names)
+	return r, nil
+}
+
+// Stat returns the FileInfo of a path relative to Root. Directories
+// come with their Listing filled.
+func (r *Registry) Stat(p string) (*files.FileInfo, error) {
+	mount, name := split(p)
+
+	if mount == "" {
+		return r.root(), nil
+	}
+
+	provider, ok := r.providers[mount]
+	if !ok {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	if name == "" {
+		return listing(path.Join(Root, mount), mount, provider)
+	}
+
+	return provider.Stat(name)
+}
+
+// Open opens the file at a path relative to Root.
+func (r *Registry) Open(p string) (io.ReadCloser, error) {
+	mount, name := split(p)
+	if mount == "" || name == "" {
+		return nil, fbErrors.ErrIsDirectory
+	}
+
+	provider, ok := r.providers[mount]
+	if !ok {
+		return nil, fbErrors.ErrNotExist
+	}
+
+	return provider.Open(name)
+}
+
+// Provider returns the provider mounted with the given name.
+func (r *Registry) Provider(mount string) (Provider, bool) {
+	p, ok := r.providers[mount]
+	return p, ok
+}
+
+func (r *Registry) root() *files.FileInfo {
+	modTime := time.Now().Add(-5 * time.Second) //nolint:gomnd
+
+	items := make([]*files.FileInfo, 0, len(r.names))
+	for _, name := range r.names {
+		items = append(items, dirInfo(path.Join(Root, name), name, modTime))
+	}
+
+	info := dirInfo(Root, "virtual", modTime)
+	info.Listing = &files.Listing{
+		Items:    items,
+		NumDirs:  len(items),
+		NumFiles: 0,
+		Sorting:  files.Sorting{By: "name", Asc: true},
+	}
+
+	return info
+}
+
+func listing(base, name string, p Provider) (*files.FileInfo, error) {
+	items, err := p.List()
+	if err != nil {
+		return nil, err
+	}
+
+	info := dirInfo(base, name, time.Now().Add(-5*time.Second)) //nolint:gomnd
+	info.Listing = &files.Listing{
+		Items:    items,
+		NumDirs:  0,
+		NumFiles: len(items),
+		Sorting:  files.Sorting{By: "name", Asc: true},
+	}
+
+	return info, nil
+}
+
+// split splits a path relative to Root into the mount
+// name and the name of the entry inside of it.
+func split(p string) (mount, name string) {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	mount, name, _ = strings.Cut(p, "/")
+	return mount, name
+}
+
+// validName reports whether name refers to a direct child of a directory.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func dirInfo(p, name string, modTime time.Time) *files.FileInfo {
+	return &files.FileInfo{
+		Path:    p,
+		Name:    name,
+		Size:    int64(dirSize),
+		Mode:    modeDir,
+		ModTime: modTime,
+		IsDir:   true,
+	}
+}
+
+func fileInfo(base string, info os.FileInfo) *files.FileInfo {
+	return &files.FileInfo{
+		Path:      path.Join(base, info.Name()),
+		Name:      info.Name(),
+		Size:      info.Size(),
+		Extension: filepath.Ext(info.Name()),
+		ModTime:   info.ModTime(),
+		Mode:      modeFile,
+		Type:      detectType(info.Name()),
+	}
+}
+
+//nolint:goconst
+func detectType(name string) string {
+	mimetype := mime.TypeByExtension(filepath.Ext(name))
+
+	switch {
+	case strings.HasPrefix(mimetype, "image"):
+		return "image"
+	case strings.HasPrefix(mimetype, "video"):
+		return "video"
+	case strings.HasPrefix(mimetype, "audio"):
+		return "audio"
+	case strings.HasSuffix(mimetype, "pdf"):
+		return "pdf"
+	case mimetype == "", strings.HasPrefix(mimetype, "text"):
+		return "text"
+	default:
+		return "blob"
+	}
+}
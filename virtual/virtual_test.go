@@ -0,0 +1,88 @@
+package virtual
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fbErrors "github.com/filebrowser/filebrowser/v2/errors"
+	"github.com/filebrowser/filebrowser/v2/settings"
+)
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(logPath, []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewRegistry([]settings.VirtualDir{
+		{Name: "logs", Provider: settings.VirtualProviderFiles, Files: []string{logPath, filepath.Join(dir, "missing.log")}},
+		{Name: "reports", Provider: settings.VirtualProviderDir, Path: dir},
+		{Name: "state", Provider: settings.VirtualProviderCommand, Commands: map[string][]string{"echo": {"echo", "hi"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	root, err := registry.Stat("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Path != Root || root.NumDirs != 3 {
+		t.Errorf("unexpected root %s with %d dirs", root.Path, root.NumDirs)
+	}
+
+	logs, err := registry.Stat("/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs.NumFiles != 1 || logs.Items[0].Path != "/virtual/logs/app.log" {
+		t.Errorf("unexpected logs listing: %+v", logs.Items)
+	}
+
+	for _, p := range []string{"/logs/app.log", "/reports/app.log"} {
+		info, err := registry.Stat(p)
+		if err != nil {
+			t.Fatalf("stat %s: %v", p, err)
+		}
+		if info.Type != "text" || info.Size != 6 {
+			t.Errorf("stat %s: got type %s and size %d", p, info.Type, info.Size)
+		}
+	}
+
+	fd, err := registry.Open("/state/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	out, err := io.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hi\n" {
+		t.Errorf("unexpected command output %q", out)
+	}
+
+	for _, p := range []string{"/nope", "/logs/missing.log", "/logs/../etc", "/state/other"} {
+		if _, err := registry.Stat(p); err == nil {
+			t.Errorf("stat %s: expected error", p)
+		}
+	}
+}
+
+func TestRegistryInvalid(t *testing.T) {
+	cases := map[string][]settings.VirtualDir{
+		"duplicate": {{Name: "a", Provider: settings.VirtualProviderDir}, {Name: "a", Provider: settings.VirtualProviderDir}},
+		"bad name":  {{Name: "a/b", Provider: settings.VirtualProviderDir}},
+		"provider":  {{Name: "a", Provider: "nope"}},
+	}
+
+	for name, dirs := range cases {
+		if _, err := NewRegistry(dirs); !errors.Is(err, fbErrors.ErrInvalidOption) {
+			t.Errorf("%s: expected ErrInvalidOption, got %v", name, err)
+		}
+	}
+}